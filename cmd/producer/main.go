@@ -5,7 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -15,16 +15,43 @@ import (
 	"github.com/brianvoe/gofakeit/v7"
 	"github.com/segmentio/kafka-go"
 
+	"demo/orders/internal/logging"
 	"demo/orders/internal/model"
+	"demo/orders/internal/requestid"
+	"demo/orders/internal/tracing"
 )
 
 func main() {
+	level, err := logging.ParseLevel(os.Getenv("LOG_LEVEL"))
+	if err != nil {
+		fatal("logging", "err", err)
+	}
+	logger, err := logging.New(os.Stderr, os.Getenv("LOG_FORMAT"), level)
+	if err != nil {
+		fatal("logging", "err", err)
+	}
+	slog.SetDefault(logger)
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Config{
+		Exporter: env("OTEL_TRACES_EXPORTER", tracing.ExporterNone),
+		File:     os.Getenv("OTEL_TRACES_FILE"),
+		Service:  env("OTEL_SERVICE_NAME", "orders-producer"),
+	})
+	if err != nil {
+		fatal("tracing", "err", err)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			slog.Error("tracing shutdown", "err", err)
+		}
+	}()
 	gofakeit.Seed(time.Now().UnixNano())
 
 	brokers := splitCSV(env("KAFKA_BROKERS", "localhost:9094"))
 	topic := env("KAFKA_TOPIC", "orders")
 	glob := env("DATA_GLOB", "data/*.json")
-	log.Printf("brokers=%v topic=%s glob=%s", brokers, topic, glob)
+	slog.Info("producer", "brokers", brokers, "topic", topic, "glob", glob)
 
 	w := &kafka.Writer{
 		Addr:         kafka.TCP(brokers...),
@@ -35,13 +62,13 @@ func main() {
 
 	defer func() {
 		if err := w.Close(); err != nil {
-			log.Printf("close writer: %v", err)
+			slog.Error("close writer", "err", err)
 		}
 	}()
 
 	paths, err := filepath.Glob(glob)
 	if err != nil {
-		log.Fatalf("glob %s: %v", glob, err)
+		fatal("glob", "glob", glob, "err", err)
 	}
 
 	// ФОЛЛБЭК: если файлов нет — генерим N заказов
@@ -51,13 +78,13 @@ func main() {
 		for i := 0; i < n; i++ {
 			o := fakeOrder()
 			if _, err := sendOrder(context.Background(), w, o, "generated"); err != nil {
-				log.Fatalf("produce: %v", err)
+				fatal("produce", "err", err)
 			}
 			if gap > 0 {
 				time.Sleep(time.Duration(gap) * time.Millisecond)
 			}
 		}
-		log.Printf("produced %d generated message(s)", n)
+		slog.Info("done", "produced", n, "source", "generated")
 		return
 	}
 
@@ -66,17 +93,18 @@ func main() {
 	for _, p := range paths {
 		n, err := produceFile(context.Background(), w, p)
 		if err != nil {
-			log.Printf("file %s: %v", p, err)
+			slog.Error("produce file", "file", p, "err", err)
 		}
 		total += n
 	}
-	log.Printf("done: produced=%d from %d files", total, len(paths))
+	slog.Info("done", "produced", total, "files", len(paths))
 }
 
 func fakeOrder() model.Order {
+	track := strings.ToUpper(gofakeit.LetterN(4) + gofakeit.DigitN(6)) // у товаров тот же, что у заказа
 	return model.Order{
 		OrderUID:    gofakeit.UUID(),
-		TrackNumber: strings.ToUpper(gofakeit.LetterN(4) + gofakeit.DigitN(6)),
+		TrackNumber: track,
 		Entry:       "WBIL",
 		Delivery: model.Delivery{
 			Name:    gofakeit.Name(),
@@ -100,7 +128,7 @@ func fakeOrder() model.Order {
 		},
 		Items: []model.Item{{
 			ChrtID:      int64(gofakeit.Number(1000, 999999)),
-			TrackNumber: "WB" + strings.ToUpper(gofakeit.LetterN(2)+gofakeit.DigitN(6)),
+			TrackNumber: track,
 			Price:       gofakeit.Number(10, 10000),
 			RID:         gofakeit.UUID(),
 			Name:        gofakeit.ProductName(),
@@ -122,19 +150,24 @@ func sendOrder(ctx context.Context, w *kafka.Writer, o model.Order, source strin
 	if merr != nil {
 		return 0, fmt.Errorf("marshal order: %w", merr)
 	}
-	err := w.WriteMessages(ctx, kafka.Message{
+	id := requestid.New() // под этим ID заказ пройдёт по логам сервиса
+	msg := kafka.Message{
 		Key:   []byte(o.OrderUID),
 		Value: val,
 		Time:  time.Now(),
 		Headers: []kafka.Header{
 			{Key: "content-type", Value: []byte("application/json")},
 			{Key: "source", Value: []byte(source)},
+			{Key: requestid.MessageHeader, Value: []byte(id)},
 		},
-	})
+	}
+	ctx, span := tracing.StartPublish(ctx, w.Topic, &msg)
+	err := w.WriteMessages(ctx, msg)
+	tracing.EndPublish(span, err)
 	if err != nil {
 		return 0, err
 	}
-	log.Printf("produced key=%s src=%s", o.OrderUID, source)
+	slog.InfoContext(ctx, "produced", "order_uid", o.OrderUID, "source", source, "request_id", id)
 	return 1, nil
 }
 
@@ -145,7 +178,7 @@ func produceFile(ctx context.Context, w *kafka.Writer, path string) (int, error)
 	}
 	defer func() {
 		if err := f.Close(); err != nil {
-			log.Printf("close %s: %v", path, err)
+			slog.Error("close file", "file", path, "err", err)
 		}
 	}()
 	b, err := io.ReadAll(f)
@@ -162,7 +195,7 @@ func produceFile(ctx context.Context, w *kafka.Writer, path string) (int, error)
 		for _, obj := range many {
 			n, err := sendOne(ctx, w, obj, filepath.Base(path))
 			if err != nil {
-				log.Printf("produce: %v", err)
+				slog.Error("produce", "file", path, "err", err)
 				continue
 			}
 			sum += n
@@ -181,22 +214,32 @@ func sendOne(ctx context.Context, w *kafka.Writer, obj map[string]any, source st
 	if merr != nil {
 		return 0, fmt.Errorf("marshal order: %w", merr)
 	}
-	err := w.WriteMessages(ctx, kafka.Message{
+	id := requestid.New()
+	msg := kafka.Message{
 		Key:   []byte(key),
 		Value: val,
 		Time:  time.Now(),
 		Headers: []kafka.Header{
 			{Key: "content-type", Value: []byte("application/json")},
 			{Key: "source", Value: []byte(source)},
+			{Key: requestid.MessageHeader, Value: []byte(id)},
 		},
-	})
+	}
+	ctx, span := tracing.StartPublish(ctx, w.Topic, &msg)
+	err := w.WriteMessages(ctx, msg)
+	tracing.EndPublish(span, err)
 	if err != nil {
 		return 0, err
 	}
-	log.Printf("produced key=%s src=%s", key, source)
+	slog.InfoContext(ctx, "produced", "order_uid", key, "source", source, "request_id", id)
 	return 1, nil
 }
 
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
+
 func env(k, def string) string {
 	if v := os.Getenv(k); v != "" {
 		return v
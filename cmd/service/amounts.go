@@ -0,0 +1,32 @@
+package main
+
+import (
+	"net/http"
+
+	"demo/orders/internal/model"
+	"demo/orders/internal/money"
+)
+
+// amountsDecimal — клиент просит суммы десятичными строками в валюте заказа: ?amounts=decimal.
+// По умолчанию (amounts=minor) суммы — целые числа в минимальных единицах, как хранятся.
+func amountsDecimal(r *http.Request) bool {
+	return r.URL.Query().Get("amounts") == "decimal"
+}
+
+// orderBody — заказ для JSON-ответа с учётом ?amounts.
+func orderBody(r *http.Request, o model.Order) any {
+	if amountsDecimal(r) {
+		return money.DecimalOrder(o)
+	}
+	return o
+}
+
+// decimalOrders — заказы с суммами строками. Ответы-списки подставляют их внешним полем orders,
+// которое затеняет одноимённое поле встроенной структуры ответа.
+func decimalOrders(orders []model.Order) []any {
+	out := make([]any, len(orders))
+	for i, o := range orders {
+		out[i] = money.DecimalOrder(o)
+	}
+	return out
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"demo/orders/internal/model"
+	"demo/orders/internal/validate"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+// orderAmounts — суммы первого заказа из JSON-ответа как есть: числа или строки.
+func orderAmounts(t *testing.T, o any) (amount, price any) {
+	t.Helper()
+	m := o.(map[string]any)
+	return m["payment"].(map[string]any)["amount"], m["items"].([]any)[0].(map[string]any)["price"]
+}
+
+func TestGetOrder_AmountsDecimal(t *testing.T) {
+	_, cache, mux := newTestMux(t)
+	o := validOrder("b563feb7b2b84b6test")
+	cache.Set(o.OrderUID, o)
+
+	for q, want := range map[string][2]any{
+		"":                 {1817.0, 453.0},
+		"?amounts=minor":   {1817.0, 453.0},
+		"?amounts=decimal": {"18.17", "4.53"},
+	} {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/order/"+o.OrderUID+q, nil))
+		require.Equal(t, http.StatusOK, rec.Code, q)
+		var got any
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+		amount, price := orderAmounts(t, got)
+		require.Equal(t, want, [2]any{amount, price}, q)
+	}
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/order/"+o.OrderUID+"?amounts=cents", nil))
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestListAndBatch_AmountsDecimal(t *testing.T) {
+	repo, cache, mux := newTestMux(t)
+	jpy := validOrder("order0002")
+	jpy.Payment.Currency = "JPY"
+	repo.EXPECT().ListOrders(gomock.Any(), gomock.Any(), "", 2).Return([]model.Order{validOrder("order0001"), jpy}, nil)
+	cache.Set(jpy.OrderUID, jpy)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/orders?limit=2&amounts=decimal", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var list struct {
+		Orders     []any  `json:"orders"`
+		NextCursor string `json:"next_cursor"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &list))
+	require.Equal(t, "order0002", list.NextCursor)
+	amount, _ := orderAmounts(t, list.Orders[0])
+	require.Equal(t, "18.17", amount)
+	amount, price := orderAmounts(t, list.Orders[1])
+	require.Equal(t, "1817", amount) // у иены нет дробной части
+	require.Equal(t, "453", price)
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/orders:batchGet?amounts=decimal",
+		strings.NewReader(`{"order_uids": ["order0002"]}`)))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var batch struct {
+		Orders  []any    `json:"orders"`
+		Missing []string `json:"missing"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &batch))
+	require.Empty(t, batch.Missing)
+	amount, _ = orderAmounts(t, batch.Orders[0])
+	require.Equal(t, "1817", amount)
+}
+
+func TestPutOrder_UnknownCurrency(t *testing.T) {
+	repo, _, mux := newTestMux(t)
+	o := validOrder("newOrder01")
+	o.Payment.Currency = "XYZ"
+	mockUpdate(repo, o.OrderUID, model.Order{}, false)
+	body, err := json.Marshal(o)
+	require.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/order/"+o.OrderUID, strings.NewReader(string(body))))
+
+	require.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	p := decodeProblem(t, rec)
+	require.Len(t, p.Errors, 1)
+	require.Equal(t, "/payment/currency", p.Errors[0].Pointer)
+	require.Equal(t, validate.CodeEnum, p.Errors[0].Code)
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"demo/orders/internal/apispec"
+	"demo/orders/internal/auth"
+	"demo/orders/internal/etag"
+	"demo/orders/internal/feed"
+	"demo/orders/internal/model"
+	"demo/orders/internal/store/storemock"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func newAuthMux(t *testing.T) http.Handler {
+	h, _ := newAuthMuxWithCache(t)
+	return h
+}
+
+func newAuthMuxWithCache(t *testing.T) (http.Handler, *Cache) {
+	t.Helper()
+	keys := filepath.Join(t.TempDir(), "keys")
+	lines := auth.HashAPIKey("s3cret") + " support-bot support\n" +
+		auth.HashAPIKey("bi") + " dashboards analytics\n" +
+		auth.HashAPIKey("tracking") + " tracking-page public\n"
+	require.NoError(t, os.WriteFile(keys, []byte(lines), 0o600))
+	t.Setenv("AUTH_API_KEYS_FILE", keys)
+
+	repo := storemock.NewMockRepository(gomock.NewController(t))
+	cache := NewCache(time.Minute, 100)
+	mux := makeHTTPMux(repo, cache, feed.NewHub(16, 16), testMuxConfig(t), newIngest(), webFS)
+	authn, err := authFromEnv(mux)
+	require.NoError(t, err)
+	v, err := apispec.New(apispec.Full, defaultMaxBody)
+	require.NoError(t, err)
+	return withMiddleware(mux, authn, nil, v), cache
+}
+
+func get(h http.Handler, path, key string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if key != "" {
+		req.Header.Set(auth.APIKeyHeader, key)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func status(h http.Handler, path, key string) int { return get(h, path, key).Code }
+
+func TestAuth_PublicRoutes(t *testing.T) {
+	h := newAuthMux(t)
+
+	require.Equal(t, http.StatusUnauthorized, status(h, "/order/b563feb7b2b84b6test", ""))
+	require.Equal(t, http.StatusUnauthorized, status(h, "/orders?limit=1", "wrong"))
+	require.Equal(t, http.StatusOK, status(h, "/healthz", ""))
+	require.Equal(t, http.StatusOK, status(h, "/", ""))
+	require.Equal(t, http.StatusOK, status(h, "/docs.html", ""))
+	require.Equal(t, http.StatusOK, status(h, "/openapi.json", ""))
+}
+
+func TestAuth_StaticCanBeClosed(t *testing.T) {
+	t.Setenv("AUTH_PUBLIC_STATIC", "0")
+	t.Setenv("AUTH_PUBLIC_HEALTHZ", "0")
+	h := newAuthMux(t)
+
+	require.Equal(t, http.StatusUnauthorized, status(h, "/", ""))
+	require.Equal(t, http.StatusUnauthorized, status(h, "/healthz", ""))
+	require.Equal(t, http.StatusOK, status(h, "/healthz", "s3cret"))
+}
+
+func TestAuth_OrderViewsByRole(t *testing.T) {
+	h, cache := newAuthMuxWithCache(t)
+	o := validOrder("b563feb7b2b84b6test")
+	o.Delivery.Phone, o.Delivery.Email = "+9720000000", "test@gmail.com"
+	cache.Set(o.OrderUID, o)
+
+	decode := func(key string) model.Order {
+		rec := get(h, "/order/"+o.OrderUID, key)
+		require.Equal(t, http.StatusOK, rec.Code, key)
+		require.Equal(t, etag.Of(o), rec.Header().Get("ETag"), "ETag не зависит от роли")
+		var got model.Order
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+		return got
+	}
+
+	require.Equal(t, o.Delivery, decode("s3cret").Delivery)
+
+	bi := decode("bi")
+	require.Equal(t, "+********00", bi.Delivery.Phone)
+	require.Equal(t, "t***@gmail.com", bi.Delivery.Email)
+	require.Equal(t, o.Payment, bi.Payment)
+
+	pub := decode("tracking")
+	require.Empty(t, pub.Delivery)
+	require.Empty(t, pub.CustomerID)
+	require.Equal(t, o.Items, pub.Items)
+}
+
+func TestRateLimit_PerAPIKey(t *testing.T) {
+	limits := filepath.Join(t.TempDir(), "limits.json")
+	require.NoError(t, os.WriteFile(limits, []byte(`{"default": {"rps": 0.1, "burst": 1}, "routes": {"GET /healthz": {"rps": 0}}, "auth_failures": {"rps": 0.1, "burst": 2}}`), 0o600))
+	t.Setenv("RATE_LIMIT_FILE", limits)
+	t.Setenv("RATE_LIMIT_RELOAD", "0")
+
+	keys := filepath.Join(t.TempDir(), "keys")
+	lines := auth.HashAPIKey("s3cret") + " support-bot support\n" + auth.HashAPIKey("bi") + " dashboards analytics\n"
+	require.NoError(t, os.WriteFile(keys, []byte(lines), 0o600))
+	t.Setenv("AUTH_API_KEYS_FILE", keys)
+
+	repo := storemock.NewMockRepository(gomock.NewController(t))
+	cache := NewCache(time.Minute, 100)
+	cache.Set("b563feb7b2b84b6test", model.Order{OrderUID: "b563feb7b2b84b6test"})
+	mux := makeHTTPMux(repo, cache, feed.NewHub(16, 16), testMuxConfig(t), newIngest(), webFS)
+	authn, err := authFromEnv(mux)
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	limiter, err := rateLimitFromEnv(ctx, mux)
+	require.NoError(t, err)
+	v, err := apispec.New(apispec.Full, defaultMaxBody)
+	require.NoError(t, err)
+	h := withMiddleware(mux, authn, limiter, v)
+
+	require.Equal(t, http.StatusOK, status(h, "/order/b563feb7b2b84b6test", "s3cret"))
+	rec := get(h, "/order/b563feb7b2b84b6test", "s3cret")
+	require.Equal(t, http.StatusTooManyRequests, rec.Code)
+	require.Equal(t, "10", rec.Header().Get("Retry-After"))
+	require.NotEmpty(t, rec.Header().Get("X-Request-ID"))
+
+	// у другого ключа своё ведро; healthz без лимита
+	require.Equal(t, http.StatusOK, status(h, "/order/b563feb7b2b84b6test", "bi"))
+	for i := 0; i < 3; i++ {
+		require.Equal(t, http.StatusOK, status(h, "/healthz", ""))
+	}
+	// без ключа — 401 раньше лимита, ведро не тратится
+	require.Equal(t, http.StatusUnauthorized, status(h, "/order/b563feb7b2b84b6test", ""))
+	// но отказы аутентификации считаются по IP: после burst — 429 ещё до проверки ключа
+	require.Equal(t, http.StatusUnauthorized, status(h, "/order/b563feb7b2b84b6test", "guess"))
+	rec = get(h, "/order/b563feb7b2b84b6test", "s3cret")
+	require.Equal(t, http.StatusTooManyRequests, rec.Code)
+	require.Equal(t, "10", rec.Header().Get("Retry-After"))
+}
+
+func TestAuth_WriteRole(t *testing.T) {
+	keys := filepath.Join(t.TempDir(), "keys")
+	lines := auth.HashAPIKey("s3cret") + " support-bot support\n" +
+		auth.HashAPIKey("bi") + " dashboards analytics\n" +
+		auth.HashAPIKey("editor") + " back-office support,writer\n"
+	require.NoError(t, os.WriteFile(keys, []byte(lines), 0o600))
+	t.Setenv("AUTH_API_KEYS_FILE", keys)
+	repo := storemock.NewMockRepository(gomock.NewController(t))
+	mux := makeHTTPMux(repo, NewCache(time.Minute, 100), feed.NewHub(16, 16), testMuxConfig(t), newIngest(), webFS)
+	authn, err := authFromEnv(mux)
+	require.NoError(t, err)
+	v, err := apispec.New(apispec.Full, defaultMaxBody)
+	require.NoError(t, err)
+	h := withMiddleware(mux, authn, nil, v)
+
+	o := validOrder("b563feb7b2b84b6test")
+	body, err := json.Marshal(o)
+	require.NoError(t, err)
+	do := func(method, ct, body, key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/order/"+o.OrderUID, strings.NewReader(body))
+		req.Header.Set("Content-Type", ct)
+		req.Header.Set(auth.APIKeyHeader, key)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// читающие ключи заказ не меняют, в БД ни одного вызова
+	for _, key := range []string{"s3cret", "bi"} {
+		rec := do(http.MethodPut, "application/json", string(body), key)
+		require.Equal(t, http.StatusForbidden, rec.Code, key)
+		require.Equal(t, "role writer required", decodeProblem(t, rec).Detail)
+		require.Equal(t, http.StatusForbidden, do(http.MethodPatch, "application/merge-patch+json", `{"locale":"ru"}`, key).Code, key)
+	}
+
+	mockUpdate(repo, o.OrderUID, model.Order{}, false)
+	require.Equal(t, http.StatusCreated, do(http.MethodPut, "application/json", string(body), "editor").Code)
+	mockUpdate(repo, o.OrderUID, o, true)
+	require.Equal(t, http.StatusOK, do(http.MethodPatch, "application/merge-patch+json", `{"locale":"ru"}`, "editor").Code)
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"demo/orders/internal/etag"
+	"demo/orders/internal/model"
+	"demo/orders/internal/redact"
+)
+
+// writeOrderJSON отдаёт заказ в представлении вызывающего, с ETag и Last-Modified (если известен updated_at).
+// ETag — версии заказа, а не представления: по нему же работает If-Match при записи.
+func writeOrderJSON(w http.ResponseWriter, r *http.Request, status int, o model.Order, tag string) {
+	setValidators(w, o, tag)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(orderBody(r, redact.Order(r.Context(), o))); err != nil {
+		slog.ErrorContext(r.Context(), "encode order", "order_uid", o.OrderUID, "err", err)
+	}
+}
+
+// writeOrderConditional — то же для GET/HEAD, но с учётом If-None-Match / If-Modified-Since:
+// если клиентская копия актуальна, отвечаем 304 без тела.
+func writeOrderConditional(w http.ResponseWriter, r *http.Request, o model.Order, tag string) {
+	if notModified(r, o, tag) {
+		setValidators(w, o, tag)
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	writeOrderJSON(w, r, http.StatusOK, o, tag)
+}
+
+func setValidators(w http.ResponseWriter, o model.Order, tag string) {
+	h := w.Header()
+	h.Set("ETag", tag)
+	if !o.UpdatedAt.IsZero() {
+		h.Set("Last-Modified", o.UpdatedAt.UTC().Format(http.TimeFormat))
+	}
+	// разрешаем хранить ответ, но требуем ревалидацию — ETag делает её дешёвой
+	h.Set("Cache-Control", "no-cache")
+	// тело зависит от роли, то есть от учётных данных
+	h.Set("Vary", "Authorization, X-API-Key")
+}
+
+// notModified следует RFC 9110: If-None-Match главнее If-Modified-Since,
+// а последний учитывается только когда первого нет.
+func notModified(r *http.Request, o model.Order, tag string) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		return etag.Match(inm, tag)
+	}
+	ims := r.Header.Get("If-Modified-Since")
+	if ims == "" || o.UpdatedAt.IsZero() {
+		return false
+	}
+	since, err := http.ParseTime(ims)
+	if err != nil {
+		return false
+	}
+	// Last-Modified отдаётся с точностью до секунды
+	return !o.UpdatedAt.Truncate(time.Second).After(since)
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"demo/orders/internal/etag"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetOrder_ETagFromCache(t *testing.T) {
+	_, cache, mux := newTestMux(t)
+	o := validOrder("b563feb7b2b84b6test")
+	o.UpdatedAt = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	cache.Set(o.OrderUID, o)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/order/"+o.OrderUID, nil))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "HIT", rec.Header().Get("X-Cache"))
+	require.Equal(t, etag.Of(o), rec.Header().Get("ETag"))
+	require.Equal(t, "Wed, 01 May 2024 12:00:00 GMT", rec.Header().Get("Last-Modified"))
+}
+
+func TestGetOrder_IfNoneMatch(t *testing.T) {
+	_, cache, mux := newTestMux(t)
+	o := validOrder("b563feb7b2b84b6test")
+	tag := cache.Set(o.OrderUID, o)
+
+	req := httptest.NewRequest(http.MethodGet, "/order/"+o.OrderUID, nil)
+	req.Header.Set("If-None-Match", `"other", `+tag)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusNotModified, rec.Code)
+	require.Empty(t, rec.Body.Bytes())
+	require.Equal(t, tag, rec.Header().Get("ETag"))
+}
+
+func TestGetOrder_IfNoneMatchStale(t *testing.T) {
+	_, cache, mux := newTestMux(t)
+	o := validOrder("b563feb7b2b84b6test")
+	cache.Set(o.OrderUID, o)
+
+	req := httptest.NewRequest(http.MethodGet, "/order/"+o.OrderUID, nil)
+	req.Header.Set("If-None-Match", `"stale"`)
+	// If-None-Match главнее: даже «свежий» If-Modified-Since не даёт 304
+	req.Header.Set("If-Modified-Since", time.Now().UTC().Format(http.TimeFormat))
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestGetOrder_IfModifiedSinceFromDB(t *testing.T) {
+	repo, _, mux := newTestMux(t)
+	o := validOrder("b563feb7b2b84b6test")
+	o.UpdatedAt = time.Date(2024, 5, 1, 12, 0, 0, 500, time.UTC)
+	repo.EXPECT().GetOrder(gomock.Any(), o.OrderUID).Return(o, true, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/order/"+o.OrderUID, nil)
+	req.Header.Set("If-Modified-Since", "Wed, 01 May 2024 12:00:00 GMT")
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusNotModified, rec.Code)
+	require.Equal(t, "MISS", rec.Header().Get("X-Cache"))
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+
+	"demo/orders/internal/model"
+	"demo/orders/internal/normalize"
+	"demo/orders/internal/problem"
+	"demo/orders/internal/validate"
+)
+
+// dryRunResult — итог проверки заказа без записи: ответ POST /validate и строка `service validate -json`.
+type dryRunResult struct {
+	Source     string               `json:"source,omitempty"` // CLI: файл и строка или номер элемента массива
+	OrderUID   string               `json:"order_uid,omitempty"`
+	Valid      bool                 `json:"valid"`
+	Error      string               `json:"error,omitempty"`    // заказ не разобрался
+	RuleSet    string               `json:"rule_set,omitempty"` // набор правил из VALIDATE_RULES_FILE
+	Normalized []normalize.Change   `json:"normalized"`
+	Warnings   []problem.FieldError `json:"warnings"`
+	Errors     []problem.FieldError `json:"errors"`
+}
+
+// dryRun нормализует и проверяет заказ теми же настройками, что консьюмер и PUT, но ничего не пишет.
+func (c *ingestConfig) dryRun(ctx context.Context, o model.Order) dryRunResult {
+	ord, rep, err := c.prepareOrder(ctx, o)
+	return dryRunResult{
+		OrderUID:   ord.OrderUID,
+		Valid:      err == nil,
+		RuleSet:    c.validateOpts.Rules.SetName(ord),
+		Normalized: nonNil(rep.changes),
+		Warnings:   nonNil(problemFields(rep.warnings)),
+		Errors:     nonNil(problemFields(validate.Fields(err))),
+	}
+}
+
+// nonNil — пустой список вместо null в JSON.
+func nonNil[T any](s []T) []T {
+	if s == nil {
+		return []T{}
+	}
+	return s
+}
+
+// POST /validate — проверка заказа без записи: 200 с итогом, даже если заказ не прошёл.
+// 400 — только если тело не разбирается как заказ.
+func handleValidate(ing *ingestConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, ok := readBody(w, r, ing.maxBody)
+		if !ok {
+			return
+		}
+		o, err := ing.decodeOrderBody(body)
+		if err != nil {
+			problem.InvalidRequest(w, r, err.Error())
+			return
+		}
+		res := ing.dryRun(r.Context(), o)
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(res); err != nil {
+			slog.ErrorContext(r.Context(), "validate encode", "err", err)
+		}
+	}
+}
+
+// runValidate — подкоманда `service validate [-json] [-strict] [файл...]`: проверяет заказы из JSON
+// (объект или массив) и NDJSON без Kafka и БД, с настройками NORMALIZE_* и VALIDATE_* из окружения.
+// Без файлов читает stdin. Код выхода: 0 — все заказы прошли, 1 — есть отклонённые, 2 — ошибка запуска.
+func runValidate(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("validate", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	asJSON := fs.Bool("json", false, "print one JSON result per order (NDJSON)")
+	strictEnv, err := envBool("KAFKA_STRICT_DECODE", false)
+	if err != nil {
+		fmt.Fprintln(stderr, "config:", err)
+		return 2
+	}
+	strict := fs.Bool("strict", strictEnv, "reject unknown fields, as KAFKA_STRICT_DECODE=1 does")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	ctx := context.Background()
+	ing := &ingestConfig{strictDecode: *strict}
+	if ing.normalizeOpts, err = normalizeOptionsFromEnv(); err != nil {
+		fmt.Fprintln(stderr, "config:", err)
+		return 2
+	}
+	if ing.validateOpts, err = validateOptionsFromEnv(); err != nil {
+		fmt.Fprintln(stderr, "config:", err)
+		return 2
+	}
+
+	out := bufio.NewWriter(stdout)
+	defer out.Flush()
+	failed := false
+	report := func(res dryRunResult) {
+		failed = failed || !res.Valid
+		if *asJSON {
+			b, _ := json.Marshal(res)
+			fmt.Fprintf(out, "%s\n", b)
+			return
+		}
+		fmt.Fprintln(out, res.text())
+	}
+
+	files := fs.Args()
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+	for _, name := range files {
+		var b []byte
+		if name == "-" {
+			name = "stdin"
+			b, err = io.ReadAll(stdin)
+		} else {
+			b, err = os.ReadFile(name)
+		}
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 2
+		}
+		for _, rec := range splitOrders(name, b) {
+			res := dryRunResult{Source: rec.source, Normalized: []normalize.Change{}, Warnings: []problem.FieldError{}, Errors: []problem.FieldError{}}
+			if o, err := ing.decodeOrderBody(rec.raw); err != nil {
+				res.Error = err.Error()
+			} else {
+				res = ing.dryRun(ctx, o)
+				res.Source = rec.source
+			}
+			report(res)
+		}
+	}
+	if failed {
+		return 1
+	}
+	return 0
+}
+
+// text — итог одной строкой для человека: "orders.ndjson:3 b563feb7b2b84b6test: REJECTED /payment/currency=enum".
+func (r dryRunResult) text() string {
+	var b strings.Builder
+	b.WriteString(r.Source)
+	if r.OrderUID != "" {
+		b.WriteString(" " + r.OrderUID)
+	}
+	b.WriteString(": ")
+	switch {
+	case r.Error != "":
+		b.WriteString("UNREADABLE " + r.Error)
+		return b.String()
+	case !r.Valid:
+		b.WriteString("REJECTED")
+	case len(r.Warnings) > 0:
+		b.WriteString("QUARANTINED")
+	default:
+		b.WriteString("OK")
+	}
+	for _, fe := range r.Errors {
+		b.WriteString(" " + fe.Pointer + "=" + fe.Code)
+	}
+	for _, fe := range r.Warnings {
+		b.WriteString(" warn:" + fe.Pointer + "=" + fe.Code)
+	}
+	return b.String()
+}
+
+type rawOrder struct {
+	source string
+	raw    []byte
+}
+
+// splitOrders делит содержимое файла на заказы: массив — по элементам (source "file[i]"),
+// иначе — поток JSON-значений, то есть один объект или NDJSON (source "file:строка").
+// Битая запись становится записью с сырым содержимым — decodeOrder сообщит об ошибке. В .ndjson
+// и .jsonl это только её строка, дальше чтение продолжается; в остальных файлах — весь остаток.
+func splitOrders(name string, b []byte) []rawOrder {
+	trimmed := bytes.TrimSpace(b)
+	if bytes.HasPrefix(trimmed, []byte("[")) {
+		var arr []json.RawMessage
+		if err := json.Unmarshal(trimmed, &arr); err == nil {
+			out := make([]rawOrder, len(arr))
+			for i, raw := range arr {
+				out[i] = rawOrder{source: fmt.Sprintf("%s[%d]", name, i), raw: raw}
+			}
+			return out
+		}
+	}
+
+	lines := strings.HasSuffix(name, ".ndjson") || strings.HasSuffix(name, ".jsonl")
+	var out []rawOrder
+	for pos := 0; ; {
+		for pos < len(b) && strings.IndexByte(" \t\r\n", b[pos]) >= 0 {
+			pos++
+		}
+		if pos == len(b) {
+			return out
+		}
+		source := fmt.Sprintf("%s:%d", name, bytes.Count(b[:pos], []byte("\n"))+1)
+		dec := json.NewDecoder(bytes.NewReader(b[pos:]))
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err == nil {
+			out = append(out, rawOrder{source: source, raw: raw})
+			pos += int(dec.InputOffset())
+			continue
+		}
+		end := len(b)
+		if i := bytes.IndexByte(b[pos:], '\n'); lines && i >= 0 {
+			end = pos + i
+		}
+		out = append(out, rawOrder{source: source, raw: b[pos:end]})
+		pos = end
+	}
+}
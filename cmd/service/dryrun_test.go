@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"demo/orders/internal/validate"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateEndpoint(t *testing.T) {
+	_, _, mux := newTestMux(t) // ни одного вызова репозитория — gomock упадёт, если запись случится
+	post := func(body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/validate", strings.NewReader(body)))
+		return rec
+	}
+
+	o := validOrder("b563feb7b2b84b6test")
+	o.Payment.Currency = "usd"
+	b, err := json.Marshal(o)
+	require.NoError(t, err)
+	rec := post(string(b))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var res dryRunResult
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+	require.True(t, res.Valid)
+	require.Equal(t, "b563feb7b2b84b6test", res.OrderUID)
+	require.Equal(t, "/payment/currency", res.Normalized[0].Pointer)
+	require.Empty(t, res.Errors)
+
+	o.Payment.Currency = "XYZ"
+	o.Items[0].Sale = 120
+	b, err = json.Marshal(o)
+	require.NoError(t, err)
+	rec = post(string(b))
+	require.Equal(t, http.StatusOK, rec.Code)
+	res = dryRunResult{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+	require.False(t, res.Valid)
+	require.Len(t, res.Errors, 2)
+	require.Equal(t, validate.CodeEnum, res.Errors[0].Code)
+
+	require.Equal(t, http.StatusBadRequest, post(`{"order_uid": 1}`).Code)
+
+	// без order_uid — обычный итог с ошибкой поля, а не 400
+	rec = post(`{"track_number": "WB"}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+	res = dryRunResult{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+	require.False(t, res.Valid)
+	require.Equal(t, "/order_uid", res.Errors[0].Pointer)
+	require.Equal(t, validate.CodeRequired, res.Errors[0].Code)
+}
+
+func TestRunValidate(t *testing.T) {
+	t.Setenv("VALIDATE_WARN", "delivery.phone")
+
+	good, err := json.Marshal(validOrder("order0001"))
+	require.NoError(t, err)
+	bad := validOrder("order0002")
+	bad.Payment.Currency = "XYZ"
+	badJSON, err := json.Marshal(bad)
+	require.NoError(t, err)
+	soft := validOrder("order0003")
+	soft.Delivery.Phone = "call me"
+	softJSON, err := json.Marshal(soft)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	nd := filepath.Join(dir, "orders.ndjson")
+	require.NoError(t, os.WriteFile(nd, []byte(string(good)+"\n{broken\n"+string(badJSON)+"\n"), 0o600))
+	arr := filepath.Join(dir, "orders.json")
+	require.NoError(t, os.WriteFile(arr, []byte("["+string(good)+",\n"+string(softJSON)+"]"), 0o600))
+
+	var out, errOut bytes.Buffer
+	code := runValidate([]string{nd, arr}, nil, &out, &errOut)
+	require.Equal(t, 1, code, errOut.String())
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Equal(t, []string{
+		nd + ":1 order0001: OK",
+		nd + ":2: UNREADABLE invalid character 'b' looking for beginning of object key string",
+		nd + ":3 order0002: REJECTED /payment/currency=enum",
+		arr + "[0] order0001: OK",
+		arr + "[1] order0003: QUARANTINED warn:/delivery/phone=format",
+	}, lines)
+
+	// stdin, NDJSON-вывод; все заказы прошли — код 0
+	out.Reset()
+	code = runValidate([]string{"-json"}, strings.NewReader(string(good)), &out, &errOut)
+	require.Equal(t, 0, code)
+	var res dryRunResult
+	require.NoError(t, json.Unmarshal(out.Bytes(), &res))
+	require.Equal(t, "stdin:1", res.Source)
+	require.Equal(t, "order0001", res.OrderUID)
+	require.True(t, res.Valid)
+	require.Empty(t, res.Warnings)
+
+	// без order_uid — отклонён с ошибкой поля, а не «не разобрался»
+	out.Reset()
+	require.Equal(t, 1, runValidate(nil, strings.NewReader(`{"track_number":"WB"}`), &out, &errOut))
+	require.Contains(t, out.String(), "stdin:1: REJECTED /order_uid=required")
+
+	require.Equal(t, 2, runValidate([]string{filepath.Join(dir, "missing.json")}, nil, &out, &errOut))
+
+	// с файлом правил CLI только читает его: ни перечитывания, ни сообщений в лог
+	var logs bytes.Buffer
+	prevLog := slog.Default()
+	t.Cleanup(func() { slog.SetDefault(prevLog) })
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logs, nil)))
+	t.Setenv("VALIDATE_RULES_FILE", "../../internal/validate/builtin.yaml")
+	out.Reset()
+	require.Equal(t, 0, runValidate(nil, strings.NewReader(string(good)), &out, &errOut))
+	require.Empty(t, logs.String())
+}
+
+func TestRunValidate_StrictEnv(t *testing.T) {
+	var out, errOut bytes.Buffer
+	t.Setenv("KAFKA_STRICT_DECODE", "true")
+	require.Equal(t, 1, runValidate(nil, strings.NewReader(`{"promo": "SALE"}`), &out, &errOut))
+	require.Contains(t, out.String(), `unknown field "promo"`)
+
+	t.Setenv("KAFKA_STRICT_DECODE", "yes")
+	errOut.Reset()
+	require.Equal(t, 2, runValidate(nil, strings.NewReader(`{}`), &out, &errOut))
+	require.Contains(t, errOut.String(), "KAFKA_STRICT_DECODE")
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"demo/orders/internal/feed"
+	"demo/orders/internal/model"
+	"demo/orders/internal/requestid"
+	"demo/orders/internal/store/storemock"
+	"demo/orders/internal/tracing"
+	"demo/orders/internal/validate"
+)
+
+func TestOpenAPIDocAndViewer(t *testing.T) {
+	_, _, mux := newTestMux(t)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	var doc struct {
+		OpenAPI string         `json:"openapi"`
+		Paths   map[string]any `json:"paths"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &doc))
+	require.Equal(t, "3.0.3", doc.OpenAPI)
+	require.Contains(t, doc.Paths, "/order/{id}")
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/docs.html", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Contains(t, rec.Body.String(), "/openapi.json")
+}
+
+func TestMuxErrorsAreProblems(t *testing.T) {
+	_, _, mux := newTestMux(t)
+	for _, tc := range []struct {
+		method, path string
+		status       int
+		allow        string
+	}{
+		{http.MethodGet, "/nope.html", http.StatusNotFound, ""},
+		{http.MethodPut, "/orders", http.StatusMethodNotAllowed, "GET, HEAD"},
+		{http.MethodDelete, "/order/x", http.StatusMethodNotAllowed, "GET, HEAD, PATCH, PUT"},
+	} {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(tc.method, tc.path, nil))
+		require.Equal(t, tc.status, rec.Code, tc.path)
+		require.Equal(t, tc.allow, rec.Header().Get("Allow"), tc.path)
+		require.Equal(t, tc.status, decodeProblem(t, rec).Status, tc.path)
+	}
+}
+
+func TestMessageRequestID(t *testing.T) {
+	m := kafka.Message{Headers: []kafka.Header{
+		{Key: "source", Value: []byte("file.json")},
+		{Key: "X-Request-ID", Value: []byte("from-producer")},
+	}}
+	require.Equal(t, "from-producer", messageRequestID(m))
+
+	require.Len(t, messageRequestID(kafka.Message{}), 32)
+	bad := kafka.Message{Headers: []kafka.Header{{Key: requestid.MessageHeader, Value: []byte("bad id")}}}
+	require.Len(t, messageRequestID(bad), 32)
+}
+
+func TestConsumeMessage_Spans(t *testing.T) {
+	rec := tracetest.NewSpanRecorder()
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)))
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+
+	ctrl := gomock.NewController(t)
+	repo := storemock.NewMockRepository(ctrl)
+	cache := NewCache(time.Minute, 100)
+	hub := feed.NewHub(16, 16)
+
+	ctx, parent := tracing.Tracer().Start(context.Background(), "orders consume")
+	o := validOrder("b563feb7b2b84b6test")
+	o.TrackNumber = "wbilmtesttrack"
+	val, err := json.Marshal(o)
+	require.NoError(t, err)
+	repo.EXPECT().UpsertOrder(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, o model.Order) error {
+		// запросы к БД идут под спаном upsert; пишется уже нормализованный заказ
+		require.Equal(t, "upsert", trace.SpanFromContext(ctx).(sdktrace.ReadOnlySpan).Name())
+		require.Equal(t, "WBILMTESTTRACK", o.TrackNumber)
+		return nil
+	})
+	res := consumeMessage(ctx, kafka.Message{Value: val}, repo, cache, hub, newIngest())
+	parent.End()
+	require.Equal(t, "stored", res.result)
+	require.Equal(t, "/track_number", res.report.changes[0].Pointer)
+	cached, ok := cache.Get("b563feb7b2b84b6test")
+	require.True(t, ok)
+	require.Equal(t, "WBILMTESTTRACK", cached.TrackNumber)
+
+	var names []string
+	for _, s := range rec.Ended() {
+		if s.Name() != "orders consume" {
+			require.Equal(t, parent.SpanContext().SpanID(), s.Parent().SpanID(), s.Name())
+		}
+		names = append(names, s.Name())
+	}
+	require.Equal(t, []string{"decode", "normalize", "validate", "upsert", "cache set", "orders consume"}, names)
+
+	res = consumeMessage(context.Background(), kafka.Message{Value: []byte("{")}, repo, cache, hub, newIngest())
+	require.Equal(t, "invalid_json", res.result)
+	require.Equal(t, slog.LevelWarn, res.level)
+
+	repo.EXPECT().UpsertOrder(gomock.Any(), gomock.Any()).Return(errors.New("db down"))
+	res = consumeMessage(context.Background(), kafka.Message{Value: val}, repo, cache, hub, newIngest())
+	require.Equal(t, "retry", res.result)
+	require.Equal(t, slog.LevelError, res.level)
+	last := rec.Ended()[len(rec.Ended())-1]
+	require.Equal(t, "upsert", last.Name())
+	require.Equal(t, codes.Error, last.Status().Code)
+}
+
+func TestConsumeMessage_Quarantine(t *testing.T) {
+	ing := newIngest()
+	ing.validateOpts = validate.Options{Warn: []string{"delivery.phone"}}
+
+	repo := storemock.NewMockRepository(gomock.NewController(t))
+	o := validOrder("b563feb7b2b84b6test")
+	o.Delivery.Phone = "call me"
+	val, err := json.Marshal(o)
+	require.NoError(t, err)
+	repo.EXPECT().UpsertOrder(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, o model.Order) error {
+		require.Len(t, o.Warnings, 1)
+		require.Equal(t, "/delivery/phone", o.Warnings[0].Pointer)
+		return nil
+	})
+	res := consumeMessage(context.Background(), kafka.Message{Value: val}, repo, NewCache(time.Minute, 100), feed.NewHub(16, 16), ing)
+	require.Equal(t, "quarantined", res.result)
+	require.Equal(t, slog.LevelInfo, res.level)
+
+	// без мягкого поля тот же заказ отклоняется
+	res = consumeMessage(context.Background(), kafka.Message{Value: val}, repo, NewCache(time.Minute, 100), feed.NewHub(16, 16), newIngest())
+	require.Equal(t, "invalid_order", res.result)
+}
+
+func TestConsumeMessage_DuplicateItems(t *testing.T) {
+	repo := storemock.NewMockRepository(gomock.NewController(t)) // до UpsertOrder дойти не должно
+	o := validOrder("b563feb7b2b84b6test")
+	o.Items = append(o.Items, o.Items[0])
+	val, err := json.Marshal(o)
+	require.NoError(t, err)
+	res := consumeMessage(context.Background(), kafka.Message{Value: val}, repo, NewCache(time.Minute, 100), feed.NewHub(16, 16), newIngest())
+	require.Equal(t, "invalid_order", res.result)
+	require.ErrorContains(t, res.err, "items[1].chrt_id: duplicate of items[0].chrt_id")
+}
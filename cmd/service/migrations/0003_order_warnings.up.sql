@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS order_warnings (
+  id         BIGSERIAL PRIMARY KEY,
+  order_uid  TEXT NOT NULL REFERENCES orders(order_uid) ON DELETE CASCADE,
+  field      TEXT NOT NULL,
+  pointer    TEXT NOT NULL,
+  code       TEXT NOT NULL,
+  message    TEXT NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS order_warnings_order_uid_idx ON order_warnings (order_uid);
+CREATE INDEX IF NOT EXISTS order_warnings_code_idx ON order_warnings (code, order_uid);
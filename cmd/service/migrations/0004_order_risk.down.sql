@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS review_queue;
+DROP INDEX IF EXISTS orders_customer_history_idx;
+ALTER TABLE orders DROP COLUMN IF EXISTS risk_signals;
+ALTER TABLE orders DROP COLUMN IF EXISTS risk_score;
@@ -0,0 +1,18 @@
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS risk_score   DOUBLE PRECISION;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS risk_signals JSONB;
+
+-- история покупателя для оценки риска и GraphQL customerHistory: последние заказы без сортировки
+CREATE INDEX IF NOT EXISTS orders_customer_history_idx ON orders (customer_id, date_created DESC, order_uid);
+
+-- заказы, отложенные консьюмером на ручную проверку; в orders они попадают после одобрения
+CREATE TABLE IF NOT EXISTS review_queue (
+  order_uid    TEXT PRIMARY KEY,
+  customer_id  TEXT NOT NULL,
+  payload      JSONB NOT NULL,
+  warnings     JSONB NOT NULL DEFAULT '[]',
+  risk_score   DOUBLE PRECISION NOT NULL,
+  risk_signals JSONB NOT NULL,
+  queued_at    TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+-- отложенные заказы тоже входят в историю покупателя (Velocity)
+CREATE INDEX IF NOT EXISTS review_queue_customer_idx ON review_queue (customer_id);
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"demo/orders/internal/model"
+	"demo/orders/internal/problem"
+	"demo/orders/internal/redact"
+	"demo/orders/internal/store"
+)
+
+type batchGetRequest struct {
+	OrderUIDs []string `json:"order_uids"`
+}
+
+type batchGetResponse struct {
+	Orders  []model.Order `json:"orders"`
+	Missing []string      `json:"missing"`
+}
+
+// POST /orders:batchGet — до maxIDs заказов за раз: сначала кэш, остальное одним запросом в БД.
+// Тело — не больше maxBody байт.
+func handleBatchGet(repo store.Repository, cache *Cache, maxIDs int, maxBody int64) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req batchGetRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBody)).Decode(&req); err != nil {
+			var mbe *http.MaxBytesError
+			if errors.As(err, &mbe) {
+				problem.Error(w, r, http.StatusRequestEntityTooLarge, "request body too large")
+				return
+			}
+			problem.InvalidRequest(w, r, "invalid JSON body")
+			return
+		}
+		ids := dedupIDs(req.OrderUIDs)
+		if len(ids) == 0 {
+			problem.InvalidRequest(w, r, "order_uids: required", problem.FieldError{Field: "order_uids", Message: "required"})
+			return
+		}
+		if len(ids) > maxIDs {
+			msg := fmt.Sprintf("at most %d ids per request", maxIDs)
+			problem.InvalidRequest(w, r, "order_uids: "+msg, problem.FieldError{Field: "order_uids", Message: msg})
+			return
+		}
+
+		found := make(map[string]model.Order, len(ids))
+		var misses []string
+		for _, id := range ids {
+			if o, ok := cache.Get(id); ok {
+				found[id] = o
+			} else {
+				misses = append(misses, id)
+			}
+		}
+		if len(misses) > 0 {
+			orders, err := repo.GetOrders(r.Context(), misses)
+			if err != nil {
+				slog.ErrorContext(r.Context(), "batch get", "err", err)
+				problem.Error(w, r, http.StatusInternalServerError, "internal error")
+				return
+			}
+			for _, o := range orders {
+				cache.Set(o.OrderUID, o)
+				found[o.OrderUID] = o
+			}
+		}
+
+		// ответ в порядке запроса
+		view := redact.ViewOf(r.Context())
+		resp := batchGetResponse{Orders: make([]model.Order, 0, len(found)), Missing: []string{}}
+		for _, id := range ids {
+			if o, ok := found[id]; ok {
+				resp.Orders = append(resp.Orders, redact.Apply(view, o))
+			} else {
+				resp.Missing = append(resp.Missing, id)
+			}
+		}
+		var body any = resp
+		if amountsDecimal(r) {
+			body = struct {
+				batchGetResponse
+				Orders []any `json:"orders"`
+			}{resp, decimalOrders(resp.Orders)}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Cache-Hits", fmt.Sprint(len(ids)-len(misses)))
+		if err := json.NewEncoder(w).Encode(body); err != nil {
+			slog.ErrorContext(r.Context(), "batch get encode", "err", err)
+		}
+	}
+}
+
+func dedupIDs(ids []string) []string {
+	seen := make(map[string]struct{}, len(ids))
+	out := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if id == "" {
+			continue
+		}
+		if _, ok := seen[id]; ok {
+			continue
+		}
+		seen[id] = struct{}{}
+		out = append(out, id)
+	}
+	return out
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"demo/orders/internal/model"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBatchGet_CacheAndDB(t *testing.T) {
+	repo, cache, mux := newTestMux(t)
+	hit := validOrder("cached0001")
+	fromDB := validOrder("fromdb0001")
+	cache.Set(hit.OrderUID, hit)
+	// в БД идут только промахи кэша, одним вызовом
+	repo.EXPECT().GetOrders(gomock.Any(), []string{"fromdb0001", "missing001"}).Return([]model.Order{fromDB}, nil)
+
+	body := `{"order_uids":["fromdb0001","cached0001","missing001","cached0001"]}`
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/orders:batchGet", strings.NewReader(body)))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	var resp batchGetResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Len(t, resp.Orders, 2)
+	require.Equal(t, "fromdb0001", resp.Orders[0].OrderUID)
+	require.Equal(t, "cached0001", resp.Orders[1].OrderUID)
+	require.Equal(t, []string{"missing001"}, resp.Missing)
+	require.Equal(t, "1", rec.Header().Get("X-Cache-Hits"))
+
+	_, ok := cache.Get("fromdb0001")
+	require.True(t, ok)
+}
+
+func TestBatchGet_TooMany(t *testing.T) {
+	t.Setenv("BATCH_GET_MAX", "2")
+	_, _, mux := newTestMux(t)
+
+	body := `{"order_uids":["a","b","c"]}`
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/orders:batchGet", strings.NewReader(body)))
+
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestBatchGet_BodyTooLarge(t *testing.T) {
+	_, _, mux := newTestMux(t)
+	body := `{"order_uids":["` + strings.Repeat("a", defaultMaxBody) + `"]}`
+	rec := httptest.NewRecorder()
+	handleBatchGet(nil, nil, 100, defaultMaxBody).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/orders:batchGet", strings.NewReader(body)))
+	require.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+
+	// и через весь стек — тоже 413, а не 400
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/orders:batchGet", strings.NewReader(body)))
+	require.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+}
+
+func TestMuxConfig_BatchMax(t *testing.T) {
+	for _, bad := range []string{"lots", "0", "-5"} {
+		t.Setenv("BATCH_GET_MAX", bad)
+		_, err := muxConfigFromEnv()
+		require.ErrorContains(t, err, "BATCH_GET_MAX")
+	}
+	t.Setenv("BATCH_GET_MAX", "")
+	cfg, err := muxConfigFromEnv()
+	require.NoError(t, err)
+	require.Equal(t, 100, cfg.batchMax)
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"demo/orders/internal/export"
+	"demo/orders/internal/model"
+	"demo/orders/internal/problem"
+	"demo/orders/internal/redact"
+	"demo/orders/internal/store"
+)
+
+const (
+	listDefaultLimit = 50
+	listMaxLimit     = 500
+	exportFlushEvery = 100
+)
+
+// parseOrderFilter читает общие фильтры листинга и выгрузки из query:
+// customer_id, delivery_service, currency, created_from, created_to (RFC 3339 или YYYY-MM-DD),
+// flagged=true и warning_code — заказы в карантине.
+func parseOrderFilter(r *http.Request) (store.OrderFilter, error) {
+	q := r.URL.Query()
+	f := store.OrderFilter{
+		CustomerID:      q.Get("customer_id"),
+		DeliveryService: q.Get("delivery_service"),
+		Currency:        q.Get("currency"),
+		WarningCode:     q.Get("warning_code"),
+	}
+	var err error
+	if s := q.Get("flagged"); s != "" {
+		if f.Flagged, err = strconv.ParseBool(s); err != nil {
+			return store.OrderFilter{}, errors.New("flagged: want true or false")
+		}
+	}
+	if f.CreatedFrom, err = parseTimeParam(q.Get("created_from")); err != nil {
+		return store.OrderFilter{}, fmt.Errorf("created_from: %w", err)
+	}
+	if f.CreatedTo, err = parseTimeParam(q.Get("created_to")); err != nil {
+		return store.OrderFilter{}, fmt.Errorf("created_to: %w", err)
+	}
+	return f, nil
+}
+
+func parseTimeParam(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.DateOnly, s)
+}
+
+// parseLimit читает ?limit= (по умолчанию listDefaultLimit); при ошибке отвечает 400 и возвращает false.
+func parseLimit(w http.ResponseWriter, r *http.Request) (int, bool) {
+	s := r.URL.Query().Get("limit")
+	if s == "" {
+		return listDefaultLimit, true
+	}
+	limit, err := strconv.Atoi(s)
+	if err != nil || limit <= 0 || limit > listMaxLimit {
+		msg := fmt.Sprintf("1..%d", listMaxLimit)
+		problem.InvalidRequest(w, r, "limit: "+msg, problem.FieldError{Field: "limit", Message: msg})
+		return 0, false
+	}
+	return limit, true
+}
+
+type listResponse struct {
+	Orders     []model.Order `json:"orders"`
+	NextCursor string        `json:"next_cursor,omitempty"`
+}
+
+// GET /orders — страница заказов по фильтрам; дальше листать через ?cursor=<next_cursor>.
+func handleListOrders(repo store.Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		f, err := parseOrderFilter(r)
+		if err != nil {
+			problem.InvalidRequest(w, r, err.Error())
+			return
+		}
+		limit, ok := parseLimit(w, r)
+		if !ok {
+			return
+		}
+
+		orders, err := repo.ListOrders(r.Context(), f, r.URL.Query().Get("cursor"), limit)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "list orders", "err", err)
+			problem.Error(w, r, http.StatusInternalServerError, "internal error")
+			return
+		}
+		view := redact.ViewOf(r.Context())
+		resp := listResponse{Orders: make([]model.Order, len(orders))}
+		for i, o := range orders {
+			resp.Orders[i] = redact.Apply(view, o)
+		}
+		if len(orders) == limit {
+			resp.NextCursor = orders[len(orders)-1].OrderUID
+		}
+		var body any = resp
+		if amountsDecimal(r) {
+			body = struct {
+				listResponse
+				Orders []any `json:"orders"`
+			}{resp, decimalOrders(resp.Orders)}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(body); err != nil {
+			slog.ErrorContext(r.Context(), "list orders encode", "err", err)
+		}
+	}
+}
+
+// GET /orders/export?format=ndjson|csv&rows=order|item — потоковая выгрузка с теми же фильтрами, что у /orders.
+// Читает через серверный курсор и периодически сбрасывает ответ клиенту, так что память не растёт.
+// 200 уходит вместе с первой строкой: ошибка до неё — problem-ответ, после — обрыв соединения,
+// чтобы клиент не принял обрезанную выгрузку за полную.
+func handleExportOrders(repo store.Repository, writeWait time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		f, err := parseOrderFilter(r)
+		if err != nil {
+			problem.InvalidRequest(w, r, err.Error())
+			return
+		}
+		format := r.URL.Query().Get("format")
+		if format == "" {
+			format = export.FormatNDJSON
+		}
+		out := &countingWriter{w: w}
+		ew, err := export.New(out, format, r.URL.Query().Get("rows"))
+		if err != nil {
+			problem.InvalidRequest(w, r, err.Error())
+			return
+		}
+
+		rc := http.NewResponseController(w)
+		extend := streamWriteDeadline(rc, writeWait)
+		w.Header().Set("Content-Type", export.ContentType(format))
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="orders-%s.%s"`, time.Now().UTC().Format("20060102-150405"), format))
+
+		view := redact.ViewOf(r.Context())
+		n := 0
+		err = repo.StreamOrders(r.Context(), f, func(o model.Order) error {
+			if err := ew.Write(redact.Apply(view, o)); err != nil {
+				return err
+			}
+			n++
+			if n == 1 || n%exportFlushEvery == 0 {
+				if err := ew.Flush(); err != nil {
+					return err
+				}
+				_ = rc.Flush()
+				extend()
+			}
+			return nil
+		})
+		if err != nil {
+			slog.ErrorContext(r.Context(), "export orders", "rows", n, "err", err)
+			if out.n == 0 {
+				w.Header().Del("Content-Disposition")
+				problem.Error(w, r, http.StatusInternalServerError, "internal error")
+				return
+			}
+			// 200 уже ушёл — остаётся только оборвать поток
+			panic(http.ErrAbortHandler)
+		}
+		if err := ew.Flush(); err != nil {
+			slog.ErrorContext(r.Context(), "export flush", "err", err)
+		}
+	}
+}
+
+// countingWriter считает байты, ушедшие в ответ: пока их нет, статус ещё можно поменять.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
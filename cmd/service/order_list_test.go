@@ -0,0 +1,105 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"demo/orders/internal/model"
+	"demo/orders/internal/store"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListOrders_FiltersAndCursor(t *testing.T) {
+	repo, _, mux := newTestMux(t)
+	want := store.OrderFilter{
+		CustomerID:  "test",
+		Currency:    "USD",
+		CreatedFrom: time.Date(2021, 11, 1, 0, 0, 0, 0, time.UTC),
+	}
+	repo.EXPECT().ListOrders(gomock.Any(), want, "order0001", 2).
+		Return([]model.Order{validOrder("order0002"), validOrder("order0003")}, nil)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet,
+		"/orders?customer_id=test&currency=USD&created_from=2021-11-01&cursor=order0001&limit=2", nil))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	var resp listResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Len(t, resp.Orders, 2)
+	require.Equal(t, "order0003", resp.NextCursor)
+}
+
+func TestListOrders_BadParams(t *testing.T) {
+	_, _, mux := newTestMux(t)
+	for _, q := range []string{"limit=0", "limit=100000", "created_to=yesterday"} {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/orders?"+q, nil))
+		require.Equal(t, http.StatusBadRequest, rec.Code, q)
+	}
+}
+
+func TestExportOrders_CSVItems(t *testing.T) {
+	repo, _, mux := newTestMux(t)
+	repo.EXPECT().StreamOrders(gomock.Any(), store.OrderFilter{DeliveryService: "meest"}, gomock.Any()).
+		DoAndReturn(func(_ any, _ store.OrderFilter, fn func(model.Order) error) error {
+			for _, id := range []string{"order0001", "order0002"} {
+				if err := fn(validOrder(id)); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/orders/export?format=csv&rows=item&delivery_service=meest", nil))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "text/csv; charset=utf-8", rec.Header().Get("Content-Type"))
+	require.Contains(t, rec.Header().Get("Content-Disposition"), ".csv")
+	lines := strings.Split(strings.TrimSpace(rec.Body.String()), "\n")
+	require.Len(t, lines, 3) // заголовок + по одному товару на заказ
+}
+
+func TestExportOrders_StreamError(t *testing.T) {
+	repo, _, mux := newTestMux(t)
+	stream := func(rows int) func() {
+		repo.EXPECT().StreamOrders(gomock.Any(), gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ any, _ store.OrderFilter, fn func(model.Order) error) error {
+				for i := 0; i < rows; i++ {
+					if err := fn(validOrder(fmt.Sprintf("order%04d", i))); err != nil {
+						return err
+					}
+				}
+				return errors.New("connection reset")
+			})
+		rec := httptest.NewRecorder()
+		return func() { mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/orders/export?format=csv", nil)) }
+	}
+
+	// до первой строки — нормальный 500, без вложения
+	rec := httptest.NewRecorder()
+	repo.EXPECT().StreamOrders(gomock.Any(), gomock.Any(), gomock.Any()).Return(errors.New("connection reset"))
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/orders/export?format=csv", nil))
+	require.Equal(t, http.StatusInternalServerError, rec.Code)
+	require.Empty(t, rec.Header().Get("Content-Disposition"))
+
+	// после первой строки 200 уже ушёл — соединение обрывается, а не закрывается как успешное
+	require.PanicsWithValue(t, http.ErrAbortHandler, stream(1))
+	require.PanicsWithValue(t, http.ErrAbortHandler, stream(3))
+}
+
+func TestExportOrders_UnknownFormat(t *testing.T) {
+	_, _, mux := newTestMux(t)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/orders/export?format=xml", nil))
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"log/slog"
+	"net/http"
+
+	"demo/orders/internal/orderschema"
+	"demo/orders/internal/problem"
+	"demo/orders/internal/validate"
+)
+
+// GET /schemas/order.json — JSON Schema заказа по встроенным правилам; ?set=имя — по набору
+// из VALIDATE_RULES_FILE. Схема описывает, как заказы принимает этот сервис: без KAFKA_STRICT_DECODE
+// неизвестные поля разрешены, правила полей из VALIDATE_WARN в неё не входят (они в x-warn).
+func handleOrderSchema(ing *ingestConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		opts := orderschema.Options{AllowUnknown: !ing.strictDecode, Warn: ing.validateOpts.Warn}
+		rules := validate.BuiltinRules()
+		if name := r.URL.Query().Get("set"); name != "" {
+			var ok bool
+			if rules, ok = ing.validateOpts.Rules.SetRules(name); !ok {
+				problem.Error(w, r, http.StatusNotFound, "rule set not found")
+				return
+			}
+		}
+		b, err := orderschema.Generate(rules, opts)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "order schema", "err", err)
+			problem.Error(w, r, http.StatusInternalServerError, "internal error")
+			return
+		}
+		w.Header().Set("Content-Type", "application/schema+json")
+		_, _ = w.Write(b)
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"demo/orders/internal/validate"
+)
+
+func TestOrderSchema(t *testing.T) {
+	rules, err := validate.LoadRules("../../internal/validate/builtin.yaml")
+	require.NoError(t, err)
+	ing := newIngest()
+	ing.strictDecode = true
+	ing.validateOpts = validate.Options{Rules: rules}
+	_, _, mux := newTestMuxWith(t, ing)
+	get := func(url string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, url, nil))
+		return rec
+	}
+
+	rec := get("/schemas/order.json")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "application/schema+json", rec.Header().Get("Content-Type"))
+	golden, err := os.ReadFile("../../api/order.schema.json")
+	require.NoError(t, err)
+	require.Equal(t, string(golden), rec.Body.String())
+
+	rec = get("/schemas/order.json?set=builtin")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, string(golden), rec.Body.String())
+
+	require.Equal(t, http.StatusNotFound, get("/schemas/order.json?set=nope").Code)
+
+	// без строгого разбора и с VALIDATE_WARN схема описывает мягкий режим
+	soft := newIngest()
+	soft.validateOpts = validate.Options{Rules: rules, Warn: []string{"delivery.email"}}
+	_, _, mux = newTestMuxWith(t, soft)
+	rec = get("/schemas/order.json")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var s struct {
+		AdditionalProperties *bool    `json:"additionalProperties"`
+		Warn                 []string `json:"x-warn"`
+		Properties           struct {
+			Delivery struct {
+				Properties map[string]map[string]any `json:"properties"`
+			} `json:"delivery"`
+		} `json:"properties"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &s))
+	require.Nil(t, s.AdditionalProperties)
+	require.Equal(t, []string{"delivery.email"}, s.Warn)
+	require.NotContains(t, s.Properties.Delivery.Properties["email"], "format")
+}
+
+func TestDecodeOrder_Strict(t *testing.T) {
+	o := validOrder("b563feb7b2b84b6test")
+	b, err := json.Marshal(o)
+	require.NoError(t, err)
+	var m map[string]any
+	require.NoError(t, json.Unmarshal(b, &m))
+	m["promo"] = "SALE"
+	extra, err := json.Marshal(m)
+	require.NoError(t, err)
+
+	_, err = newIngest().decodeOrder(extra)
+	require.NoError(t, err)
+
+	strict := &ingestConfig{strictDecode: true}
+	got, err := strict.decodeOrder(b)
+	require.NoError(t, err)
+	require.Equal(t, o.OrderUID, got.OrderUID)
+	_, err = strict.decodeOrder(extra)
+	require.ErrorContains(t, err, `unknown field "promo"`)
+	_, err = strict.decodeOrder(append(b, `{}`...))
+	require.Error(t, err)
+}
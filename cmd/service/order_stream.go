@@ -0,0 +1,188 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"demo/orders/internal/feed"
+	"demo/orders/internal/model"
+	"demo/orders/internal/redact"
+)
+
+const wsWriteWait = 10 * time.Second
+
+// streamWriteDeadline: WRITE_TIMEOUT сервера отсчитывается от начала запроса и оборвал бы долгий поток,
+// поэтому потоковые хендлеры зовут возвращённую функцию перед каждой порцией — дедлайн сдвигается
+// на writeWait от текущего момента. writeWait == 0 — без дедлайна записи.
+func streamWriteDeadline(rc *http.ResponseController, writeWait time.Duration) (extend func()) {
+	extend = func() {
+		var d time.Time
+		if writeWait > 0 {
+			d = time.Now().Add(writeWait)
+		}
+		_ = rc.SetWriteDeadline(d)
+	}
+	extend()
+	return extend
+}
+
+func parseFeedFilter(r *http.Request) feed.Filter {
+	q := r.URL.Query()
+	return feed.Filter{
+		CustomerID:      q.Get("customer_id"),
+		DeliveryService: q.Get("delivery_service"),
+		Currency:        q.Get("currency"),
+	}
+}
+
+// lastEventID берёт точку докачки из заголовка Last-Event-ID (его шлёт EventSource при переподключении)
+// или из ?last_event_id= (для WebSocket, где заголовок не выставить). Мусор — как будто его нет.
+func lastEventID(r *http.Request) uint64 {
+	s := r.Header.Get("Last-Event-ID")
+	if s == "" {
+		s = r.URL.Query().Get("last_event_id")
+	}
+	id, _ := strconv.ParseUint(s, 10, 64)
+	return id
+}
+
+// GET /orders/stream — Server-Sent Events: event "order" на каждый принятый консьюмером заказ.
+func handleOrderSSE(hub *feed.Hub, heartbeat, writeWait time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		rc := http.NewResponseController(w)
+		extend := streamWriteDeadline(rc, writeWait)
+		view := redact.ViewOf(r.Context())
+		backlog, events, cancel := hub.Subscribe(parseFeedFilter(r), lastEventID(r))
+		defer cancel()
+
+		h := w.Header()
+		h.Set("Content-Type", "text/event-stream")
+		h.Set("Cache-Control", "no-cache")
+		h.Set("X-Accel-Buffering", "no") // чтобы nginx не копил поток
+		w.WriteHeader(http.StatusOK)
+		if _, err := fmt.Fprint(w, "retry: 3000\n\n"); err != nil {
+			return
+		}
+		for _, ev := range backlog {
+			if err := writeSSE(w, view, ev); err != nil {
+				return
+			}
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+
+		t := time.NewTicker(heartbeat)
+		defer t.Stop()
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case ev, ok := <-events:
+				if !ok {
+					return
+				}
+				extend()
+				if err := writeSSE(w, view, ev); err != nil {
+					return
+				}
+			case <-t.C:
+				extend()
+				if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+					return
+				}
+			}
+			if err := rc.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func writeSSE(w http.ResponseWriter, view redact.View, ev feed.Event) error {
+	b, err := json.Marshal(redact.Apply(view, ev.Order))
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: order\ndata: %s\n\n", ev.ID, b)
+	return err
+}
+
+var wsUpgrader = websocket.Upgrader{}
+
+// wsMessage — кадр WebSocket-ленты. id строкой: значения не влезают в точность JS-number.
+type wsMessage struct {
+	ID    string      `json:"id"`
+	Order model.Order `json:"order"`
+}
+
+// GET /orders/ws — то же, что /orders/stream, но по WebSocket. Heartbeat — ping-кадры,
+// клиент, не ответивший pong за два интервала, отключается.
+func handleOrderWS(hub *feed.Hub, heartbeat time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		filter, after, view := parseFeedFilter(r), lastEventID(r), redact.ViewOf(r.Context())
+		conn, err := wsUpgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return // Upgrade сам ответил клиенту
+		}
+		defer conn.Close()
+
+		backlog, events, cancel := hub.Subscribe(filter, after)
+		defer cancel()
+
+		// читатель нужен, чтобы обрабатывать pong и close от клиента
+		done := make(chan struct{})
+		_ = conn.SetReadDeadline(time.Now().Add(2 * heartbeat))
+		conn.SetPongHandler(func(string) error {
+			return conn.SetReadDeadline(time.Now().Add(2 * heartbeat))
+		})
+		go func() {
+			defer close(done)
+			for {
+				if _, _, err := conn.ReadMessage(); err != nil {
+					return
+				}
+			}
+		}()
+
+		send := func(ev feed.Event) error {
+			_ = conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			return conn.WriteJSON(wsMessage{ID: strconv.FormatUint(ev.ID, 10), Order: redact.Apply(view, ev.Order)})
+		}
+		for _, ev := range backlog {
+			if err := send(ev); err != nil {
+				return
+			}
+		}
+
+		t := time.NewTicker(heartbeat)
+		defer t.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case ev, ok := <-events:
+				if !ok {
+					_ = conn.WriteControl(websocket.CloseMessage,
+						websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "subscriber too slow"),
+						time.Now().Add(wsWriteWait))
+					return
+				}
+				if err := send(ev); err != nil {
+					slog.WarnContext(r.Context(), "ws send", "err", err)
+					return
+				}
+			case <-t.C:
+				if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteWait)); err != nil {
+					return
+				}
+			}
+		}
+	}
+}
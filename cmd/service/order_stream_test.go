@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"demo/orders/internal/feed"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/require"
+)
+
+// readSSE читает события из потока до тех пор, пока не наберёт n штук (комментарии-пинги пропускаются).
+func readSSE(t *testing.T, sc *bufio.Scanner, n int) []map[string]string {
+	t.Helper()
+	var out []map[string]string
+	cur := map[string]string{}
+	for len(out) < n && sc.Scan() {
+		line := sc.Text()
+		if line == "" {
+			if cur["event"] != "" {
+				out = append(out, cur)
+			}
+			cur = map[string]string{}
+			continue
+		}
+		if k, v, ok := strings.Cut(line, ": "); ok {
+			cur[k] = v
+		}
+	}
+	require.Len(t, out, n)
+	return out
+}
+
+func TestOrderSSE_FilterAndResume(t *testing.T) {
+	hub := feed.NewHub(16, 16)
+	srv := httptest.NewServer(handleOrderSSE(hub, time.Hour, 0))
+	defer srv.Close()
+
+	first := hub.Publish(validOrder("order0001"))
+	hub.Publish(validOrder("order0002"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"?currency=usd", nil)
+	require.NoError(t, err)
+	req.Header.Set("Last-Event-ID", strconv.FormatUint(first.ID, 10))
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	other := validOrder("order0003")
+	other.Payment.Currency = "RUB"
+	hub.Publish(other) // не проходит фильтр
+	hub.Publish(validOrder("order0004"))
+
+	evs := readSSE(t, bufio.NewScanner(resp.Body), 2)
+	require.Contains(t, evs[0]["data"], `"order_uid":"order0002"`)
+	require.Contains(t, evs[1]["data"], `"order_uid":"order0004"`)
+	require.Equal(t, "order", evs[1]["event"])
+}
+
+func TestOrderWS(t *testing.T) {
+	hub := feed.NewHub(16, 16)
+	srv := httptest.NewServer(handleOrderWS(hub, time.Hour))
+	defer srv.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"?customer_id=test", nil)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// подписка оформляется после апгрейда — ждём её, публикуя до первого полученного кадра
+	got := make(chan wsMessage, 1)
+	go func() {
+		var m wsMessage
+		if err := conn.ReadJSON(&m); err == nil {
+			got <- m
+		}
+	}()
+	deadline := time.After(5 * time.Second)
+	for {
+		hub.Publish(validOrder("order0001"))
+		select {
+		case m := <-got:
+			require.Equal(t, "order0001", m.Order.OrderUID)
+			require.NotEmpty(t, m.ID)
+			return
+		case <-time.After(20 * time.Millisecond):
+		case <-deadline:
+			t.Fatal("no websocket message")
+		}
+	}
+}
+
+func TestMuxConfig_Heartbeat(t *testing.T) {
+	for _, bad := range []string{"0", "-1s", "often"} {
+		t.Setenv("FEED_HEARTBEAT", bad)
+		_, err := muxConfigFromEnv()
+		require.ErrorContains(t, err, "FEED_HEARTBEAT")
+	}
+	t.Setenv("FEED_HEARTBEAT", "")
+	cfg, err := muxConfigFromEnv()
+	require.NoError(t, err)
+	require.Equal(t, 15*time.Second, cfg.heartbeat)
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"demo/orders/internal/logging"
+	"demo/orders/internal/model"
+	"demo/orders/internal/problem"
+	"demo/orders/internal/store"
+)
+
+type warningsResponse struct {
+	OrderUID string          `json:"order_uid"`
+	Warnings []model.Warning `json:"warnings"`
+}
+
+// GET /order/{id}/warnings — предупреждения, с которыми сохранена текущая версия заказа.
+// Пустой список — заказ не в карантине.
+func handleOrderWarnings(repo store.Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+		logging.Annotate(r.Context(), slog.String("order_uid", id))
+		ws, found, err := repo.OrderWarnings(r.Context(), id)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "order warnings", "err", err)
+			problem.Error(w, r, http.StatusInternalServerError, "internal error")
+			return
+		}
+		if !found {
+			problem.Error(w, r, http.StatusNotFound, "order not found")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(warningsResponse{OrderUID: id, Warnings: ws}); err != nil {
+			slog.ErrorContext(r.Context(), "order warnings encode", "err", err)
+		}
+	}
+}
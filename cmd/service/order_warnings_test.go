@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"demo/orders/internal/model"
+	"demo/orders/internal/store"
+	"demo/orders/internal/validate"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrderWarnings(t *testing.T) {
+	repo, _, mux := newTestMux(t)
+	ws := []model.Warning{{Field: "delivery.phone", Pointer: "/delivery/phone", Code: "format", Message: "invalid"}}
+	repo.EXPECT().OrderWarnings(gomock.Any(), "b563feb7b2b84b6test").Return(ws, true, nil)
+	repo.EXPECT().OrderWarnings(gomock.Any(), "missing01").Return(nil, false, nil)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/order/b563feb7b2b84b6test/warnings", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var resp warningsResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Equal(t, warningsResponse{OrderUID: "b563feb7b2b84b6test", Warnings: ws}, resp)
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/order/missing01/warnings", nil))
+	require.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestListOrders_Flagged(t *testing.T) {
+	repo, _, mux := newTestMux(t)
+	repo.EXPECT().ListOrders(gomock.Any(), store.OrderFilter{Flagged: true}, "", listDefaultLimit).Return(nil, nil)
+	repo.EXPECT().ListOrders(gomock.Any(), store.OrderFilter{WarningCode: "mismatch"}, "", listDefaultLimit).Return(nil, nil)
+
+	for _, q := range []string{"flagged=true", "warning_code=mismatch"} {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/orders?"+q, nil))
+		require.Equal(t, http.StatusOK, rec.Code, q)
+	}
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/orders?flagged=maybe", nil))
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestPutOrder_Quarantined(t *testing.T) {
+	ing := newIngest()
+	ing.validateOpts = validate.Options{Warn: []string{"delivery.phone"}}
+	repo, _, mux := newTestMuxWith(t, ing)
+	o := validOrder("newOrder01")
+	o.Delivery.Phone = "call me"
+	var stored model.Order
+	repo.EXPECT().UpdateOrder(gomock.Any(), o.OrderUID, gomock.Any()).
+		DoAndReturn(func(_ any, _ string, fn store.UpdateFunc) (model.Order, error) {
+			var err error
+			stored, err = fn(model.Order{}, false)
+			return stored, err
+		})
+	body, err := json.Marshal(o)
+	require.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/order/"+o.OrderUID, strings.NewReader(string(body))))
+
+	require.Equal(t, http.StatusCreated, rec.Code)
+	require.Equal(t, []model.Warning{{Field: "delivery.phone", Pointer: "/delivery/phone", Code: validate.CodeFormat, Message: "invalid"}}, stored.Warnings)
+	require.NotContains(t, rec.Body.String(), "warnings", "в JSON заказа предупреждений нет")
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"demo/orders/internal/etag"
+	"demo/orders/internal/logging"
+	"demo/orders/internal/model"
+	"demo/orders/internal/patch"
+	"demo/orders/internal/problem"
+	"demo/orders/internal/store"
+	"demo/orders/internal/validate"
+)
+
+var (
+	errPrecondition = errors.New("etag mismatch")
+	errUIDMismatch  = errors.New("order_uid in body does not match path")
+)
+
+// writeRole — роль, которой можно менять заказы через PUT и PATCH; чтение её не требует.
+const writeRole = "writer"
+
+type validationError struct{ err error }
+
+func (e validationError) Error() string { return e.err.Error() }
+
+// PUT /order/{id} — полная замена заказа (или создание, если его ещё нет).
+func handlePutOrder(repo store.Repository, cache *Cache, ing *ingestConfig) http.HandlerFunc {
+	return requireRole(writeRole, func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+		logging.Annotate(r.Context(), slog.String("order_uid", id))
+		body, ok := readBody(w, r, ing.maxBody)
+		if !ok {
+			return
+		}
+		next, err := patch.Decode(body)
+		if err != nil {
+			problem.InvalidRequest(w, r, err.Error())
+			return
+		}
+		if next.OrderUID == "" {
+			next.OrderUID = id
+		}
+		if next.OrderUID != id {
+			problem.InvalidRequest(w, r, errUIDMismatch.Error(), problem.FieldError{Field: "order_uid", Message: "must match the path"})
+			return
+		}
+
+		created := false
+		var rep ingestReport
+		updated, err := repo.UpdateOrder(r.Context(), id, func(cur model.Order, found bool) (model.Order, error) {
+			if err := checkIfMatch(r, cur, found); err != nil {
+				return model.Order{}, err
+			}
+			created = !found
+			return ing.checkReplacement(r.Context(), id, next, &rep)
+		})
+		if err != nil {
+			writeUpdateError(w, r, id, err)
+			return
+		}
+		logging.Annotate(r.Context(), rep.logAttrs()...)
+		tag := cache.Set(id, updated)
+
+		status := http.StatusOK
+		if created {
+			status = http.StatusCreated
+		}
+		writeOrderJSON(w, r, status, updated, tag)
+	})
+}
+
+// PATCH /order/{id} — частичное обновление: merge patch или JSON Patch по Content-Type.
+func handlePatchOrder(repo store.Repository, cache *Cache, ing *ingestConfig) http.HandlerFunc {
+	return requireRole(writeRole, func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+		logging.Annotate(r.Context(), slog.String("order_uid", id))
+		body, ok := readBody(w, r, ing.maxBody)
+		if !ok {
+			return
+		}
+		ct := r.Header.Get("Content-Type")
+
+		var rep ingestReport
+		updated, err := repo.UpdateOrder(r.Context(), id, func(cur model.Order, found bool) (model.Order, error) {
+			if !found {
+				return model.Order{}, store.ErrNotFound
+			}
+			if err := checkIfMatch(r, cur, found); err != nil {
+				return model.Order{}, err
+			}
+			next, err := patch.Apply(cur, ct, body)
+			if err != nil {
+				return model.Order{}, err
+			}
+			return ing.checkReplacement(r.Context(), id, next, &rep)
+		})
+		if err != nil {
+			writeUpdateError(w, r, id, err)
+			return
+		}
+		logging.Annotate(r.Context(), rep.logAttrs()...)
+		tag := cache.Set(id, updated)
+		writeOrderJSON(w, r, http.StatusOK, updated, tag)
+	})
+}
+
+func readBody(w http.ResponseWriter, r *http.Request, maxBody int64) ([]byte, bool) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBody))
+	if err != nil {
+		var mbe *http.MaxBytesError
+		if errors.As(err, &mbe) {
+			problem.Error(w, r, http.StatusRequestEntityTooLarge, "request body too large")
+			return nil, false
+		}
+		problem.InvalidRequest(w, r, "read body failed")
+		return nil, false
+	}
+	return body, true
+}
+
+// checkIfMatch сравнивает If-Match с ETag текущей версии; без заголовка обновление безусловное.
+func checkIfMatch(r *http.Request, cur model.Order, found bool) error {
+	im := r.Header.Get("If-Match")
+	if im == "" {
+		return nil
+	}
+	if !found || !etag.MatchStrong(im, etag.Of(cur)) {
+		return errPrecondition
+	}
+	return nil
+}
+
+// checkReplacement нормализует и проверяет новую версию заказа; что исправлено и о чём
+// предупредили проверки, кладётся в rep (перезаписывается при повторе UpdateFunc).
+func (c *ingestConfig) checkReplacement(ctx context.Context, id string, next model.Order, rep *ingestReport) (model.Order, error) {
+	if next.OrderUID != id {
+		return model.Order{}, errUIDMismatch
+	}
+	next, r, err := c.prepareOrder(ctx, next)
+	if err != nil {
+		return model.Order{}, validationError{err}
+	}
+	*rep = r
+	return next, nil
+}
+
+func writeUpdateError(w http.ResponseWriter, r *http.Request, id string, err error) {
+	var ve validationError
+	switch {
+	case errors.As(err, &ve):
+		writeValidationProblem(w, r, ve.err)
+	case errors.Is(err, store.ErrNotFound):
+		problem.Error(w, r, http.StatusNotFound, "order not found")
+	case errors.Is(err, store.ErrUnderReview):
+		problem.Error(w, r, http.StatusConflict, "order is awaiting review: approve or reject it first")
+	case errors.Is(err, errPrecondition):
+		problem.Write(w, r, problem.Problem{Type: problem.TypeETagMismatch, Status: http.StatusPreconditionFailed,
+			Detail: "If-Match does not match the current version of the order"})
+	case errors.Is(err, errUIDMismatch):
+		problem.InvalidRequest(w, r, err.Error(), problem.FieldError{Field: "order_uid", Message: "must match the path"})
+	case errors.Is(err, patch.ErrUnsupportedMediaType):
+		problem.Error(w, r, http.StatusUnsupportedMediaType, "use application/merge-patch+json or application/json-patch+json")
+	case errors.Is(err, patch.ErrTestFailed):
+		problem.Write(w, r, problem.Problem{Type: problem.TypePatchTestFailed, Status: http.StatusConflict, Detail: err.Error()})
+	case errors.Is(err, patch.ErrInvalid):
+		problem.InvalidRequest(w, r, err.Error())
+	default:
+		slog.ErrorContext(r.Context(), "update order", "order_uid", id, "err", err)
+		problem.Error(w, r, http.StatusInternalServerError, "internal error")
+	}
+}
+
+// writeValidationProblem — 422 с разбором ошибок validate по полям. Значения полей в ответ
+// не попадают: клиент их и так прислал, а в ответе они — лишние персональные данные.
+func writeValidationProblem(w http.ResponseWriter, r *http.Request, err error) {
+	problem.Write(w, r, problem.Problem{Type: problem.TypeValidation, Status: http.StatusUnprocessableEntity,
+		Detail: "order does not pass validation", Errors: problemFields(validate.Fields(err))})
+}
+
+// problemFields — нарушения для ответа API; значения полей не отдаются.
+func problemFields(fes []validate.FieldError) []problem.FieldError {
+	var fields []problem.FieldError
+	for _, fe := range fes {
+		fields = append(fields, problem.FieldError{Field: fe.Field, Pointer: fe.Pointer, Code: fe.Code, Message: fe.Message})
+	}
+	return fields
+}
@@ -0,0 +1,307 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"demo/orders/internal/apispec"
+	"demo/orders/internal/auth"
+	"demo/orders/internal/etag"
+	"demo/orders/internal/feed"
+	"demo/orders/internal/model"
+	"demo/orders/internal/problem"
+	"demo/orders/internal/requestid"
+	"demo/orders/internal/store"
+	"demo/orders/internal/store/storemock"
+	"demo/orders/internal/validate"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func validOrder(id string) model.Order {
+	return model.Order{
+		OrderUID:    id,
+		TrackNumber: "WBILMTESTTRACK",
+		Entry:       "WBIL",
+		CustomerID:  "test",
+		DateCreated: time.Date(2021, 11, 26, 6, 22, 19, 0, time.UTC),
+		Delivery:    model.Delivery{Name: "Test Testov", City: "Kiryat Mozkin"},
+		Payment: model.Payment{
+			Transaction: id,
+			Currency:    "USD",
+			Amount:      1817,
+			PaymentDT:   1637907727,
+			GoodsTotal:  317,
+		},
+		Items: []model.Item{{
+			ChrtID: 9934930, TrackNumber: "WBILMTESTTRACK", Price: 453, Name: "Mascaras",
+			Sale: 30, Size: "0", TotalPrice: 317, NmID: 2389212, Status: 202,
+		}},
+	}
+}
+
+func decodeProblem(t *testing.T, rec *httptest.ResponseRecorder) problem.Problem {
+	t.Helper()
+	require.Equal(t, problem.ContentType, rec.Header().Get("Content-Type"))
+	var p problem.Problem
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &p))
+	require.Equal(t, rec.Code, p.Status)
+	return p
+}
+
+// mockUpdate заставляет мок вызывать UpdateFunc так, как это делает настоящий репозиторий.
+func mockUpdate(repo *storemock.MockRepository, id string, cur model.Order, found bool) {
+	repo.EXPECT().UpdateOrder(gomock.Any(), id, gomock.Any()).
+		DoAndReturn(func(_ any, _ string, fn store.UpdateFunc) (model.Order, error) {
+			return fn(cur, found)
+		})
+}
+
+// newTestMux собирает mux так же, как main, но со сверкой со спекой и запросов, и ответов.
+func newTestMux(t *testing.T) (*storemock.MockRepository, *Cache, http.Handler) {
+	return newTestMuxWith(t, newIngest())
+}
+
+// newTestMuxWith — newTestMux со своими настройками приёма заказов.
+func newTestMuxWith(t *testing.T, ing *ingestConfig) (*storemock.MockRepository, *Cache, http.Handler) {
+	ctrl := gomock.NewController(t)
+	repo := storemock.NewMockRepository(ctrl)
+	cache := NewCache(time.Minute, 100)
+	v, err := apispec.New(apispec.Full, ing.maxBody)
+	require.NoError(t, err)
+	return repo, cache, withMiddleware(makeHTTPMux(repo, cache, feed.NewHub(16, 16), testMuxConfig(t), ing, webFS), auth.Middleware(nil, nil), nil, v)
+}
+
+// newIngest — настройки приёма по умолчанию: без правил, мягких полей и оценки риска.
+func newIngest() *ingestConfig { return &ingestConfig{maxBody: defaultMaxBody} }
+
+// testMuxConfig — настройки ручек из окружения теста (t.Setenv), как их читает main.
+func testMuxConfig(t *testing.T) muxConfig {
+	t.Helper()
+	cfg, err := muxConfigFromEnv()
+	require.NoError(t, err)
+	return cfg
+}
+
+func TestPatchOrder_MergePatch(t *testing.T) {
+	repo, cache, mux := newTestMux(t)
+	cur := validOrder("b563feb7b2b84b6test")
+	mockUpdate(repo, cur.OrderUID, cur, true)
+
+	req := httptest.NewRequest(http.MethodPatch, "/order/"+cur.OrderUID, strings.NewReader(`{"delivery":{"city":"Haifa"}}`))
+	req.Header.Set("Content-Type", "application/merge-patch+json")
+	req.Header.Set("If-Match", etag.Of(cur))
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	var got model.Order
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+	require.Equal(t, "Haifa", got.Delivery.City)
+	require.Equal(t, etag.Of(got), rec.Header().Get("ETag"))
+
+	cached, ok := cache.Get(cur.OrderUID)
+	require.True(t, ok)
+	require.Equal(t, "Haifa", cached.Delivery.City)
+}
+
+func TestPatchOrder_IfMatchMismatch(t *testing.T) {
+	repo, _, mux := newTestMux(t)
+	cur := validOrder("b563feb7b2b84b6test")
+	mockUpdate(repo, cur.OrderUID, cur, true)
+
+	req := httptest.NewRequest(http.MethodPatch, "/order/"+cur.OrderUID, strings.NewReader(`{"locale":"ru"}`))
+	req.Header.Set("Content-Type", "application/merge-patch+json")
+	req.Header.Set("If-Match", `"stale"`)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusPreconditionFailed, rec.Code)
+	require.Equal(t, problem.TypeETagMismatch, decodeProblem(t, rec).Type)
+
+	// If-Match сравнивает сильно: слабый тег с тем же значением не подходит
+	mockUpdate(repo, cur.OrderUID, cur, true)
+	req = httptest.NewRequest(http.MethodPatch, "/order/"+cur.OrderUID, strings.NewReader(`{"locale":"ru"}`))
+	req.Header.Set("Content-Type", "application/merge-patch+json")
+	req.Header.Set("If-Match", "W/"+etag.Of(cur))
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusPreconditionFailed, rec.Code)
+}
+
+func TestPatchOrder_InvalidResultRejected(t *testing.T) {
+	repo, _, mux := newTestMux(t)
+	cur := validOrder("b563feb7b2b84b6test")
+	mockUpdate(repo, cur.OrderUID, cur, true)
+
+	ops := `[{"op":"replace","path":"/payment/currency","value":"dollars"}]`
+	req := httptest.NewRequest(http.MethodPatch, "/order/"+cur.OrderUID, strings.NewReader(ops))
+	req.Header.Set("Content-Type", "application/json-patch+json")
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	p := decodeProblem(t, rec)
+	require.Equal(t, problem.TypeValidation, p.Type)
+	require.Equal(t, []problem.FieldError{{Field: "payment.currency", Pointer: "/payment/currency", Code: "format", Message: "must be 3-letter ISO code"}}, p.Errors)
+	require.Equal(t, rec.Header().Get(requestid.Header), p.RequestID)
+}
+
+func TestPatchOrder_NotFound(t *testing.T) {
+	repo, _, mux := newTestMux(t)
+	mockUpdate(repo, "missing01", model.Order{}, false)
+
+	req := httptest.NewRequest(http.MethodPatch, "/order/missing01", strings.NewReader(`{}`))
+	req.Header.Set("Content-Type", "application/merge-patch+json")
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestPutOrder_Create(t *testing.T) {
+	repo, _, mux := newTestMux(t)
+	o := validOrder("newOrder01")
+	mockUpdate(repo, o.OrderUID, model.Order{}, false)
+
+	body, err := json.Marshal(o)
+	require.NoError(t, err)
+	req := httptest.NewRequest(http.MethodPut, "/order/"+o.OrderUID, strings.NewReader(string(body)))
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusCreated, rec.Code)
+	require.Equal(t, etag.Of(o), rec.Header().Get("ETag"))
+}
+
+func TestPutOrder_UIDMismatch(t *testing.T) {
+	_, _, mux := newTestMux(t)
+	body, err := json.Marshal(validOrder("otherOrder"))
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodPut, "/order/newOrder01", strings.NewReader(string(body)))
+	req.Header.Set(requestid.Header, "test-req-1")
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	p := decodeProblem(t, rec)
+	require.Equal(t, problem.TypeInvalidRequest, p.Type)
+	require.Equal(t, "/order/newOrder01", p.Instance)
+	require.Equal(t, "test-req-1", p.RequestID)
+	require.Equal(t, "order_uid", p.Errors[0].Field)
+}
+
+func TestPutOrder_FinancialChecks(t *testing.T) {
+	o := validOrder("newOrder01") // amount 1817 при goods_total 317 и без доставки — не сходится
+	body, err := json.Marshal(o)
+	require.NoError(t, err)
+	put := func(mux http.Handler) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/order/"+o.OrderUID, strings.NewReader(string(body))))
+		return rec
+	}
+
+	ing := newIngest()
+	ing.validateOpts = validate.Options{Financial: validate.Financial{Amount: validate.Check{Severity: validate.SeverityReject}}}
+	repo, _, mux := newTestMuxWith(t, ing)
+	mockUpdate(repo, o.OrderUID, model.Order{}, false)
+	rec := put(mux)
+	require.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	p := decodeProblem(t, rec)
+	require.Equal(t, "/payment/amount", p.Errors[0].Pointer)
+	require.Equal(t, validate.CodeMismatch, p.Errors[0].Code)
+
+	// warn: заказ сохраняется
+	ing = newIngest()
+	ing.validateOpts.Financial.Amount.Severity = validate.SeverityWarn
+	repo, _, mux = newTestMuxWith(t, ing)
+	mockUpdate(repo, o.OrderUID, model.Order{}, false)
+	require.Equal(t, http.StatusCreated, put(mux).Code)
+}
+
+func TestValidateOptionsFromEnv(t *testing.T) {
+	t.Setenv("VALIDATE_AMOUNT", "reject:1")
+	t.Setenv("VALIDATE_ITEM_TOTAL", "warn")
+	opts, err := validateOptionsFromEnv()
+	require.NoError(t, err)
+	require.Equal(t, validate.Financial{
+		Amount:     validate.Check{Severity: validate.SeverityReject, Tolerance: 1},
+		GoodsTotal: validate.Check{Severity: validate.SeverityOff},
+		ItemTotal:  validate.Check{Severity: validate.SeverityWarn},
+	}, opts.Financial)
+
+	require.Nil(t, opts.Rules)
+
+	t.Setenv("VALIDATE_RULES_FILE", "../../internal/validate/builtin.yaml")
+	opts, err = validateOptionsFromEnv()
+	require.NoError(t, err)
+	require.Equal(t, "builtin", opts.Rules.SetName(validOrder("b563feb7b2b84b6test")))
+
+	t.Setenv("VALIDATE_RULES_FILE", "missing.yaml")
+	_, err = validateOptionsFromEnv()
+	require.Error(t, err)
+
+	t.Setenv("VALIDATE_GOODS_TOTAL", "maybe")
+	_, err = validateOptionsFromEnv()
+	require.ErrorContains(t, err, "VALIDATE_GOODS_TOTAL")
+}
+
+func TestPutOrder_Normalized(t *testing.T) {
+	repo, cache, mux := newTestMux(t)
+	o := validOrder("newOrder01")
+	o.Delivery.Email = " Test@Gmail.COM"
+	o.Payment.Currency = "usd"
+	mockUpdate(repo, o.OrderUID, model.Order{}, false)
+
+	body, err := json.Marshal(o)
+	require.NoError(t, err)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/order/"+o.OrderUID, strings.NewReader(string(body))))
+
+	require.Equal(t, http.StatusCreated, rec.Code)
+	var got model.Order
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+	require.Equal(t, "test@gmail.com", got.Delivery.Email)
+	require.Equal(t, "USD", got.Payment.Currency)
+	cached, _ := cache.Get(o.OrderUID)
+	require.Equal(t, "USD", cached.Payment.Currency)
+}
+
+func TestIngestConfigFromEnv(t *testing.T) {
+	ing, err := ingestConfigFromEnv()
+	require.NoError(t, err)
+	require.EqualValues(t, defaultMaxBody, ing.maxBody)
+	require.False(t, ing.strictDecode)
+	require.Nil(t, ing.riskScorer)
+
+	t.Setenv("KAFKA_STRICT_DECODE", "1")
+	t.Setenv("HTTP_MAX_BODY_BYTES", "4096")
+	ing, err = ingestConfigFromEnv()
+	require.NoError(t, err)
+	require.True(t, ing.strictDecode)
+	require.EqualValues(t, 4096, ing.maxBody)
+
+	for _, bad := range []string{"0", "1MB"} {
+		t.Setenv("HTTP_MAX_BODY_BYTES", bad)
+		_, err = ingestConfigFromEnv()
+		require.ErrorContains(t, err, "HTTP_MAX_BODY_BYTES")
+	}
+}
+
+func TestNormalizeOptionsFromEnv(t *testing.T) {
+	t.Setenv("NORMALIZE_PHONE_COUNTRY", "7")
+	opts, err := normalizeOptionsFromEnv()
+	require.NoError(t, err)
+	require.Equal(t, "7", opts.PhoneCountry)
+	for _, bad := range []string{"+7", "0049", "ru", "07"} {
+		t.Setenv("NORMALIZE_PHONE_COUNTRY", bad)
+		_, err = normalizeOptionsFromEnv()
+		require.Error(t, err, bad)
+	}
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"os"
+
+	"demo/orders/internal/ratelimit"
+)
+
+// rateLimitFromEnv поднимает ограничение частоты из RATE_LIMIT_FILE; без файла лимитов нет (nil).
+// Файл перечитывается по SIGHUP и раз в RATE_LIMIT_RELOAD, если изменился (0 — только по сигналу).
+func rateLimitFromEnv(ctx context.Context, mux *http.ServeMux) (*ratelimit.Limiter, error) {
+	path := os.Getenv("RATE_LIMIT_FILE")
+	if path == "" {
+		slog.Info("ratelimit: disabled (set RATE_LIMIT_FILE)")
+		return nil, nil
+	}
+	l, err := ratelimit.Load(path, routePattern(mux))
+	if err != nil {
+		return nil, err
+	}
+	slog.Info("ratelimit: enabled", "file", path)
+
+	every := mustDur("10s", os.Getenv("RATE_LIMIT_RELOAD"))
+	go watchReload(ctx, "ratelimit", l, every)
+	return l, nil
+}
+
+// routePattern — ключ маршрута для лимитов: шаблон, под который запрос попадёт в mux.
+func routePattern(mux *http.ServeMux) func(*http.Request) string {
+	return func(r *http.Request) string {
+		_, pattern := mux.Handler(r)
+		return pattern
+	}
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"demo/orders/internal/feed"
+	"demo/orders/internal/logging"
+	"demo/orders/internal/model"
+	"demo/orders/internal/problem"
+	"demo/orders/internal/redact"
+	"demo/orders/internal/risk"
+	"demo/orders/internal/store"
+)
+
+// riskScorerFromEnv собирает оценку из RISK_SIGNALS — признаков с весами через запятую
+// ("amount:0.5,velocity:0.3,zip_region,sale:0.2"; вес по умолчанию 1). Параметры признаков:
+// RISK_AMOUNT_FACTOR, RISK_VELOCITY ("5/1h" — больше 5 заказов за час), RISK_SALE_MAX,
+// RISK_ZIP_REGIONS (CSV справочник индексов); RISK_REVIEW_SCORE — порог ручной проверки.
+func riskScorerFromEnv() (*risk.Scorer, error) {
+	spec := os.Getenv("RISK_SIGNALS")
+	if spec == "" {
+		return nil, nil
+	}
+	signals := map[string]func() (risk.Signal, error){
+		"amount": func() (risk.Signal, error) {
+			f, err := strconv.ParseFloat(env("RISK_AMOUNT_FACTOR", "5"), 64)
+			if err != nil || f <= 1 {
+				return nil, fmt.Errorf("RISK_AMOUNT_FACTOR: want a number > 1")
+			}
+			return risk.AmountOutlier{Factor: f, MinHistory: 3}, nil
+		},
+		"velocity": func() (risk.Signal, error) {
+			n, window, ok := strings.Cut(env("RISK_VELOCITY", "5/1h"), "/")
+			maxOrders, err := strconv.Atoi(n)
+			d, derr := time.ParseDuration(window)
+			if !ok || err != nil || derr != nil || maxOrders <= 0 || d <= 0 {
+				return nil, fmt.Errorf("RISK_VELOCITY: want orders/window, e.g. 5/1h")
+			}
+			return risk.Velocity{Window: d, Max: maxOrders}, nil
+		},
+		"zip_region": func() (risk.Signal, error) {
+			path := os.Getenv("RISK_ZIP_REGIONS")
+			if path == "" {
+				return risk.ZipRegion{}, nil
+			}
+			m, err := risk.LoadZipRegions(path)
+			if err != nil {
+				return nil, fmt.Errorf("RISK_ZIP_REGIONS: %w", err)
+			}
+			return risk.ZipRegion{Regions: m}, nil
+		},
+		"sale": func() (risk.Signal, error) {
+			n, err := strconv.Atoi(env("RISK_SALE_MAX", "70"))
+			if err != nil || n < 0 || n >= 100 {
+				return nil, fmt.Errorf("RISK_SALE_MAX: want 0..99")
+			}
+			return risk.SaleOutlier{Max: n}, nil
+		},
+	}
+
+	sc := &risk.Scorer{History: 50}
+	for _, part := range strings.Split(spec, ",") {
+		name, w, hasWeight := strings.Cut(strings.TrimSpace(part), ":")
+		mk, ok := signals[name]
+		if !ok {
+			return nil, fmt.Errorf("RISK_SIGNALS: unknown signal %q (want amount, velocity, zip_region, sale)", name)
+		}
+		weight := 1.0
+		if hasWeight {
+			var err error
+			if weight, err = strconv.ParseFloat(w, 64); err != nil || weight <= 0 {
+				return nil, fmt.Errorf("RISK_SIGNALS: %s: weight must be a positive number", name)
+			}
+		}
+		sig, err := mk()
+		if err != nil {
+			return nil, err
+		}
+		if v, ok := sig.(risk.Velocity); ok && v.Max >= sc.History {
+			return nil, fmt.Errorf("RISK_VELOCITY: at most %d orders per window", sc.History-1)
+		}
+		sc.Signals = append(sc.Signals, risk.Weighted{Signal: sig, Weight: weight})
+	}
+	if s := os.Getenv("RISK_REVIEW_SCORE"); s != "" {
+		var err error
+		if sc.Review, err = strconv.ParseFloat(s, 64); err != nil || sc.Review < 0 {
+			return nil, fmt.Errorf("RISK_REVIEW_SCORE: want a number >= 0")
+		}
+	}
+	slog.Info("risk scoring: enabled", "signals", spec, "review_score", sc.Review)
+	return sc, nil
+}
+
+// riskAttrs — оценка для лога: сумма и имена сработавших признаков, без причин (в них суммы и адреса).
+func riskAttrs(r *model.Risk) []slog.Attr {
+	if r == nil {
+		return nil
+	}
+	names := make([]string, len(r.Signals))
+	for i, s := range r.Signals {
+		names[i] = s.Name
+	}
+	return []slog.Attr{slog.Float64("risk_score", r.Score), slog.Any("risk_signals", names)}
+}
+
+type riskResponse struct {
+	OrderUID string      `json:"order_uid"`
+	Risk     *model.Risk `json:"risk"`
+}
+
+// reviewRole — роль, которой видны причины оценки и очередь ручной проверки и можно одобрять
+// и отклонять отложенные заказы.
+const reviewRole = "risk"
+
+// GET /order/{id}/risk — оценка риска текущей версии заказа; null — версия не оценивалась.
+// Причины в signals подсказывают, как обойти правила, поэтому только для роли risk.
+func handleOrderRisk(repo store.Repository) http.HandlerFunc {
+	return requireRole(reviewRole, func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+		logging.Annotate(r.Context(), slog.String("order_uid", id))
+		rk, found, err := repo.OrderRisk(r.Context(), id)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "order risk", "err", err)
+			problem.Error(w, r, http.StatusInternalServerError, "internal error")
+			return
+		}
+		if !found {
+			problem.Error(w, r, http.StatusNotFound, "order not found")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(riskResponse{OrderUID: id, Risk: rk}); err != nil {
+			slog.ErrorContext(r.Context(), "order risk encode", "err", err)
+		}
+	})
+}
+
+type reviewsResponse struct {
+	Reviews    []model.Review `json:"reviews"`
+	NextCursor string         `json:"next_cursor,omitempty"`
+}
+
+// GET /reviews — очередь ручной проверки страницами; дальше — ?cursor=<next_cursor>.
+func handleListReviews(repo store.Repository) http.HandlerFunc {
+	return requireRole(reviewRole, func(w http.ResponseWriter, r *http.Request) {
+		limit, ok := parseLimit(w, r)
+		if !ok {
+			return
+		}
+		reviews, err := repo.ListReviews(r.Context(), r.URL.Query().Get("cursor"), limit)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "list reviews", "err", err)
+			problem.Error(w, r, http.StatusInternalServerError, "internal error")
+			return
+		}
+		view := redact.ViewOf(r.Context())
+		resp := reviewsResponse{Reviews: reviews}
+		for i := range resp.Reviews {
+			resp.Reviews[i].Order = redact.Apply(view, resp.Reviews[i].Order)
+		}
+		if len(reviews) == limit {
+			resp.NextCursor = reviews[len(reviews)-1].Order.OrderUID
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			slog.ErrorContext(r.Context(), "list reviews encode", "err", err)
+		}
+	})
+}
+
+// POST /reviews/{id}/approve — записать отложенный заказ в orders, как если бы консьюмер принял его сразу.
+func handleApproveReview(repo store.Repository, cache *Cache, hub *feed.Hub) http.HandlerFunc {
+	return requireRole(reviewRole, func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+		logging.Annotate(r.Context(), slog.String("order_uid", id))
+		o, found, err := repo.ApproveReview(r.Context(), id)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "approve review", "err", err)
+			problem.Error(w, r, http.StatusInternalServerError, "internal error")
+			return
+		}
+		if !found {
+			problem.Error(w, r, http.StatusNotFound, "order is not awaiting review")
+			return
+		}
+		tag := cache.Set(id, o)
+		hub.Publish(o)
+		writeOrderJSON(w, r, http.StatusOK, o, tag)
+	})
+}
+
+// DELETE /reviews/{id} — отклонить отложенный заказ: он удаляется из очереди и никуда не пишется.
+func handleRejectReview(repo store.Repository) http.HandlerFunc {
+	return requireRole(reviewRole, func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+		logging.Annotate(r.Context(), slog.String("order_uid", id))
+		found, err := repo.RejectReview(r.Context(), id)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "reject review", "err", err)
+			problem.Error(w, r, http.StatusInternalServerError, "internal error")
+			return
+		}
+		if !found {
+			problem.Error(w, r, http.StatusNotFound, "order is not awaiting review")
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
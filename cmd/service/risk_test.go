@@ -0,0 +1,231 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/require"
+
+	"demo/orders/internal/apispec"
+	"demo/orders/internal/auth"
+	"demo/orders/internal/feed"
+	"demo/orders/internal/model"
+	"demo/orders/internal/risk"
+	"demo/orders/internal/store"
+	"demo/orders/internal/store/storemock"
+)
+
+func TestRiskScorerFromEnv(t *testing.T) {
+	sc, err := riskScorerFromEnv()
+	require.NoError(t, err)
+	require.Nil(t, sc, "без RISK_SIGNALS оценка выключена")
+
+	t.Setenv("RISK_SIGNALS", "amount:0.5, velocity,sale:0.2")
+	t.Setenv("RISK_VELOCITY", "3/10m")
+	t.Setenv("RISK_REVIEW_SCORE", "0.6")
+	sc, err = riskScorerFromEnv()
+	require.NoError(t, err)
+	require.Equal(t, []risk.Weighted{
+		{Signal: risk.AmountOutlier{Factor: 5, MinHistory: 3}, Weight: 0.5},
+		{Signal: risk.Velocity{Window: 10 * time.Minute, Max: 3}, Weight: 1},
+		{Signal: risk.SaleOutlier{Max: 70}, Weight: 0.2},
+	}, sc.Signals)
+	require.Equal(t, 0.6, sc.Review)
+
+	for env, val := range map[string]string{
+		"RISK_SIGNALS":      "geo",
+		"RISK_VELOCITY":     "100/1h",
+		"RISK_REVIEW_SCORE": "high",
+		"RISK_SALE_MAX":     "100",
+	} {
+		t.Run(env, func(t *testing.T) {
+			t.Setenv("RISK_SIGNALS", "velocity,sale")
+			t.Setenv(env, val)
+			_, err := riskScorerFromEnv()
+			require.ErrorContains(t, err, env)
+		})
+	}
+}
+
+func TestConsumeMessage_Risk(t *testing.T) {
+	ing := newIngest()
+	ing.riskScorer = &risk.Scorer{
+		Signals: []risk.Weighted{{Signal: risk.AmountOutlier{Factor: 5, MinHistory: 1}, Weight: 0.8}},
+		History: 50,
+		Review:  0.5,
+	}
+	repo := storemock.NewMockRepository(gomock.NewController(t))
+	o := validOrder("b563feb7b2b84b6test")
+	val, err := json.Marshal(o)
+	require.NoError(t, err)
+	consume := func() consumeResult {
+		return consumeMessage(context.Background(), kafka.Message{Value: val}, repo, NewCache(time.Minute, 100), feed.NewHub(16, 16), ing)
+	}
+	past := validOrder("pastOrder01")
+	past.Payment.Amount = 100 // 1817 — в 18 раз больше
+
+	repo.EXPECT().RiskHistory(gomock.Any(), o.CustomerID, 50).Return([]model.Order{past}, nil)
+	repo.EXPECT().QueueReview(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, got model.Order) error {
+		require.Equal(t, 0.8, got.Risk.Score)
+		require.Equal(t, "amount", got.Risk.Signals[0].Name)
+		return nil
+	})
+	res := consume()
+	require.Equal(t, "review", res.result)
+	require.Equal(t, 0.8, res.report.risk.Score)
+
+	// ниже порога — заказ пишется в orders вместе с оценкой
+	past.Payment.Amount = 1000
+	repo.EXPECT().RiskHistory(gomock.Any(), o.CustomerID, 50).Return([]model.Order{past}, nil)
+	repo.EXPECT().UpsertOrder(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, got model.Order) error {
+		require.Equal(t, &model.Risk{Signals: []model.RiskSignal{}}, got.Risk)
+		return nil
+	})
+	require.Equal(t, "stored", consume().result)
+
+	// история не читается — сообщение перечитаем
+	repo.EXPECT().RiskHistory(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, context.DeadlineExceeded)
+	require.Equal(t, "retry", consume().result)
+}
+
+func TestReviews(t *testing.T) {
+	repo, cache, mux := newTestMux(t)
+	do := func(method, url string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(method, url, nil))
+		return rec
+	}
+	o := validOrder("b563feb7b2b84b6test")
+	rk := model.Risk{Score: 0.8, Signals: []model.RiskSignal{{Name: "amount", Score: 0.8, Reason: "amount 1817 is 18.2x the customer's median 100 over 1 orders"}}}
+	queuedAt := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	repo.EXPECT().ListReviews(gomock.Any(), "", 1).
+		Return([]model.Review{{Order: o, Risk: rk, Warnings: []model.Warning{}, QueuedAt: queuedAt}}, nil)
+	rec := do(http.MethodGet, "/reviews?limit=1")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var list reviewsResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &list))
+	require.Equal(t, o.OrderUID, list.NextCursor)
+	require.Equal(t, rk, list.Reviews[0].Risk)
+	require.Equal(t, queuedAt, list.Reviews[0].QueuedAt)
+
+	rec = do(http.MethodGet, "/reviews?limit=0")
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	require.Equal(t, "limit", decodeProblem(t, rec).Errors[0].Field)
+
+	approved := o
+	approved.Risk, approved.UpdatedAt = &rk, queuedAt
+	repo.EXPECT().ApproveReview(gomock.Any(), o.OrderUID).Return(approved, true, nil)
+	rec = do(http.MethodPost, "/reviews/"+o.OrderUID+"/approve")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.NotEmpty(t, rec.Header().Get("ETag"))
+	cached, ok := cache.Get(o.OrderUID)
+	require.True(t, ok)
+	require.Equal(t, &rk, cached.Risk)
+
+	repo.EXPECT().ApproveReview(gomock.Any(), "missingOrder").Return(model.Order{}, false, nil)
+	require.Equal(t, http.StatusNotFound, do(http.MethodPost, "/reviews/missingOrder/approve").Code)
+
+	repo.EXPECT().RejectReview(gomock.Any(), o.OrderUID).Return(true, nil)
+	require.Equal(t, http.StatusNoContent, do(http.MethodDelete, "/reviews/"+o.OrderUID).Code)
+	repo.EXPECT().RejectReview(gomock.Any(), o.OrderUID).Return(false, nil)
+	require.Equal(t, http.StatusNotFound, do(http.MethodDelete, "/reviews/"+o.OrderUID).Code)
+}
+
+func TestOrderRisk(t *testing.T) {
+	repo, _, mux := newTestMux(t)
+	get := func(id string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/order/"+id+"/risk", nil))
+		return rec
+	}
+	rk := &model.Risk{Score: 0.2, Signals: []model.RiskSignal{{Name: "sale", Score: 0.2, Reason: "items[0].sale 85% is above 70%"}}}
+	repo.EXPECT().OrderRisk(gomock.Any(), "b563feb7b2b84b6test").Return(rk, true, nil)
+	rec := get("b563feb7b2b84b6test")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var got riskResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+	require.Equal(t, rk, got.Risk)
+
+	repo.EXPECT().OrderRisk(gomock.Any(), "notScored01").Return(nil, true, nil)
+	rec = get("notScored01")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.JSONEq(t, `{"order_uid":"notScored01","risk":null}`, rec.Body.String())
+
+	repo.EXPECT().OrderRisk(gomock.Any(), "missingOrder").Return(nil, false, nil)
+	require.Equal(t, http.StatusNotFound, get("missingOrder").Code)
+}
+
+func TestReviews_RequireRiskRole(t *testing.T) {
+	keys := filepath.Join(t.TempDir(), "keys")
+	lines := auth.HashAPIKey("s3cret") + " support-bot support\n" + auth.HashAPIKey("fraud") + " fraud-desk risk,analytics\n"
+	require.NoError(t, os.WriteFile(keys, []byte(lines), 0o600))
+	t.Setenv("AUTH_API_KEYS_FILE", keys)
+	repo := storemock.NewMockRepository(gomock.NewController(t))
+	mux := makeHTTPMux(repo, NewCache(time.Minute, 100), feed.NewHub(16, 16), testMuxConfig(t), newIngest(), webFS)
+	authn, err := authFromEnv(mux)
+	require.NoError(t, err)
+	v, err := apispec.New(apispec.Full, defaultMaxBody)
+	require.NoError(t, err)
+	h := withMiddleware(mux, authn, nil, v)
+	do := func(method, url, key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, url, nil)
+		req.Header.Set(auth.APIKeyHeader, key)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// без роли risk — 403, в БД ни одного вызова
+	rec := do(http.MethodPost, "/reviews/b563feb7b2b84b6test/approve", "s3cret")
+	require.Equal(t, http.StatusForbidden, rec.Code)
+	require.Equal(t, "role risk required", decodeProblem(t, rec).Detail)
+	require.Equal(t, http.StatusForbidden, do(http.MethodDelete, "/reviews/b563feb7b2b84b6test", "s3cret").Code)
+	// причины оценки и очередь тоже только для роли risk
+	require.Equal(t, http.StatusForbidden, do(http.MethodGet, "/order/b563feb7b2b84b6test/risk", "s3cret").Code)
+	require.Equal(t, http.StatusForbidden, do(http.MethodGet, "/reviews", "s3cret").Code)
+
+	repo.EXPECT().OrderRisk(gomock.Any(), "b563feb7b2b84b6test").Return(&model.Risk{Score: 0.8, Signals: []model.RiskSignal{{Name: "sale", Score: 0.8, Reason: "items[0].sale 85% is above 70%"}}}, true, nil)
+	require.Equal(t, http.StatusOK, do(http.MethodGet, "/order/b563feb7b2b84b6test/risk", "fraud").Code)
+	repo.EXPECT().ListReviews(gomock.Any(), "", gomock.Any()).Return([]model.Review{}, nil)
+	require.Equal(t, http.StatusOK, do(http.MethodGet, "/reviews", "fraud").Code)
+
+	repo.EXPECT().ApproveReview(gomock.Any(), "b563feb7b2b84b6test").Return(validOrder("b563feb7b2b84b6test"), true, nil)
+	require.Equal(t, http.StatusOK, do(http.MethodPost, "/reviews/b563feb7b2b84b6test/approve", "fraud").Code)
+	repo.EXPECT().RejectReview(gomock.Any(), "b563feb7b2b84b6test").Return(true, nil)
+	require.Equal(t, http.StatusNoContent, do(http.MethodDelete, "/reviews/b563feb7b2b84b6test", "fraud").Code)
+}
+
+func TestWriteOrder_UnderReview(t *testing.T) {
+	repo, cache, mux := newTestMux(t)
+	o := validOrder("b563feb7b2b84b6test")
+	body, err := json.Marshal(o)
+	require.NoError(t, err)
+
+	// PUT и PATCH заказа из очереди проверки — 409, в обход approve/reject он не пишется
+	repo.EXPECT().UpdateOrder(gomock.Any(), o.OrderUID, gomock.Any()).Return(model.Order{}, store.ErrUnderReview).Times(2)
+	req := httptest.NewRequest(http.MethodPut, "/order/"+o.OrderUID, bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusConflict, rec.Code)
+	require.Contains(t, decodeProblem(t, rec).Detail, "awaiting review")
+
+	req = httptest.NewRequest(http.MethodPatch, "/order/"+o.OrderUID, strings.NewReader(`{"locale":"ru"}`))
+	req.Header.Set("Content-Type", "application/merge-patch+json")
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusConflict, rec.Code)
+	_, cached := cache.Get(o.OrderUID)
+	require.False(t, cached)
+}
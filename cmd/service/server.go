@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+
+	"demo/orders/internal/tlsconfig"
+)
+
+// httpServerFromEnv собирает http.Server: таймауты (READ_HEADER_TIMEOUT, READ_TIMEOUT, WRITE_TIMEOUT,
+// IDLE_TIMEOUT), предел заголовков (HTTP_MAX_HEADER_BYTES) и тела (maxBody), TLS/mTLS из TLS_*
+// и HTTP/2 из HTTP2. Сертификаты перечитываются по SIGHUP и раз в TLS_RELOAD.
+func httpServerFromEnv(ctx context.Context, addr string, h http.Handler, maxBody int64) (*http.Server, error) {
+	maxHeader := mustInt("1048576", os.Getenv("HTTP_MAX_HEADER_BYTES"))
+	if maxHeader <= 0 {
+		return nil, errors.New("HTTP_MAX_HEADER_BYTES: want a positive number of bytes")
+	}
+	srv := &http.Server{
+		Addr:              addr,
+		ReadHeaderTimeout: mustDur("5s", os.Getenv("READ_HEADER_TIMEOUT")),
+		ReadTimeout:       mustDur("5s", os.Getenv("READ_TIMEOUT")),
+		WriteTimeout:      mustDur("10s", os.Getenv("WRITE_TIMEOUT")),
+		IdleTimeout:       mustDur("60s", os.Getenv("IDLE_TIMEOUT")),
+		MaxHeaderBytes:    maxHeader,
+	}
+	h = limitBody(h, maxBody)
+
+	mode := env("HTTP2", "on") // on|off|h2c
+	if mode != "on" && mode != "off" && mode != "h2c" {
+		return nil, fmt.Errorf("unknown HTTP2=%q (want on|off|h2c)", mode)
+	}
+	certFile, keyFile, caFile := os.Getenv("TLS_CERT_FILE"), os.Getenv("TLS_KEY_FILE"), os.Getenv("TLS_CLIENT_CA_FILE")
+	if certFile == "" && keyFile == "" {
+		if caFile != "" {
+			return nil, errors.New("TLS_CLIENT_CA_FILE needs TLS_CERT_FILE and TLS_KEY_FILE")
+		}
+		if mode == "h2c" {
+			// HTTP/2 без TLS — за прокси, который сам терминирует TLS
+			h = h2c.NewHandler(h, &http2.Server{IdleTimeout: srv.IdleTimeout})
+		}
+		srv.Handler = h
+		return srv, nil
+	}
+	if mode == "h2c" {
+		return nil, errors.New("HTTP2=h2c is for plaintext; with TLS use HTTP2=on")
+	}
+
+	clientAuth, err := parseClientAuth(env("TLS_CLIENT_AUTH", "require"))
+	if err != nil {
+		return nil, err
+	}
+	protos := []string{"h2", "http/1.1"}
+	if mode == "off" {
+		protos = []string{"http/1.1"}
+		srv.TLSNextProto = map[string]func(*http.Server, *tls.Conn, http.Handler){}
+	}
+	certs, err := tlsconfig.Load(tlsconfig.Files{Cert: certFile, Key: keyFile, ClientCA: caFile, ClientAuth: clientAuth}, protos...)
+	if err != nil {
+		return nil, err
+	}
+	srv.TLSConfig = certs.Config()
+	srv.Handler = h
+	go watchReload(ctx, "tls", certs, mustDur("1m", os.Getenv("TLS_RELOAD")))
+	slog.Info("http: tls", "cert", certFile, "client_ca", caFile, "http2", mode)
+	return srv, nil
+}
+
+func parseClientAuth(s string) (tls.ClientAuthType, error) {
+	switch s {
+	case "require":
+		return tls.RequireAndVerifyClientCert, nil
+	case "optional":
+		return tls.VerifyClientCertIfGiven, nil
+	}
+	return 0, fmt.Errorf("unknown TLS_CLIENT_AUTH=%q (want require|optional)", s)
+}
+
+// serveHTTP — ListenAndServe или ListenAndServeTLS, смотря по конфигурации.
+func serveHTTP(srv *http.Server) error {
+	if srv.TLSConfig != nil {
+		return srv.ListenAndServeTLS("", "")
+	}
+	return srv.ListenAndServe()
+}
+
+// limitBody ограничивает тело любого запроса, в том числе маршрутов, которые не сверяются со спекой.
+func limitBody(next http.Handler, max int64) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Body != nil && r.Body != http.NoBody {
+			r.Body = http.MaxBytesReader(w, r.Body, max)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// reloader — то, что умеет перечитать свой файл и сказать, что он изменился.
+type reloader interface {
+	Reload() (changed bool, err error)
+	Stale() bool
+}
+
+// watchReload перечитывает r по SIGHUP и раз в every, если файл изменился (every == 0 — только по сигналу).
+// Ошибка перечитывания не роняет сервис: остаётся прежняя конфигурация.
+func watchReload(ctx context.Context, name string, r reloader, every time.Duration) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	var tick <-chan time.Time
+	if every > 0 {
+		t := time.NewTicker(every)
+		defer t.Stop()
+		tick = t.C
+	}
+	reload := func() {
+		changed, err := r.Reload()
+		switch {
+		case err != nil:
+			slog.Error("reload failed, keeping previous config", "config", name, "err", err)
+		case changed:
+			slog.Info("config reloaded", "config", name)
+		}
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			reload()
+		case <-tick:
+			if r.Stale() {
+				reload()
+			}
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/http2"
+
+	"demo/orders/internal/feed"
+)
+
+// startServer собирает сервер из окружения и слушает случайный порт.
+func startServer(t *testing.T, h http.Handler) string {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	srv, err := httpServerFromEnv(ctx, "", h, defaultMaxBody)
+	require.NoError(t, err)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() { _ = srv.Serve(ln) }()
+	t.Cleanup(func() { _ = srv.Close() })
+	return "http://" + ln.Addr().String()
+}
+
+func TestHTTPServer_FromEnv(t *testing.T) {
+	t.Setenv("READ_TIMEOUT", "7s")
+	t.Setenv("WRITE_TIMEOUT", "0")
+	t.Setenv("HTTP_MAX_HEADER_BYTES", "4096")
+	srv, err := httpServerFromEnv(context.Background(), ":0", http.NotFoundHandler(), defaultMaxBody)
+	require.NoError(t, err)
+	require.Equal(t, 7*time.Second, srv.ReadTimeout)
+	require.Equal(t, time.Duration(0), srv.WriteTimeout)
+	require.Equal(t, 60*time.Second, srv.IdleTimeout)
+	require.Equal(t, 4096, srv.MaxHeaderBytes)
+	require.Nil(t, srv.TLSConfig)
+
+	for k, v := range map[string]string{"HTTP2": "maybe", "TLS_CLIENT_CA_FILE": "ca.crt", "HTTP_MAX_HEADER_BYTES": "-1"} {
+		t.Run(k, func(t *testing.T) {
+			t.Setenv(k, v)
+			_, err := httpServerFromEnv(context.Background(), ":0", http.NotFoundHandler(), defaultMaxBody)
+			require.Error(t, err)
+		})
+	}
+}
+
+// Лента живёт дольше WRITE_TIMEOUT: хендлер сдвигает дедлайн записи перед каждой порцией.
+func TestHTTPServer_StreamOutlivesTimeouts(t *testing.T) {
+	t.Setenv("READ_TIMEOUT", "100ms")
+	t.Setenv("WRITE_TIMEOUT", "100ms")
+	hub := feed.NewHub(16, 16)
+	url := startServer(t, handleOrderSSE(hub, 20*time.Millisecond, 100*time.Millisecond))
+
+	resp, err := http.Get(url)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	time.Sleep(400 * time.Millisecond)
+	hub.Publish(validOrder("order0001"))
+	evs := readSSE(t, bufio.NewScanner(resp.Body), 1)
+	require.Contains(t, evs[0]["data"], `"order_uid":"order0001"`)
+}
+
+func TestHTTPServer_H2C(t *testing.T) {
+	t.Setenv("HTTP2", "h2c")
+	url := startServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { _, _ = w.Write([]byte(r.Proto)) }))
+
+	c := &http.Client{Transport: &http2.Transport{
+		AllowHTTP: true,
+		DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, network, addr)
+		},
+	}}
+	resp, err := c.Get(url)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, 2, resp.ProtoMajor)
+}
+
+func TestLimitBody(t *testing.T) {
+	h := limitBody(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var mbe *http.MaxBytesError
+		if _, err := io.ReadAll(r.Body); errors.As(err, &mbe) {
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+		}
+	}), 16)
+	for body, code := range map[string]int{"0123456789abcdef": http.StatusOK, "0123456789abcdefX": http.StatusRequestEntityTooLarge} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(body)))
+		require.Equal(t, code, rec.Code, body)
+	}
+}
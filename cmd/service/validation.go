@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"demo/orders/internal/model"
+	"demo/orders/internal/normalize"
+	"demo/orders/internal/patch"
+	"demo/orders/internal/risk"
+	"demo/orders/internal/validate"
+)
+
+// defaultMaxBody — предел тела запроса без HTTP_MAX_BODY_BYTES.
+const defaultMaxBody = 1 << 20
+
+// ingestConfig — как сервис принимает заказы: консьюмер, PUT/PATCH, /validate и CLI. main собирает
+// его из окружения и передаёт в хендлеры и consumeMessage, как repo и cache; после старта не меняется.
+type ingestConfig struct {
+	normalizeOpts normalize.Options
+	validateOpts  validate.Options
+	// strictDecode — KAFKA_STRICT_DECODE=1: сообщение с неизвестными полями отклоняется, а не
+	// записывается без них.
+	strictDecode bool
+	riskScorer   *risk.Scorer // оценка риска в консьюмере; nil — выключена
+	maxBody      int64        // предел тела запроса, HTTP_MAX_BODY_BYTES
+}
+
+// ingestConfigFromEnv читает NORMALIZE_*, VALIDATE_*, KAFKA_STRICT_DECODE, RISK_* и HTTP_MAX_BODY_BYTES.
+// Только читает: перечитывание файла правил запускает watchValidateRules.
+func ingestConfigFromEnv() (*ingestConfig, error) {
+	var ing ingestConfig
+	var err error
+	if ing.normalizeOpts, err = normalizeOptionsFromEnv(); err != nil {
+		return nil, err
+	}
+	if ing.validateOpts, err = validateOptionsFromEnv(); err != nil {
+		return nil, err
+	}
+	if ing.strictDecode, err = envBool("KAFKA_STRICT_DECODE", false); err != nil {
+		return nil, err
+	}
+	if ing.riskScorer, err = riskScorerFromEnv(); err != nil {
+		return nil, err
+	}
+	maxBody, err := envPositiveInt("HTTP_MAX_BODY_BYTES", defaultMaxBody)
+	if err != nil {
+		return nil, err
+	}
+	ing.maxBody = int64(maxBody)
+	return &ing, nil
+}
+
+// decodeOrder разбирает заказ так же, как консьюмер: неизвестные поля игнорируются (или, при
+// strictDecode, отклоняются), без order_uid заказ некуда записать.
+func (c *ingestConfig) decodeOrder(b []byte) (model.Order, error) {
+	o, err := c.decodeOrderBody(b)
+	if err != nil {
+		return model.Order{}, err
+	}
+	if o.OrderUID == "" {
+		return model.Order{}, errors.New("order_uid is empty")
+	}
+	return o, nil
+}
+
+// decodeOrderBody — только разбор JSON, без проверки order_uid: пробному прогону её ошибка нужна
+// в общем итоге проверки, а не отказом разбирать заказ.
+func (c *ingestConfig) decodeOrderBody(b []byte) (model.Order, error) {
+	if c.strictDecode {
+		return patch.Decode(b)
+	}
+	var o model.Order
+	err := json.Unmarshal(b, &o)
+	return o, err
+}
+
+// ingestReport — что нормализация и проверка сказали о принятом заказе.
+type ingestReport struct {
+	changes  []normalize.Change
+	warnings []validate.FieldError
+	risk     *model.Risk // только в консьюмере, если оценка включена
+}
+
+// logAttrs — отчёт для лога: пути исправленных полей и коды предупреждений, без значений.
+func (r ingestReport) logAttrs() []slog.Attr {
+	var attrs []slog.Attr
+	if len(r.changes) > 0 {
+		fields := make([]string, len(r.changes))
+		for i, c := range r.changes {
+			fields[i] = c.Pointer
+		}
+		attrs = append(attrs, slog.Any("normalized", fields))
+	}
+	if len(r.warnings) > 0 {
+		attrs = append(attrs, slog.Any("warnings", violationCodes(r.warnings)))
+	}
+	return append(attrs, riskAttrs(r.risk)...)
+}
+
+// prepareOrder нормализует и проверяет заказ перед записью — общий путь консьюмера и HTTP.
+// Предупреждения проверки кладутся в o.Warnings: заказ сохранится вместе с ними, в карантине.
+func (c *ingestConfig) prepareOrder(ctx context.Context, o model.Order) (model.Order, ingestReport, error) {
+	var rep ingestReport
+	_ = step(ctx, "normalize", func(context.Context) error {
+		o, rep.changes = normalize.Order(o, c.normalizeOpts)
+		return nil
+	})
+	err := step(ctx, "validate", func(context.Context) (err error) {
+		rep.warnings, err = validate.Validate(o, c.validateOpts)
+		return err
+	})
+	o.Warnings = storedWarnings(rep.warnings)
+	return o, rep, err
+}
+
+// storedWarnings — предупреждения в том виде, в каком они хранятся с заказом: без значений полей.
+func storedWarnings(fes []validate.FieldError) []model.Warning {
+	if len(fes) == 0 {
+		return nil
+	}
+	out := make([]model.Warning, len(fes))
+	for i, fe := range fes {
+		out[i] = model.Warning{Field: fe.Field, Pointer: fe.Pointer, Code: fe.Code, Message: fe.Message}
+	}
+	return out
+}
+
+// normalizeOptionsFromEnv читает NORMALIZE_PHONE_COUNTRY — код страны для телефонов без
+// международного префикса.
+func normalizeOptionsFromEnv() (normalize.Options, error) {
+	cc := os.Getenv("NORMALIZE_PHONE_COUNTRY")
+	if len(cc) > 3 || strings.Trim(cc, "0123456789") != "" || strings.HasPrefix(cc, "0") {
+		return normalize.Options{}, fmt.Errorf("NORMALIZE_PHONE_COUNTRY=%q: want a country calling code without +, e.g. 7", cc)
+	}
+	return normalize.Options{PhoneCountry: cc}, nil
+}
+
+// validateOptionsFromEnv читает сверки сумм: VALIDATE_AMOUNT, VALIDATE_GOODS_TOTAL,
+// VALIDATE_ITEM_TOTAL — "off", "warn" или "reject", с допуском через двоеточие ("warn:2"),
+// мягкие поля VALIDATE_WARN ("delivery.phone,items[*].sale") и наборы правил из VALIDATE_RULES_FILE.
+// Только читает: перечитывание файла запускает watchValidateRules.
+func validateOptionsFromEnv() (validate.Options, error) {
+	var opts validate.Options
+	for _, c := range []struct {
+		env string
+		dst *validate.Check
+	}{
+		{"VALIDATE_AMOUNT", &opts.Financial.Amount},
+		{"VALIDATE_GOODS_TOTAL", &opts.Financial.GoodsTotal},
+		{"VALIDATE_ITEM_TOTAL", &opts.Financial.ItemTotal},
+	} {
+		chk, err := validate.ParseCheck(os.Getenv(c.env))
+		if err != nil {
+			return validate.Options{}, fmt.Errorf("%s: %w", c.env, err)
+		}
+		*c.dst = chk
+	}
+	warn, err := validate.ParseWarn(os.Getenv("VALIDATE_WARN"))
+	if err != nil {
+		return validate.Options{}, fmt.Errorf("VALIDATE_WARN: %w", err)
+	}
+	opts.Warn = warn
+
+	path := os.Getenv("VALIDATE_RULES_FILE")
+	if path == "" {
+		return opts, nil
+	}
+	rules, err := validate.LoadRules(path)
+	if err != nil {
+		return validate.Options{}, err
+	}
+	opts.Rules = rules
+	return opts, nil
+}
+
+// watchValidateRules перечитывает файл правил по SIGHUP и раз в VALIDATE_RULES_RELOAD; без файла — ничего.
+func watchValidateRules(ctx context.Context, opts validate.Options) {
+	if opts.Rules == nil {
+		return
+	}
+	go watchReload(ctx, "validation rules", opts.Rules, mustDur("10s", os.Getenv("VALIDATE_RULES_RELOAD")))
+	slog.Info("validation rules: enabled", "file", os.Getenv("VALIDATE_RULES_FILE"))
+}
+
+// violationCodes — нарушения для лога: "/payment/currency=format". Значений полей здесь нет —
+// в них персональные данные.
+func violationCodes(fes []validate.FieldError) []string {
+	out := make([]string, len(fes))
+	for i, fe := range fes {
+		out[i] = fe.Pointer + "=" + fe.Code
+	}
+	return out
+}
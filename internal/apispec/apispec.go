@@ -0,0 +1,271 @@
+// Package apispec — OpenAPI-описание HTTP API сервиса и middleware, сверяющее с ним запросы
+// (а в тестовом режиме и ответы).
+package apispec
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/legacy"
+
+	"demo/orders/internal/problem"
+	"demo/orders/internal/validate"
+)
+
+// JSON — документ OpenAPI 3, как он отдаётся на /openapi.json.
+//
+//go:embed openapi.json
+var JSON []byte
+
+// Mode — что сверять со спецификацией.
+type Mode int
+
+const (
+	Off      Mode = iota
+	Requests      // только запросы — режим по умолчанию
+	Full          // запросы и ответы; ответы буферизуются, поэтому только для тестов
+)
+
+// ParseMode разбирает значение OPENAPI_VALIDATE: off | request | full.
+func ParseMode(s string) (Mode, error) {
+	switch strings.ToLower(s) {
+	case "off":
+		return Off, nil
+	case "", "request":
+		return Requests, nil
+	case "full":
+		return Full, nil
+	}
+	return Off, fmt.Errorf("unknown OPENAPI_VALIDATE=%q (use off|request|full)", s)
+}
+
+// Load разбирает и проверяет встроенный документ.
+func Load() (*openapi3.T, error) {
+	doc, err := openapi3.NewLoader().LoadFromData(JSON)
+	if err != nil {
+		return nil, fmt.Errorf("openapi: %w", err)
+	}
+	if err := doc.Validate(context.Background()); err != nil {
+		return nil, fmt.Errorf("openapi: %w", err)
+	}
+	return doc, nil
+}
+
+// Validator сверяет трафик со спецификацией.
+type Validator struct {
+	router  routers.Router
+	mode    Mode
+	maxBody int64
+}
+
+// New готовит валидатор; maxBody — предел тела запроса, который читается для проверки.
+func New(mode Mode, maxBody int64) (*Validator, error) {
+	doc, err := Load()
+	if err != nil {
+		return nil, err
+	}
+	router, err := legacy.NewRouter(doc)
+	if err != nil {
+		return nil, fmt.Errorf("openapi router: %w", err)
+	}
+	return &Validator{router: router, mode: mode, maxBody: maxBody}, nil
+}
+
+var filterOptions = &openapi3filter.Options{AuthenticationFunc: openapi3filter.NoopAuthenticationFunc}
+
+func init() {
+	// без этого в текст ошибки попадает дамп схемы и значения — клиенту он ни к чему
+	openapi3.SchemaErrorDetailsDisabled = true
+	// GET /schemas/order.json отдаёт JSON Schema со своим типом
+	openapi3filter.RegisterBodyDecoder("application/schema+json", openapi3filter.JSONBodyDecoder)
+}
+
+// Wrap возвращает next, перед которым стоит проверка запроса. Маршруты, которых нет
+// в спецификации (статика), и неподходящие методы пропускаются как есть — на них ответит mux.
+func (v *Validator) Wrap(next http.Handler) http.Handler {
+	if v == nil || v.mode == Off {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route, params, err := v.router.FindRoute(r)
+		if err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if r.Body != nil && r.Body != http.NoBody {
+			r.Body = http.MaxBytesReader(w, r.Body, v.maxBody)
+		}
+		in := &openapi3filter.RequestValidationInput{
+			Request:    requestForValidation(r),
+			PathParams: params,
+			Route:      route,
+			Options:    filterOptions,
+		}
+		if p, ok := v.checkRequest(r.Context(), route, in); !ok {
+			problem.Write(w, r, p)
+			return
+		}
+		// фильтр вычитал тело и подменил его копией — дальше идёт она
+		r.Body = in.Request.Body
+
+		if v.mode != Full || route.Operation.Extensions["x-streaming"] == true {
+			next.ServeHTTP(w, r)
+			return
+		}
+		rec := &bufferedResponse{header: make(http.Header)}
+		next.ServeHTTP(rec, r)
+		if err := v.checkResponse(r.Context(), in, rec); err != nil {
+			slog.ErrorContext(r.Context(), "openapi: response does not match spec", "method", r.Method, "path", r.URL.Path, "err", err)
+			problem.Error(w, r, http.StatusInternalServerError, "response does not match API spec: "+err.Error())
+			return
+		}
+		rec.writeTo(w)
+	})
+}
+
+// requestForValidation — копия запроса для фильтра. Запросы без Content-Type хендлеры
+// читают как JSON, так же их проверяем и мы, не трогая заголовки исходного запроса.
+func requestForValidation(r *http.Request) *http.Request {
+	if r.Header.Get("Content-Type") != "" || r.Body == nil || r.Body == http.NoBody {
+		return r
+	}
+	c := r.Clone(r.Context())
+	c.Header.Set("Content-Type", "application/json")
+	return c
+}
+
+func (v *Validator) checkRequest(ctx context.Context, route *routers.Route, in *openapi3filter.RequestValidationInput) (problem.Problem, bool) {
+	if rb := route.Operation.RequestBody; rb != nil && rb.Value != nil {
+		mt, _, _ := mime.ParseMediaType(in.Request.Header.Get("Content-Type"))
+		if rb.Value.Content.Get(mt) == nil {
+			return problem.Problem{Status: http.StatusUnsupportedMediaType, Detail: fmt.Sprintf("unsupported Content-Type %q", mt)}, false
+		}
+	}
+	err := openapi3filter.ValidateRequest(ctx, in)
+	if err == nil {
+		return problem.Problem{}, true
+	}
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return problem.Problem{Status: http.StatusRequestEntityTooLarge, Detail: "request body too large"}, false
+	}
+	p := problem.Problem{Type: problem.TypeInvalidRequest, Status: http.StatusBadRequest, Detail: err.Error()}
+	if fe, ok := fieldError(err); ok {
+		p.Errors = []problem.FieldError{fe}
+	}
+	return p, false
+}
+
+// fieldError находит, какое поле запроса не прошло проверку: параметр по имени,
+// поле тела — путём (delivery.city, items[0].price).
+func fieldError(err error) (problem.FieldError, bool) {
+	var re *openapi3filter.RequestError
+	if !errors.As(err, &re) {
+		return problem.FieldError{}, false
+	}
+	var path []string
+	if re.Parameter != nil {
+		path = append(path, re.Parameter.Name)
+	}
+	msg := re.Reason
+	var fe problem.FieldError
+	var se *openapi3.SchemaError
+	if errors.As(err, &se) {
+		ptr := se.JSONPointer()
+		path = append(path, ptr...)
+		msg = se.Reason
+		fe.Code = se.SchemaField // required, type, minLength...
+		if re.Parameter == nil && len(ptr) > 0 {
+			fe.Pointer = validate.Pointer(fieldPath(ptr))
+		}
+	}
+	if len(path) == 0 || msg == "" {
+		return problem.FieldError{}, false
+	}
+	fe.Field, fe.Message = fieldPath(path), msg
+	return fe, true
+}
+
+// fieldPath записывает путь так же, как validate: индексы массивов в скобках.
+func fieldPath(segs []string) string {
+	var b strings.Builder
+	for i, s := range segs {
+		switch {
+		case isIndex(s):
+			b.WriteString("[" + s + "]")
+		case i > 0:
+			b.WriteString("." + s)
+		default:
+			b.WriteString(s)
+		}
+	}
+	return b.String()
+}
+
+func isIndex(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func (v *Validator) checkResponse(ctx context.Context, in *openapi3filter.RequestValidationInput, rec *bufferedResponse) error {
+	out := &openapi3filter.ResponseValidationInput{
+		RequestValidationInput: in,
+		Status:                 rec.statusCode(),
+		Header:                 rec.header,
+		Options:                &openapi3filter.Options{IncludeResponseStatus: true},
+	}
+	out.SetBodyBytes(rec.body.Bytes())
+	return openapi3filter.ValidateResponse(ctx, out)
+}
+
+// bufferedResponse копит ответ целиком, чтобы сверить его до отправки клиенту.
+type bufferedResponse struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (b *bufferedResponse) Header() http.Header { return b.header }
+
+func (b *bufferedResponse) WriteHeader(status int) {
+	if b.status == 0 {
+		b.status = status
+	}
+}
+
+func (b *bufferedResponse) Write(p []byte) (int, error) {
+	b.WriteHeader(http.StatusOK)
+	return b.body.Write(p)
+}
+
+func (b *bufferedResponse) statusCode() int {
+	if b.status == 0 {
+		return http.StatusOK
+	}
+	return b.status
+}
+
+func (b *bufferedResponse) writeTo(w http.ResponseWriter) {
+	for k, vs := range b.header {
+		w.Header()[k] = vs
+	}
+	w.WriteHeader(b.statusCode())
+	_, _ = w.Write(b.body.Bytes())
+}
@@ -0,0 +1,117 @@
+// internal/apispec/apispec_test.go
+package apispec_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"demo/orders/internal/apispec"
+	"demo/orders/internal/problem"
+)
+
+func serve(t *testing.T, mode apispec.Mode, h http.HandlerFunc, req *http.Request) *httptest.ResponseRecorder {
+	t.Helper()
+	v, err := apispec.New(mode, 64)
+	require.NoError(t, err)
+	rec := httptest.NewRecorder()
+	v.Wrap(h).ServeHTTP(rec, req)
+	return rec
+}
+
+func okJSON(body string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(body))
+	}
+}
+
+func TestLoad(t *testing.T) {
+	doc, err := apispec.Load()
+	require.NoError(t, err)
+	for _, p := range []string{"/order/{id}", "/orders:batchGet", "/orders", "/orders/export", "/orders/stream", "/orders/ws", "/graphql"} {
+		require.NotNil(t, doc.Paths.Find(p), p)
+	}
+}
+
+func TestRequestValidation(t *testing.T) {
+	called := false
+	next := func(w http.ResponseWriter, r *http.Request) { called = true }
+
+	for _, tc := range []struct {
+		name   string
+		req    *http.Request
+		status int
+	}{
+		{"limit out of range", httptest.NewRequest(http.MethodGet, "/orders?limit=0", nil), http.StatusBadRequest},
+		{"unknown export format", httptest.NewRequest(http.MethodGet, "/orders/export?format=xml", nil), http.StatusBadRequest},
+		{"wrong body type", httptest.NewRequest(http.MethodPost, "/orders:batchGet", strings.NewReader(`{"order_uids":"a"}`)), http.StatusBadRequest},
+		{"unknown field", httptest.NewRequest(http.MethodPut, "/order/order0001", strings.NewReader(`{"colour":"red"}`)), http.StatusBadRequest},
+		{"body too large", httptest.NewRequest(http.MethodPut, "/order/order0001", strings.NewReader(`{"entry":"`+strings.Repeat("x", 100)+`"}`)), http.StatusRequestEntityTooLarge},
+	} {
+		called = false
+		rec := serve(t, apispec.Requests, next, tc.req)
+		require.Equal(t, tc.status, rec.Code, tc.name)
+		require.False(t, called, tc.name)
+	}
+
+	rec := serve(t, apispec.Requests, next, httptest.NewRequest(http.MethodGet, "/orders?limit=0", nil))
+	var p problem.Problem
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &p))
+	require.Equal(t, problem.TypeInvalidRequest, p.Type)
+	require.Equal(t, "limit", p.Errors[0].Field)
+	require.Empty(t, p.Errors[0].Pointer, "у параметров запроса указателя в тело нет")
+
+	rec = serve(t, apispec.Requests, next, httptest.NewRequest(http.MethodPost, "/orders:batchGet", strings.NewReader(`{"order_uids":["a",1]}`)))
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &p))
+	require.Equal(t, "order_uids[1]", p.Errors[0].Field)
+	require.Equal(t, "/order_uids/1", p.Errors[0].Pointer)
+	require.Equal(t, "type", p.Errors[0].Code)
+
+	req := httptest.NewRequest(http.MethodPatch, "/order/order0001", strings.NewReader(`locale=ru`))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	require.Equal(t, http.StatusUnsupportedMediaType, serve(t, apispec.Requests, next, req).Code)
+}
+
+func TestRequestValidation_PassesBodyThrough(t *testing.T) {
+	var got string
+	next := func(w http.ResponseWriter, r *http.Request) {
+		b, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		got = string(b)
+		require.Empty(t, r.Header.Get("Content-Type")) // заголовок запроса не подменяется
+	}
+	body := `{"order_uids":["a","b"]}`
+	rec := serve(t, apispec.Requests, next, httptest.NewRequest(http.MethodPost, "/orders:batchGet", strings.NewReader(body)))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, body, got)
+}
+
+func TestUnknownRoutesPassThrough(t *testing.T) {
+	rec := serve(t, apispec.Full, okJSON(`"anything"`), httptest.NewRequest(http.MethodGet, "/index.html", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestResponseValidation(t *testing.T) {
+	req := func() *http.Request { return httptest.NewRequest(http.MethodGet, "/order/order0001", nil) }
+	good := okJSON(`{"order_uid":"order0001","items":[{"chrt_id":1,"name":"x"}]}`)
+	bad := okJSON(`{"order_uid":"order0001","items":[{"chrt_id":"1"}]}`)
+
+	require.Equal(t, http.StatusOK, serve(t, apispec.Full, good, req()).Code)
+	require.Equal(t, http.StatusInternalServerError, serve(t, apispec.Full, bad, req()).Code)
+	// в режиме по умолчанию ответы не проверяются
+	require.Equal(t, http.StatusOK, serve(t, apispec.Requests, bad, req()).Code)
+}
+
+func TestParseMode(t *testing.T) {
+	m, err := apispec.ParseMode("")
+	require.NoError(t, err)
+	require.Equal(t, apispec.Requests, m)
+	_, err = apispec.ParseMode("strict")
+	require.Error(t, err)
+}
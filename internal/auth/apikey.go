@@ -0,0 +1,86 @@
+package auth
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// APIKeyHeader — заголовок с ключом; также принимается "Authorization: ApiKey <ключ>".
+const APIKeyHeader = "X-API-Key"
+
+// APIKeys — статические ключи. Сами ключи сервис не хранит, только их SHA-256.
+type APIKeys struct {
+	byHash map[string]Principal
+}
+
+// HashAPIKey — то, что пишется в файл ключей вместо самого ключа.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// LoadAPIKeys читает файл ключей. Строка — "<sha256 ключа, hex> <субъект> [роль,роль...]",
+// пустые строки и строки с # пропускаются.
+func LoadAPIKeys(path string) (*APIKeys, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("api keys: %w", err)
+	}
+	defer f.Close()
+
+	keys := &APIKeys{byHash: make(map[string]Principal)}
+	sc := bufio.NewScanner(f)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 || len(fields) > 3 {
+			return nil, fmt.Errorf("api keys %s:%d: want \"<sha256> <subject> [roles]\"", path, n)
+		}
+		hash := strings.ToLower(strings.TrimPrefix(fields[0], "sha256:"))
+		if b, err := hex.DecodeString(hash); err != nil || len(b) != sha256.Size {
+			return nil, fmt.Errorf("api keys %s:%d: not a hex SHA-256", path, n)
+		}
+		if _, dup := keys.byHash[hash]; dup {
+			return nil, fmt.Errorf("api keys %s:%d: duplicate key", path, n)
+		}
+		p := Principal{Subject: fields[1], Method: MethodAPIKey}
+		if len(fields) == 3 {
+			p.Roles = strings.Split(fields[2], ",")
+		}
+		keys.byHash[hash] = p
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("api keys: %w", err)
+	}
+	return keys, nil
+}
+
+// Len — сколько ключей загружено.
+func (k *APIKeys) Len() int { return len(k.byHash) }
+
+func (k *APIKeys) Authenticate(r *http.Request) (Principal, error) {
+	key := r.Header.Get(APIKeyHeader)
+	if key == "" {
+		scheme, rest, _ := strings.Cut(r.Header.Get("Authorization"), " ")
+		if !strings.EqualFold(scheme, "ApiKey") {
+			return Principal{}, ErrNoCredentials
+		}
+		key = strings.TrimSpace(rest)
+	}
+	// ищем по хэшу, так что время ответа не зависит от того, насколько ключ «похож» на настоящий
+	p, ok := k.byHash[HashAPIKey(key)]
+	if !ok {
+		return Principal{}, fmt.Errorf("%w: unknown API key", ErrInvalidCredentials)
+	}
+	return p, nil
+}
+
+func (k *APIKeys) Challenge() string { return `ApiKey realm="orders"` }
@@ -0,0 +1,98 @@
+// Package auth — аутентификация HTTP-запросов: статические API-ключи (в файле только хэши)
+// и JWT, проверяемые по локальному JWKS.
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+
+	"demo/orders/internal/problem"
+)
+
+var (
+	// ErrNoCredentials — в запросе нет учётных данных этого вида; пробуем следующий способ.
+	ErrNoCredentials = errors.New("no credentials")
+	// ErrInvalidCredentials — учётные данные есть, но не подошли.
+	ErrInvalidCredentials = errors.New("invalid credentials")
+)
+
+// Способы аутентификации в Principal.Method.
+const (
+	MethodAPIKey = "api_key"
+	MethodJWT    = "jwt"
+)
+
+// Principal — кто сделал запрос.
+type Principal struct {
+	Subject string
+	Roles   []string
+	Method  string
+}
+
+// HasRole — есть ли у субъекта роль.
+func (p Principal) HasRole(role string) bool { return slices.Contains(p.Roles, role) }
+
+// Authenticator проверяет учётные данные одного вида.
+type Authenticator interface {
+	// Authenticate возвращает ErrNoCredentials, если данных его вида в запросе нет.
+	Authenticate(r *http.Request) (Principal, error)
+	// Challenge — значение WWW-Authenticate для ответа 401.
+	Challenge() string
+}
+
+type ctxKey struct{}
+
+// NewContext кладёт субъекта в контекст.
+func NewContext(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, ctxKey{}, p)
+}
+
+// FromContext достаёт субъекта; ok=false — запрос анонимный (публичный маршрут или auth выключен).
+func FromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(ctxKey{}).(Principal)
+	return p, ok
+}
+
+// Middleware пропускает запрос дальше, только если один из authenticators его узнал.
+// public решает, каким запросам учётные данные не нужны (их всё равно проверим, если переданы).
+// Без authenticators аутентификация выключена.
+func Middleware(authenticators []Authenticator, public func(*http.Request) bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if len(authenticators) == 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			p, err := Authenticate(authenticators, r)
+			switch {
+			case err == nil:
+				next.ServeHTTP(w, r.WithContext(NewContext(r.Context(), p)))
+			case errors.Is(err, ErrNoCredentials) && public != nil && public(r):
+				next.ServeHTTP(w, r)
+			default:
+				for _, a := range authenticators {
+					w.Header().Add("WWW-Authenticate", a.Challenge())
+				}
+				detail := "authentication required"
+				if !errors.Is(err, ErrNoCredentials) {
+					detail = err.Error()
+				}
+				problem.Error(w, r, http.StatusUnauthorized, detail)
+			}
+		})
+	}
+}
+
+// Authenticate — первый authenticator, узнавший учётные данные запроса. Для не-HTTP транспортов
+// (gRPC) достаточно запроса с одними заголовками.
+func Authenticate(authenticators []Authenticator, r *http.Request) (Principal, error) {
+	for _, a := range authenticators {
+		p, err := a.Authenticate(r)
+		if errors.Is(err, ErrNoCredentials) {
+			continue
+		}
+		return p, err
+	}
+	return Principal{}, ErrNoCredentials
+}
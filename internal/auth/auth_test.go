@@ -0,0 +1,189 @@
+// internal/auth/auth_test.go
+package auth_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/require"
+
+	"demo/orders/internal/auth"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func request(header, value string) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, "/order/x", nil)
+	if header != "" {
+		r.Header.Set(header, value)
+	}
+	return r
+}
+
+func TestAPIKeys(t *testing.T) {
+	path := writeFile(t, "keys", "# ключи\n"+auth.HashAPIKey("s3cret")+" support-bot support\n\nsha256:"+auth.HashAPIKey("other")+" etl\n")
+	keys, err := auth.LoadAPIKeys(path)
+	require.NoError(t, err)
+	require.Equal(t, 2, keys.Len())
+
+	p, err := keys.Authenticate(request(auth.APIKeyHeader, "s3cret"))
+	require.NoError(t, err)
+	require.Equal(t, auth.Principal{Subject: "support-bot", Roles: []string{"support"}, Method: auth.MethodAPIKey}, p)
+
+	p, err = keys.Authenticate(request("Authorization", "ApiKey other"))
+	require.NoError(t, err)
+	require.Equal(t, "etl", p.Subject)
+
+	_, err = keys.Authenticate(request(auth.APIKeyHeader, "guess"))
+	require.ErrorIs(t, err, auth.ErrInvalidCredentials)
+	_, err = keys.Authenticate(request("Authorization", "Bearer xyz"))
+	require.ErrorIs(t, err, auth.ErrNoCredentials)
+}
+
+func TestLoadAPIKeys_Errors(t *testing.T) {
+	h := auth.HashAPIKey("k")
+	for _, content := range []string{"plaintext-key bob", h, h + " a\n" + h + " b"} {
+		_, err := auth.LoadAPIKeys(writeFile(t, "keys", content))
+		require.Error(t, err, content)
+	}
+}
+
+func b64(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+
+type signer struct {
+	kid    string
+	method jwt.SigningMethod
+	key    any
+}
+
+func (s signer) sign(t *testing.T, claims jwt.MapClaims) string {
+	t.Helper()
+	tok := jwt.NewWithClaims(s.method, claims)
+	if s.kid != "" {
+		tok.Header["kid"] = s.kid
+	}
+	out, err := tok.SignedString(s.key)
+	require.NoError(t, err)
+	return out
+}
+
+// newJWKS генерирует по ключу RSA, EC и Ed25519 и кладёт их открытые части в JWKS-файл.
+func newJWKS(t *testing.T) (string, map[string]signer) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	edPub, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	set := map[string]any{"keys": []map[string]string{
+		{"kty": "RSA", "kid": "rsa1", "alg": "RS256", "n": b64(rsaKey.N.Bytes()), "e": b64(big.NewInt(int64(rsaKey.E)).Bytes())},
+		{"kty": "EC", "kid": "ec1", "crv": "P-256", "x": b64(ecKey.X.FillBytes(make([]byte, 32))), "y": b64(ecKey.Y.FillBytes(make([]byte, 32)))},
+		{"kty": "OKP", "kid": "ed1", "crv": "Ed25519", "x": b64(edPub)},
+		{"kty": "RSA", "kid": "enc1", "use": "enc", "n": "AQAB", "e": "AQAB"},
+	}}
+	b, err := json.Marshal(set)
+	require.NoError(t, err)
+	return writeFile(t, "jwks.json", string(b)), map[string]signer{
+		"rsa": {"rsa1", jwt.SigningMethodRS256, rsaKey},
+		"ec":  {"ec1", jwt.SigningMethodES256, ecKey},
+		"ed":  {"ed1", jwt.SigningMethodEdDSA, edKey},
+	}
+}
+
+func TestJWT(t *testing.T) {
+	path, signers := newJWKS(t)
+	jwks, err := auth.LoadJWKS(path)
+	require.NoError(t, err)
+	a := auth.NewJWT(jwks, auth.JWTConfig{Issuer: "https://idp.example", Audience: "orders"})
+
+	claims := func(extra jwt.MapClaims) jwt.MapClaims {
+		c := jwt.MapClaims{"sub": "alice", "iss": "https://idp.example", "aud": "orders",
+			"exp": time.Now().Add(time.Hour).Unix(), "roles": []string{"support", "analytics"}}
+		for k, v := range extra {
+			c[k] = v
+		}
+		return c
+	}
+	bearer := func(tok string) *http.Request { return request("Authorization", "Bearer "+tok) }
+
+	for name, s := range signers {
+		p, err := a.Authenticate(bearer(s.sign(t, claims(nil))))
+		require.NoError(t, err, name)
+		require.Equal(t, auth.Principal{Subject: "alice", Roles: []string{"support", "analytics"}, Method: auth.MethodJWT}, p, name)
+	}
+
+	p, err := a.Authenticate(bearer(signers["ed"].sign(t, claims(jwt.MapClaims{"roles": "support public"}))))
+	require.NoError(t, err)
+	require.True(t, p.HasRole("public"))
+
+	rejected := map[string]string{
+		"expired":      signers["rsa"].sign(t, claims(jwt.MapClaims{"exp": time.Now().Add(-time.Hour).Unix()})),
+		"no exp":       signers["rsa"].sign(t, jwt.MapClaims{"sub": "alice", "iss": "https://idp.example", "aud": "orders"}),
+		"wrong aud":    signers["rsa"].sign(t, claims(jwt.MapClaims{"aud": "billing"})),
+		"wrong iss":    signers["ec"].sign(t, claims(jwt.MapClaims{"iss": "https://evil.example"})),
+		"no sub":       signers["ec"].sign(t, claims(jwt.MapClaims{"sub": ""})),
+		"unknown kid":  signer{"nope", signers["ec"].method, signers["ec"].key}.sign(t, claims(nil)),
+		"kid/key swap": signer{"rsa1", signers["ec"].method, signers["ec"].key}.sign(t, claims(nil)),
+		"hmac":         signer{"rsa1", jwt.SigningMethodHS256, []byte("secret")}.sign(t, claims(nil)),
+		"garbage":      "not.a.token",
+	}
+	for name, tok := range rejected {
+		_, err := a.Authenticate(bearer(tok))
+		require.ErrorIs(t, err, auth.ErrInvalidCredentials, name)
+	}
+
+	_, err = a.Authenticate(request("", ""))
+	require.ErrorIs(t, err, auth.ErrNoCredentials)
+}
+
+func TestMiddleware(t *testing.T) {
+	keys, err := auth.LoadAPIKeys(writeFile(t, "keys", auth.HashAPIKey("s3cret")+" bot support"))
+	require.NoError(t, err)
+
+	var got auth.Principal
+	var authed bool
+	h := auth.Middleware([]auth.Authenticator{keys}, func(r *http.Request) bool { return r.URL.Path == "/healthz" })(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { got, authed = auth.FromContext(r.Context()) }))
+
+	serve := func(r *http.Request) int {
+		got, authed = auth.Principal{}, false
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, r)
+		if rec.Code == http.StatusUnauthorized {
+			require.Equal(t, `ApiKey realm="orders"`, rec.Header().Get("WWW-Authenticate"))
+		}
+		return rec.Code
+	}
+
+	require.Equal(t, http.StatusUnauthorized, serve(request("", "")))
+	require.Equal(t, http.StatusUnauthorized, serve(request(auth.APIKeyHeader, "wrong")))
+	require.Equal(t, http.StatusOK, serve(request(auth.APIKeyHeader, "s3cret")))
+	require.True(t, authed)
+	require.Equal(t, "bot", got.Subject)
+
+	// публичный маршрут: без ключа — анонимно, с неверным ключом — всё равно 401
+	require.Equal(t, http.StatusOK, serve(httptest.NewRequest(http.MethodGet, "/healthz", nil)))
+	require.False(t, authed)
+	bad := httptest.NewRequest(http.MethodGet, "/healthz", nil)
+	bad.Header.Set(auth.APIKeyHeader, "wrong")
+	require.Equal(t, http.StatusUnauthorized, serve(bad))
+}
@@ -0,0 +1,234 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// JWKS — открытые ключи для проверки подписи токенов, по kid.
+type JWKS struct {
+	keys map[string]jwk
+}
+
+type jwk struct {
+	alg string // если задан в JWK — токен обязан быть подписан именно им
+	key crypto.PublicKey
+}
+
+type rawJWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// LoadJWKS читает JWKS-файл (RFC 7517). Поддерживаются RSA, EC (P-256/384/521) и Ed25519;
+// ключи с use, отличным от "sig", пропускаются.
+func LoadJWKS(path string) (*JWKS, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("jwks: %w", err)
+	}
+	var set struct {
+		Keys []rawJWK `json:"keys"`
+	}
+	if err := json.Unmarshal(b, &set); err != nil {
+		return nil, fmt.Errorf("jwks %s: %w", path, err)
+	}
+	out := &JWKS{keys: make(map[string]jwk)}
+	for i, rk := range set.Keys {
+		if rk.Use != "" && rk.Use != "sig" {
+			continue
+		}
+		key, err := rk.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("jwks %s: key %d (kid %q): %w", path, i, rk.Kid, err)
+		}
+		if _, dup := out.keys[rk.Kid]; dup {
+			return nil, fmt.Errorf("jwks %s: duplicate kid %q", path, rk.Kid)
+		}
+		out.keys[rk.Kid] = jwk{alg: rk.Alg, key: key}
+	}
+	if len(out.keys) == 0 {
+		return nil, fmt.Errorf("jwks %s: no signing keys", path)
+	}
+	return out, nil
+}
+
+func (k rawJWK) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := b64int(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("n: %w", err)
+		}
+		e, err := b64int(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("e: %w", err)
+		}
+		if !e.IsInt64() || e.Int64() < 3 || e.Int64() > 1<<31-1 {
+			return nil, errors.New("e: out of range")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		c, ok := ecCurves[k.Crv]
+		if !ok {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := b64int(k.X)
+		if err != nil {
+			return nil, fmt.Errorf("x: %w", err)
+		}
+		y, err := b64int(k.Y)
+		if err != nil {
+			return nil, fmt.Errorf("y: %w", err)
+		}
+		// точку проверяет crypto/ecdh: ключ не на кривой — ошибка
+		size := (c.ec.Params().BitSize + 7) / 8
+		if x.BitLen() > size*8 || y.BitLen() > size*8 {
+			return nil, errors.New("point is not on curve")
+		}
+		point := make([]byte, 1+2*size)
+		point[0] = 4
+		x.FillBytes(point[1 : 1+size])
+		y.FillBytes(point[1+size:])
+		if _, err := c.ecdh.NewPublicKey(point); err != nil {
+			return nil, errors.New("point is not on curve")
+		}
+		return &ecdsa.PublicKey{Curve: c.ec, X: x, Y: y}, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("x: bad Ed25519 public key")
+		}
+		return ed25519.PublicKey(x), nil
+	}
+	return nil, fmt.Errorf("unsupported kty %q", k.Kty)
+}
+
+var ecCurves = map[string]struct {
+	ec   elliptic.Curve
+	ecdh ecdh.Curve
+}{
+	"P-256": {elliptic.P256(), ecdh.P256()},
+	"P-384": {elliptic.P384(), ecdh.P384()},
+	"P-521": {elliptic.P521(), ecdh.P521()},
+}
+
+func b64int(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(b) == 0 {
+		return nil, errors.New("bad base64url integer")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+// JWTConfig — что, кроме подписи и срока, проверять в токене.
+type JWTConfig struct {
+	Issuer     string        // iss; пусто — не проверять
+	Audience   string        // aud; пусто — не проверять
+	RolesClaim string        // claim с ролями: массив строк или строка через пробел; по умолчанию "roles"
+	Leeway     time.Duration // допуск на расхождение часов
+}
+
+// JWT проверяет "Authorization: Bearer <token>".
+type JWT struct {
+	keys   *JWKS
+	cfg    JWTConfig
+	parser *jwt.Parser
+}
+
+var jwtMethods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"}
+
+func NewJWT(keys *JWKS, cfg JWTConfig) *JWT {
+	if cfg.RolesClaim == "" {
+		cfg.RolesClaim = "roles"
+	}
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods(jwtMethods), // HS* и none не принимаем никогда
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(cfg.Leeway),
+	}
+	if cfg.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(cfg.Issuer))
+	}
+	if cfg.Audience != "" {
+		opts = append(opts, jwt.WithAudience(cfg.Audience))
+	}
+	return &JWT{keys: keys, cfg: cfg, parser: jwt.NewParser(opts...)}
+}
+
+func (j *JWT) Authenticate(r *http.Request) (Principal, error) {
+	scheme, raw, _ := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !strings.EqualFold(scheme, "Bearer") {
+		return Principal{}, ErrNoCredentials
+	}
+	claims := jwt.MapClaims{}
+	if _, err := j.parser.ParseWithClaims(strings.TrimSpace(raw), claims, j.keyFor); err != nil {
+		return Principal{}, fmt.Errorf("%w: %v", ErrInvalidCredentials, err)
+	}
+	sub, _ := claims.GetSubject()
+	if sub == "" {
+		return Principal{}, fmt.Errorf("%w: token has no sub", ErrInvalidCredentials)
+	}
+	return Principal{Subject: sub, Roles: rolesFrom(claims[j.cfg.RolesClaim]), Method: MethodJWT}, nil
+}
+
+func (j *JWT) keyFor(t *jwt.Token) (any, error) {
+	kid, _ := t.Header["kid"].(string)
+	k, ok := j.keys.keys[kid]
+	if !ok && kid == "" && len(j.keys.keys) == 1 {
+		// без kid допустимо, только если ключ единственный
+		for _, only := range j.keys.keys {
+			k, ok = only, true
+		}
+	}
+	if !ok {
+		return nil, fmt.Errorf("unknown kid %q", kid)
+	}
+	if k.alg != "" && k.alg != t.Method.Alg() {
+		return nil, fmt.Errorf("alg %s not allowed for kid %q", t.Method.Alg(), kid)
+	}
+	return k.key, nil
+}
+
+func rolesFrom(v any) []string {
+	switch v := v.(type) {
+	case string:
+		return strings.Fields(v)
+	case []any:
+		out := make([]string, 0, len(v))
+		for _, r := range v {
+			if s, ok := r.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+func (j *JWT) Challenge() string { return `Bearer realm="orders"` }
@@ -0,0 +1,65 @@
+// Package etag считает ETag заказа по его JSON-представлению и разбирает If-Match/If-None-Match.
+package etag
+
+import (
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"slices"
+	"strings"
+	"time"
+
+	"demo/orders/internal/model"
+)
+
+// Of возвращает сильный ETag (в кавычках) — хэш JSON заказа.
+// Поля структуры сериализуются в фиксированном порядке, а items перед хэшированием
+// сортируются по chrt_id: БД не гарантирует порядок строк, и хэш не должен от него зависеть.
+// По той же причине date_created приводится к UTC с точностью Postgres (микросекунды).
+func Of(o model.Order) string {
+	items := slices.Clone(o.Items)
+	slices.SortStableFunc(items, func(a, b model.Item) int { return cmp.Compare(a.ChrtID, b.ChrtID) })
+	o.Items = items
+	o.DateCreated = o.DateCreated.UTC().Truncate(time.Microsecond)
+
+	b, err := json.Marshal(o)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(b)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// Match — слабое сравнение (RFC 9110 §8.8.3.2) для If-None-Match: совпадает ли tag хотя бы
+// с одним значением заголовка. Поддерживаются "*", списки через запятую и W/-теги.
+func Match(header, tag string) bool {
+	if tag == "" {
+		return false
+	}
+	for _, p := range strings.Split(header, ",") {
+		p = strings.TrimSpace(p)
+		if p == "*" {
+			return true
+		}
+		if strings.TrimPrefix(p, "W/") == strings.TrimPrefix(tag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchStrong — сильное сравнение для If-Match (RFC 9110 §13.1.1): W/-теги не совпадают ни с чем,
+// даже с тем же значением без W/.
+func MatchStrong(header, tag string) bool {
+	if tag == "" || strings.HasPrefix(tag, "W/") {
+		return false
+	}
+	for _, p := range strings.Split(header, ",") {
+		p = strings.TrimSpace(p)
+		if p == "*" || p == tag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,79 @@
+// internal/etag/etag_test.go
+package etag_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"demo/orders/internal/etag"
+	"demo/orders/internal/model"
+)
+
+func TestOf(t *testing.T) {
+	created := time.Date(2021, 11, 26, 6, 22, 19, 123456789, time.UTC)
+	o := model.Order{
+		OrderUID:    "b563feb7b2b84b6test",
+		DateCreated: created,
+		Items:       []model.Item{{ChrtID: 2, Name: "b"}, {ChrtID: 1, Name: "a"}},
+	}
+	tag := etag.Of(o)
+	require.Regexp(t, `^"[0-9a-f]{32}"$`, tag)
+
+	// порядок items из БД не важен
+	swapped := o
+	swapped.Items = []model.Item{o.Items[1], o.Items[0]}
+	require.Equal(t, tag, etag.Of(swapped))
+	require.Equal(t, "b", o.Items[0].Name, "исходный заказ не меняется")
+
+	// тот же момент в другой зоне и с точностью Postgres
+	moved := o
+	moved.DateCreated = created.In(time.FixedZone("MSK", 3*60*60)).Truncate(time.Microsecond)
+	require.Equal(t, tag, etag.Of(moved))
+
+	// служебные поля не входят в JSON заказа
+	stamped := o
+	stamped.UpdatedAt = time.Now()
+	stamped.Warnings = []model.Warning{{Field: "delivery.phone"}}
+	require.Equal(t, tag, etag.Of(stamped))
+
+	changed := o
+	changed.Items = []model.Item{{ChrtID: 1, Name: "a"}, {ChrtID: 2, Name: "c"}}
+	require.NotEqual(t, tag, etag.Of(changed))
+}
+
+func TestMatch(t *testing.T) {
+	tag := `"abc"`
+	for header, want := range map[string]bool{
+		`"abc"`:            true,
+		`W/"abc"`:          true,
+		`"x", W/"abc"`:     true,
+		`*`:                true,
+		`"abcd"`:           false,
+		``:                 false,
+		`"x" , "y"`:        false,
+		`  "abc"  , "zzz"`: true,
+	} {
+		require.Equal(t, want, etag.Match(header, tag), header)
+	}
+	require.True(t, etag.Match(`"abc"`, `W/"abc"`))
+	require.False(t, etag.Match("*", ""))
+}
+
+func TestMatchStrong(t *testing.T) {
+	tag := `"abc"`
+	for header, want := range map[string]bool{
+		`"abc"`:        true,
+		`"x", "abc"`:   true,
+		`*`:            true,
+		`W/"abc"`:      false,
+		`"x", W/"abc"`: false,
+		`"abcd"`:       false,
+		``:             false,
+	} {
+		require.Equal(t, want, etag.MatchStrong(header, tag), header)
+	}
+	require.False(t, etag.MatchStrong(`W/"abc"`, `W/"abc"`))
+	require.False(t, etag.MatchStrong("*", ""))
+}
@@ -0,0 +1,140 @@
+// Package export пишет поток заказов в NDJSON или CSV (строка на заказ либо строка на товар).
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"demo/orders/internal/model"
+)
+
+const (
+	FormatNDJSON = "ndjson"
+	FormatCSV    = "csv"
+
+	RowsOrder = "order" // одна строка на заказ, товары свёрнуты в items_count
+	RowsItem  = "item"  // одна строка на товар, поля заказа повторяются
+)
+
+// Writer пишет заказы по одному; Flush сбрасывает буферы в нижележащий io.Writer.
+type Writer interface {
+	Write(o model.Order) error
+	Flush() error
+}
+
+// New возвращает Writer для формата и вида строк. rows учитывается только для CSV.
+func New(w io.Writer, format, rows string) (Writer, error) {
+	switch format {
+	case FormatNDJSON, "":
+		return &ndjsonWriter{enc: json.NewEncoder(w)}, nil
+	case FormatCSV:
+		switch rows {
+		case RowsOrder, "":
+			return newCSVWriter(w, orderHeader, orderRows), nil
+		case RowsItem:
+			return newCSVWriter(w, itemHeader, itemRows), nil
+		default:
+			return nil, fmt.Errorf("unknown rows %q (use %s|%s)", rows, RowsOrder, RowsItem)
+		}
+	default:
+		return nil, fmt.Errorf("unknown format %q (use %s|%s)", format, FormatNDJSON, FormatCSV)
+	}
+}
+
+// ContentType — MIME-тип ответа для формата.
+func ContentType(format string) string {
+	if format == FormatCSV {
+		return "text/csv; charset=utf-8"
+	}
+	return "application/x-ndjson"
+}
+
+type ndjsonWriter struct{ enc *json.Encoder }
+
+func (n *ndjsonWriter) Write(o model.Order) error { return n.enc.Encode(o) }
+func (n *ndjsonWriter) Flush() error              { return nil }
+
+type csvWriter struct {
+	w      *csv.Writer
+	header []string
+	rows   func(model.Order) [][]string
+	wrote  bool
+}
+
+func newCSVWriter(w io.Writer, header []string, rows func(model.Order) [][]string) *csvWriter {
+	return &csvWriter{w: csv.NewWriter(w), header: header, rows: rows}
+}
+
+func (c *csvWriter) Write(o model.Order) error {
+	if !c.wrote {
+		if err := c.w.Write(c.header); err != nil {
+			return err
+		}
+		c.wrote = true
+	}
+	for _, rec := range c.rows(o) {
+		if err := c.w.Write(rec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *csvWriter) Flush() error {
+	if !c.wrote {
+		// пустая выгрузка — всё равно отдаём заголовок
+		if err := c.w.Write(c.header); err != nil {
+			return err
+		}
+		c.wrote = true
+	}
+	c.w.Flush()
+	return c.w.Error()
+}
+
+var orderHeader = []string{
+	"order_uid", "track_number", "entry", "locale", "customer_id", "delivery_service", "shardkey", "sm_id", "date_created", "oof_shard",
+	"delivery_name", "delivery_phone", "delivery_zip", "delivery_city", "delivery_address", "delivery_region", "delivery_email",
+	"payment_transaction", "payment_request_id", "payment_currency", "payment_provider", "payment_amount", "payment_dt",
+	"payment_bank", "payment_delivery_cost", "payment_goods_total", "payment_custom_fee",
+	"items_count",
+}
+
+func orderColumns(o model.Order) []string {
+	d, p := o.Delivery, o.Payment
+	return []string{
+		o.OrderUID, o.TrackNumber, o.Entry, o.Locale, o.CustomerID, o.DeliveryService, o.ShardKey, strconv.Itoa(o.SmID),
+		o.DateCreated.UTC().Format(time.RFC3339), o.OofShard,
+		d.Name, d.Phone, d.Zip, d.City, d.Address, d.Region, d.Email,
+		p.Transaction, p.RequestID, p.Currency, p.Provider, strconv.Itoa(p.Amount),
+		time.Unix(p.PaymentDT, 0).UTC().Format(time.RFC3339),
+		p.Bank, strconv.Itoa(p.DeliveryCost), strconv.Itoa(p.GoodsTotal), strconv.Itoa(p.CustomFee),
+	}
+}
+
+func orderRows(o model.Order) [][]string {
+	return [][]string{append(orderColumns(o), strconv.Itoa(len(o.Items)))}
+}
+
+var itemHeader = append(orderHeader[:len(orderHeader)-1:len(orderHeader)-1],
+	"item_chrt_id", "item_track_number", "item_price", "item_rid", "item_name", "item_sale", "item_size",
+	"item_total_price", "item_nm_id", "item_brand", "item_status",
+)
+
+func itemRows(o model.Order) [][]string {
+	base := orderColumns(o)
+	out := make([][]string, 0, len(o.Items))
+	for _, it := range o.Items {
+		rec := append(base[:len(base):len(base)],
+			strconv.FormatInt(it.ChrtID, 10), it.TrackNumber, strconv.Itoa(it.Price), it.RID, it.Name,
+			strconv.Itoa(it.Sale), it.Size, strconv.Itoa(it.TotalPrice), strconv.FormatInt(it.NmID, 10),
+			it.Brand, strconv.Itoa(it.Status),
+		)
+		out = append(out, rec)
+	}
+	return out
+}
@@ -0,0 +1,96 @@
+// internal/export/export_test.go
+package export_test
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"demo/orders/internal/export"
+	"demo/orders/internal/model"
+
+	"github.com/stretchr/testify/require"
+)
+
+func twoItemOrder() model.Order {
+	return model.Order{
+		OrderUID:   "order01",
+		CustomerID: "cust1",
+		Payment:    model.Payment{Currency: "USD", Amount: 1817},
+		Items: []model.Item{
+			{ChrtID: 1, Name: "A", Price: 100},
+			{ChrtID: 2, Name: "B, with comma", Price: 200},
+		},
+	}
+}
+
+func TestNDJSON(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := export.New(&buf, export.FormatNDJSON, "")
+	require.NoError(t, err)
+	require.NoError(t, w.Write(twoItemOrder()))
+	require.NoError(t, w.Write(model.Order{OrderUID: "order02"}))
+	require.NoError(t, w.Flush())
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 2)
+	var o model.Order
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &o))
+	require.Equal(t, "order01", o.OrderUID)
+}
+
+func TestCSV_OrderRows(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := export.New(&buf, export.FormatCSV, export.RowsOrder)
+	require.NoError(t, err)
+	require.NoError(t, w.Write(twoItemOrder()))
+	require.NoError(t, w.Flush())
+
+	recs, err := csv.NewReader(&buf).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, recs, 2)
+	require.Equal(t, "order_uid", recs[0][0])
+	require.Equal(t, "items_count", recs[0][len(recs[0])-1])
+	require.Equal(t, "2", recs[1][len(recs[1])-1])
+}
+
+func TestCSV_ItemRows(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := export.New(&buf, export.FormatCSV, export.RowsItem)
+	require.NoError(t, err)
+	require.NoError(t, w.Write(twoItemOrder()))
+	require.NoError(t, w.Flush())
+
+	recs, err := csv.NewReader(&buf).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, recs, 3)
+	for _, rec := range recs {
+		require.Len(t, rec, len(recs[0]))
+	}
+	nameCol := -1
+	for i, h := range recs[0] {
+		if h == "item_name" {
+			nameCol = i
+		}
+	}
+	require.Equal(t, "A", recs[1][nameCol])
+	require.Equal(t, "B, with comma", recs[2][nameCol])
+	require.Equal(t, "order01", recs[2][0])
+}
+
+func TestCSV_EmptyHasHeader(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := export.New(&buf, export.FormatCSV, "")
+	require.NoError(t, err)
+	require.NoError(t, w.Flush())
+	require.True(t, strings.HasPrefix(buf.String(), "order_uid,"))
+}
+
+func TestNew_Unknown(t *testing.T) {
+	_, err := export.New(&bytes.Buffer{}, "xml", "")
+	require.Error(t, err)
+	_, err = export.New(&bytes.Buffer{}, export.FormatCSV, "bogus")
+	require.Error(t, err)
+}
@@ -0,0 +1,125 @@
+// Package feed раздаёт подписчикам свежие заказы и хранит короткую историю для докачки по Last-Event-ID.
+package feed
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"demo/orders/internal/model"
+)
+
+// Event — заказ с порядковым номером. Номера растут монотонно; отсчёт начинается
+// с времени старта процесса в наносекундах, поэтому и после рестарта новые id больше старых.
+type Event struct {
+	ID    uint64
+	Order model.Order
+}
+
+// Filter отбирает заказы на стороне сервера; пустые поля не фильтруют.
+type Filter struct {
+	CustomerID      string
+	DeliveryService string
+	Currency        string
+}
+
+func (f Filter) Match(o model.Order) bool {
+	if f.CustomerID != "" && f.CustomerID != o.CustomerID {
+		return false
+	}
+	if f.DeliveryService != "" && f.DeliveryService != o.DeliveryService {
+		return false
+	}
+	if f.Currency != "" && !strings.EqualFold(f.Currency, o.Payment.Currency) {
+		return false
+	}
+	return true
+}
+
+type subscriber struct {
+	filter Filter
+	ch     chan Event
+}
+
+// Hub — кольцевой буфер последних событий плюс набор подписчиков.
+type Hub struct {
+	mu     sync.Mutex
+	ring   []Event
+	start  int // индекс самого старого события в ring
+	n      int // сколько событий в ring сейчас
+	nextID uint64
+	subs   map[*subscriber]struct{}
+	bufLen int
+}
+
+// NewHub создаёт хаб с историей на size событий. bufLen — буфер канала подписчика:
+// кто не успевает вычитывать, того отключаем (клиент переподключится с Last-Event-ID).
+func NewHub(size, bufLen int) *Hub {
+	if size <= 0 {
+		size = 1
+	}
+	return &Hub{
+		ring:   make([]Event, size),
+		nextID: uint64(time.Now().UnixNano()),
+		subs:   make(map[*subscriber]struct{}),
+		bufLen: bufLen,
+	}
+}
+
+// Publish присваивает заказу id, кладёт в историю и рассылает подходящим подписчикам.
+func (h *Hub) Publish(o model.Order) Event {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	ev := Event{ID: h.nextID, Order: o}
+	h.nextID++
+	if h.n < len(h.ring) {
+		h.ring[(h.start+h.n)%len(h.ring)] = ev
+		h.n++
+	} else {
+		h.ring[h.start] = ev
+		h.start = (h.start + 1) % len(h.ring)
+	}
+
+	for s := range h.subs {
+		if !s.filter.Match(o) {
+			continue
+		}
+		select {
+		case s.ch <- ev:
+		default:
+			// медленный подписчик — отключаем, чтобы не тормозить консьюмер
+			delete(h.subs, s)
+			close(s.ch)
+		}
+	}
+	return ev
+}
+
+// Subscribe регистрирует подписчика. Если afterID != 0, backlog содержит события из истории
+// с id > afterID (по фильтру). Канал закрывается при отписке или если подписчик отстал.
+func (h *Hub) Subscribe(f Filter, afterID uint64) (backlog []Event, events <-chan Event, cancel func()) {
+	s := &subscriber{filter: f, ch: make(chan Event, h.bufLen)}
+
+	h.mu.Lock()
+	if afterID != 0 {
+		for i := 0; i < h.n; i++ {
+			ev := h.ring[(h.start+i)%len(h.ring)]
+			if ev.ID > afterID && f.Match(ev.Order) {
+				backlog = append(backlog, ev)
+			}
+		}
+	}
+	h.subs[s] = struct{}{}
+	h.mu.Unlock()
+
+	cancel = func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		if _, ok := h.subs[s]; ok {
+			delete(h.subs, s)
+			close(s.ch)
+		}
+	}
+	return backlog, s.ch, cancel
+}
@@ -0,0 +1,67 @@
+// internal/feed/feed_test.go
+package feed_test
+
+import (
+	"testing"
+
+	"demo/orders/internal/feed"
+	"demo/orders/internal/model"
+
+	"github.com/stretchr/testify/require"
+)
+
+func order(id, customer string) model.Order {
+	return model.Order{OrderUID: id, CustomerID: customer, Payment: model.Payment{Currency: "USD"}}
+}
+
+func TestHub_PublishToMatchingSubscribers(t *testing.T) {
+	h := feed.NewHub(10, 10)
+	_, all, cancelAll := h.Subscribe(feed.Filter{}, 0)
+	defer cancelAll()
+	_, onlyBob, cancelBob := h.Subscribe(feed.Filter{CustomerID: "bob", Currency: "usd"}, 0)
+	defer cancelBob()
+
+	h.Publish(order("o1", "alice"))
+	h.Publish(order("o2", "bob"))
+
+	require.Equal(t, "o1", (<-all).Order.OrderUID)
+	require.Equal(t, "o2", (<-all).Order.OrderUID)
+	require.Equal(t, "o2", (<-onlyBob).Order.OrderUID)
+	require.Len(t, onlyBob, 0)
+}
+
+func TestHub_ResumeFromRing(t *testing.T) {
+	h := feed.NewHub(3, 10)
+	var ids []uint64
+	for _, id := range []string{"o1", "o2", "o3", "o4", "o5"} {
+		ids = append(ids, h.Publish(order(id, "c")).ID)
+	}
+	require.Greater(t, ids[1], ids[0])
+
+	// в истории только последние три; докачиваем всё после o3
+	backlog, _, cancel := h.Subscribe(feed.Filter{}, ids[2])
+	defer cancel()
+	require.Len(t, backlog, 2)
+	require.Equal(t, "o4", backlog[0].Order.OrderUID)
+	require.Equal(t, "o5", backlog[1].Order.OrderUID)
+
+	// id старше истории — отдаём всё, что осталось
+	backlog, _, cancel2 := h.Subscribe(feed.Filter{}, ids[0])
+	defer cancel2()
+	require.Len(t, backlog, 3)
+}
+
+func TestHub_SlowSubscriberDropped(t *testing.T) {
+	h := feed.NewHub(10, 1)
+	_, ch, cancel := h.Subscribe(feed.Filter{}, 0)
+	defer cancel()
+
+	h.Publish(order("o1", "c"))
+	h.Publish(order("o2", "c")) // буфер полон — подписчика отключаем
+
+	ev, ok := <-ch
+	require.True(t, ok)
+	require.Equal(t, "o1", ev.Order.OrderUID)
+	_, ok = <-ch
+	require.False(t, ok)
+}
@@ -3,7 +3,7 @@ package gen
 import (
 	"context"
 	"encoding/json"
-	"log"
+	"log/slog"
 	"strings"
 	"time"
 
@@ -11,14 +11,17 @@ import (
 	"github.com/segmentio/kafka-go"
 
 	"demo/orders/internal/model"
+	"demo/orders/internal/requestid"
+	"demo/orders/internal/tracing"
 )
 
 func SeedOnce() { gofakeit.Seed(time.Now().UnixNano()) }
 
 func FakeOrder() model.Order {
+	track := strings.ToUpper(gofakeit.LetterN(4) + gofakeit.DigitN(6)) // у товаров тот же, что у заказа
 	return model.Order{
 		OrderUID:    gofakeit.UUID(),
-		TrackNumber: strings.ToUpper(gofakeit.LetterN(4) + gofakeit.DigitN(6)),
+		TrackNumber: track,
 		Entry:       "WBIL",
 		Delivery: model.Delivery{
 			Name:    gofakeit.Name(),
@@ -42,7 +45,7 @@ func FakeOrder() model.Order {
 		},
 		Items: []model.Item{{
 			ChrtID:      int64(gofakeit.Number(1000, 999999)),
-			TrackNumber: "WB" + strings.ToUpper(gofakeit.LetterN(2)+gofakeit.DigitN(6)),
+			TrackNumber: track,
 			Price:       gofakeit.Number(10, 10000),
 			RID:         gofakeit.UUID(),
 			Name:        gofakeit.ProductName(),
@@ -61,18 +64,23 @@ func FakeOrder() model.Order {
 
 func SendOrder(ctx context.Context, w *kafka.Writer, o model.Order, source string) (int, error) {
 	val, _ := json.Marshal(o)
-	err := w.WriteMessages(ctx, kafka.Message{
+	id := requestid.New()
+	msg := kafka.Message{
 		Key:   []byte(o.OrderUID),
 		Value: val,
 		Time:  time.Now(),
 		Headers: []kafka.Header{
 			{Key: "content-type", Value: []byte("application/json")},
 			{Key: "source", Value: []byte(source)},
+			{Key: requestid.MessageHeader, Value: []byte(id)},
 		},
-	})
+	}
+	ctx, span := tracing.StartPublish(ctx, w.Topic, &msg)
+	err := w.WriteMessages(ctx, msg)
+	tracing.EndPublish(span, err)
 	if err != nil {
 		return 0, err
 	}
-	log.Printf("produced key=%s src=%s", o.OrderUID, source)
+	slog.InfoContext(ctx, "produced", "order_uid", o.OrderUID, "source", source, "request_id", id)
 	return 1, nil
 }
@@ -0,0 +1,296 @@
+// Package graphqlapi — GraphQL-эндпоинт для выборочных запросов по заказам.
+// Заказы по id и история покупателей грузятся пачками (dataloader) на весь запрос, без N+1.
+package graphqlapi
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	graphql "github.com/graph-gophers/graphql-go"
+	"github.com/graph-gophers/graphql-go/relay"
+
+	"demo/orders/internal/model"
+	"demo/orders/internal/redact"
+	"demo/orders/internal/store"
+)
+
+//go:embed schema.graphql
+var schemaSDL string
+
+// Cache — то, что эндпоинт использует из кэша заказов сервиса.
+type Cache interface {
+	GetWithETag(id string) (model.Order, string, bool)
+	Set(id string, v model.Order) string
+}
+
+const (
+	batchWait    = 2 * time.Millisecond
+	batchTimeout = 10 * time.Second
+	maxFirst     = 500
+	// история внутри заказа умножается на размер страницы — у неё свой, меньший предел
+	maxHistoryFirst = 50
+	// { orders { orders { customerHistory { items { name } } } } } — глубина 5
+	maxDepth = 6
+)
+
+type historyKey struct {
+	customerID string
+	first      int
+}
+
+type loaders struct {
+	orders  *loader[string, model.Order]
+	history *loader[historyKey, []model.Order]
+}
+
+type ctxKey struct{}
+
+func newLoaders(repo store.Repository, cache Cache) *loaders {
+	return &loaders{
+		orders: newLoader(batchWait, func(ctx context.Context, ids []string) (map[string]model.Order, error) {
+			out := make(map[string]model.Order, len(ids))
+			var misses []string
+			for _, id := range ids {
+				if o, _, ok := cache.GetWithETag(id); ok {
+					out[id] = o
+				} else {
+					misses = append(misses, id)
+				}
+			}
+			if len(misses) == 0 {
+				return out, nil
+			}
+			orders, err := repo.GetOrders(ctx, misses)
+			if err != nil {
+				return nil, err
+			}
+			for _, o := range orders {
+				cache.Set(o.OrderUID, o)
+				out[o.OrderUID] = o
+			}
+			return out, nil
+		}),
+		history: newLoader(batchWait, func(ctx context.Context, keys []historyKey) (map[historyKey][]model.Order, error) {
+			// разные first — отдельные запросы, одинаковые — один на всех покупателей
+			byFirst := make(map[int][]string)
+			for _, k := range keys {
+				byFirst[k.first] = append(byFirst[k.first], k.customerID)
+			}
+			out := make(map[historyKey][]model.Order, len(keys))
+			for first, customers := range byFirst {
+				res, err := repo.CustomerOrders(ctx, customers, first)
+				if err != nil {
+					return nil, err
+				}
+				for _, c := range customers {
+					out[historyKey{c, first}] = res[c]
+				}
+			}
+			return out, nil
+		}),
+	}
+}
+
+func loadersFrom(ctx context.Context) *loaders { return ctx.Value(ctxKey{}).(*loaders) }
+
+// Handler отвечает на POST /graphql ({"query": ..., "variables": ...}).
+func Handler(repo store.Repository, cache Cache) http.Handler {
+	schema := graphql.MustParseSchema(schemaSDL, &queryResolver{repo: repo}, graphql.MaxDepth(maxDepth))
+	h := &relay.Handler{Schema: schema}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), ctxKey{}, newLoaders(repo, cache))
+		h.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func clampFirst(first int32, limit int) (int, error) {
+	if first <= 0 || int(first) > limit {
+		return 0, fmt.Errorf("first: 1..%d", limit)
+	}
+	return int(first), nil
+}
+
+type queryResolver struct {
+	repo store.Repository
+}
+
+func (q *queryResolver) Order(ctx context.Context, args struct{ ID graphql.ID }) (*orderResolver, error) {
+	o, ok, err := loadersFrom(ctx).orders.Load(ctx, string(args.ID))
+	if err != nil || !ok {
+		return nil, err
+	}
+	return newOrderResolver(ctx, o), nil
+}
+
+type orderFilterInput struct {
+	CustomerID      *string
+	DeliveryService *string
+	Currency        *string
+	CreatedFrom     *graphql.Time
+	CreatedTo       *graphql.Time
+}
+
+func (in *orderFilterInput) toStore() store.OrderFilter {
+	var f store.OrderFilter
+	if in == nil {
+		return f
+	}
+	deref := func(s *string) string {
+		if s == nil {
+			return ""
+		}
+		return *s
+	}
+	f.CustomerID, f.DeliveryService, f.Currency = deref(in.CustomerID), deref(in.DeliveryService), deref(in.Currency)
+	if in.CreatedFrom != nil {
+		f.CreatedFrom = in.CreatedFrom.Time
+	}
+	if in.CreatedTo != nil {
+		f.CreatedTo = in.CreatedTo.Time
+	}
+	return f
+}
+
+type orderPageResolver struct {
+	orders     []*orderResolver
+	nextCursor *string
+}
+
+func (p *orderPageResolver) Orders() []*orderResolver { return p.orders }
+func (p *orderPageResolver) NextCursor() *string      { return p.nextCursor }
+
+func (q *queryResolver) Orders(ctx context.Context, args struct {
+	Filter *orderFilterInput
+	First  int32
+	After  *string
+}) (*orderPageResolver, error) {
+	first, err := clampFirst(args.First, maxFirst)
+	if err != nil {
+		return nil, err
+	}
+	after := ""
+	if args.After != nil {
+		after = *args.After
+	}
+	orders, err := q.repo.ListOrders(ctx, args.Filter.toStore(), after, first)
+	if err != nil {
+		return nil, err
+	}
+	page := &orderPageResolver{orders: wrapOrders(ctx, orders)}
+	if len(orders) == first {
+		c := orders[len(orders)-1].OrderUID
+		page.nextCursor = &c
+	}
+	return page, nil
+}
+
+func (q *queryResolver) CustomerHistory(ctx context.Context, args struct {
+	CustomerID string
+	First      int32
+}) ([]*orderResolver, error) {
+	first, err := clampFirst(args.First, maxFirst)
+	if err != nil {
+		return nil, err
+	}
+	orders, _, err := loadersFrom(ctx).history.Load(ctx, historyKey{args.CustomerID, first})
+	if err != nil {
+		return nil, err
+	}
+	return wrapOrders(ctx, orders), nil
+}
+
+// newOrderResolver отдаёт заказ в представлении вызывающего (см. redact): все поля
+// резолвятся уже из замаскированной копии.
+func newOrderResolver(ctx context.Context, o model.Order) *orderResolver {
+	return &orderResolver{redact.Order(ctx, o)}
+}
+
+func wrapOrders(ctx context.Context, orders []model.Order) []*orderResolver {
+	out := make([]*orderResolver, len(orders))
+	for i := range orders {
+		out[i] = newOrderResolver(ctx, orders[i])
+	}
+	return out
+}
+
+type orderResolver struct{ o model.Order }
+
+func (r *orderResolver) OrderUID() graphql.ID        { return graphql.ID(r.o.OrderUID) }
+func (r *orderResolver) TrackNumber() string         { return r.o.TrackNumber }
+func (r *orderResolver) Entry() string               { return r.o.Entry }
+func (r *orderResolver) Locale() string              { return r.o.Locale }
+func (r *orderResolver) InternalSignature() string   { return r.o.InternalSignature }
+func (r *orderResolver) CustomerID() string          { return r.o.CustomerID }
+func (r *orderResolver) DeliveryService() string     { return r.o.DeliveryService }
+func (r *orderResolver) Shardkey() string            { return r.o.ShardKey }
+func (r *orderResolver) SmID() int32                 { return int32(r.o.SmID) }
+func (r *orderResolver) DateCreated() graphql.Time   { return graphql.Time{Time: r.o.DateCreated} }
+func (r *orderResolver) OofShard() string            { return r.o.OofShard }
+func (r *orderResolver) Delivery() *deliveryResolver { return &deliveryResolver{r.o.Delivery} }
+func (r *orderResolver) Payment() *paymentResolver   { return &paymentResolver{r.o.Payment} }
+
+func (r *orderResolver) Items() []*itemResolver {
+	out := make([]*itemResolver, len(r.o.Items))
+	for i := range r.o.Items {
+		out[i] = &itemResolver{r.o.Items[i]}
+	}
+	return out
+}
+
+func (r *orderResolver) CustomerHistory(ctx context.Context, args struct{ First int32 }) ([]*orderResolver, error) {
+	first, err := clampFirst(args.First, maxHistoryFirst)
+	if err != nil {
+		return nil, err
+	}
+	if r.o.CustomerID == "" {
+		return []*orderResolver{}, nil
+	}
+	orders, _, err := loadersFrom(ctx).history.Load(ctx, historyKey{r.o.CustomerID, first})
+	if err != nil {
+		return nil, err
+	}
+	return wrapOrders(ctx, orders), nil
+}
+
+type deliveryResolver struct{ d model.Delivery }
+
+func (r *deliveryResolver) Name() string    { return r.d.Name }
+func (r *deliveryResolver) Phone() string   { return r.d.Phone }
+func (r *deliveryResolver) Zip() string     { return r.d.Zip }
+func (r *deliveryResolver) City() string    { return r.d.City }
+func (r *deliveryResolver) Address() string { return r.d.Address }
+func (r *deliveryResolver) Region() string  { return r.d.Region }
+func (r *deliveryResolver) Email() string   { return r.d.Email }
+
+type paymentResolver struct{ p model.Payment }
+
+func (r *paymentResolver) Transaction() string { return r.p.Transaction }
+func (r *paymentResolver) RequestID() string   { return r.p.RequestID }
+func (r *paymentResolver) Currency() string    { return r.p.Currency }
+func (r *paymentResolver) Provider() string    { return r.p.Provider }
+func (r *paymentResolver) Amount() float64     { return float64(r.p.Amount) }
+func (r *paymentResolver) PaymentDt() graphql.Time {
+	return graphql.Time{Time: time.Unix(r.p.PaymentDT, 0).UTC()}
+}
+func (r *paymentResolver) Bank() string          { return r.p.Bank }
+func (r *paymentResolver) DeliveryCost() float64 { return float64(r.p.DeliveryCost) }
+func (r *paymentResolver) GoodsTotal() float64   { return float64(r.p.GoodsTotal) }
+func (r *paymentResolver) CustomFee() float64    { return float64(r.p.CustomFee) }
+
+type itemResolver struct{ it model.Item }
+
+func (r *itemResolver) ChrtID() graphql.ID  { return graphql.ID(strconv.FormatInt(r.it.ChrtID, 10)) }
+func (r *itemResolver) TrackNumber() string { return r.it.TrackNumber }
+func (r *itemResolver) Price() float64      { return float64(r.it.Price) }
+func (r *itemResolver) Rid() string         { return r.it.RID }
+func (r *itemResolver) Name() string        { return r.it.Name }
+func (r *itemResolver) Sale() int32         { return int32(r.it.Sale) }
+func (r *itemResolver) Size() string        { return r.it.Size }
+func (r *itemResolver) TotalPrice() float64 { return float64(r.it.TotalPrice) }
+func (r *itemResolver) NmID() graphql.ID    { return graphql.ID(strconv.FormatInt(r.it.NmID, 10)) }
+func (r *itemResolver) Brand() string       { return r.it.Brand }
+func (r *itemResolver) Status() int32       { return int32(r.it.Status) }
@@ -0,0 +1,174 @@
+// internal/graphqlapi/graphqlapi_test.go
+package graphqlapi_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+
+	"demo/orders/internal/graphqlapi"
+	"demo/orders/internal/model"
+	"demo/orders/internal/store"
+	"demo/orders/internal/store/storemock"
+)
+
+type mapCache struct {
+	mu sync.Mutex
+	m  map[string]model.Order
+}
+
+func (c *mapCache) GetWithETag(id string) (model.Order, string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	o, ok := c.m[id]
+	return o, "", ok
+}
+
+func (c *mapCache) Set(id string, v model.Order) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.m[id] = v
+	return ""
+}
+
+func order(id, customer string) model.Order {
+	return model.Order{
+		OrderUID:    id,
+		CustomerID:  customer,
+		DateCreated: time.Date(2021, 11, 26, 6, 22, 19, 0, time.UTC),
+		Delivery:    model.Delivery{City: "Kiryat Mozkin", Name: "Test Testov"},
+		Items:       []model.Item{{ChrtID: 9934930, Name: "Mascaras"}},
+	}
+}
+
+type gqlResponse struct {
+	Data   map[string]json.RawMessage `json:"data"`
+	Errors []struct{ Message string } `json:"errors"`
+}
+
+func query(t *testing.T, h http.Handler, q string) gqlResponse {
+	t.Helper()
+	body, err := json.Marshal(map[string]string{"query": q})
+	require.NoError(t, err)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(string(body))))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var resp gqlResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Empty(t, resp.Errors)
+	return resp
+}
+
+func TestOrderByID_BatchedAcrossAliases(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repo := storemock.NewMockRepository(ctrl)
+	cache := &mapCache{m: map[string]model.Order{"cached0001": order("cached0001", "c1")}}
+
+	// три алиаса, один из них в кэше — в БД один запрос на оставшиеся два
+	repo.EXPECT().GetOrders(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ any, ids []string) ([]model.Order, error) {
+			sort.Strings(ids)
+			require.Equal(t, []string{"missing001", "order0001"}, ids)
+			return []model.Order{order("order0001", "c1")}, nil
+		}).Times(1)
+
+	resp := query(t, graphqlapi.Handler(repo, cache), `{
+		a: order(id: "order0001") { orderUid delivery { city } items { name chrtId } }
+		b: order(id: "cached0001") { orderUid }
+		c: order(id: "missing001") { orderUid }
+	}`)
+
+	require.JSONEq(t, `{"orderUid":"order0001","delivery":{"city":"Kiryat Mozkin"},"items":[{"name":"Mascaras","chrtId":"9934930"}]}`, string(resp.Data["a"]))
+	require.JSONEq(t, `{"orderUid":"cached0001"}`, string(resp.Data["b"]))
+	require.JSONEq(t, `null`, string(resp.Data["c"]))
+	_, _, ok := cache.GetWithETag("order0001")
+	require.True(t, ok)
+}
+
+func TestOrdersWithCustomerHistory_NoNPlusOne(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repo := storemock.NewMockRepository(ctrl)
+
+	repo.EXPECT().ListOrders(gomock.Any(), store.OrderFilter{Currency: "USD"}, "", 3).
+		Return([]model.Order{order("o1", "alice"), order("o2", "bob"), order("o3", "alice")}, nil)
+	// история всех покупателей страницы — одним вызовом
+	repo.EXPECT().CustomerOrders(gomock.Any(), gomock.Any(), 2).
+		DoAndReturn(func(_ any, customers []string, _ int) (map[string][]model.Order, error) {
+			sort.Strings(customers)
+			require.Equal(t, []string{"alice", "bob"}, customers)
+			return map[string][]model.Order{
+				"alice": {order("o3", "alice"), order("o1", "alice")},
+				"bob":   {order("o2", "bob")},
+			}, nil
+		}).Times(1)
+
+	resp := query(t, graphqlapi.Handler(repo, &mapCache{m: map[string]model.Order{}}), `{
+		orders(filter: {currency: "USD"}, first: 3) {
+			nextCursor
+			orders { orderUid customerHistory(first: 2) { orderUid } }
+		}
+	}`)
+
+	var page struct {
+		NextCursor *string `json:"nextCursor"`
+		Orders     []struct {
+			OrderUID        string `json:"orderUid"`
+			CustomerHistory []struct {
+				OrderUID string `json:"orderUid"`
+			} `json:"customerHistory"`
+		} `json:"orders"`
+	}
+	require.NoError(t, json.Unmarshal(resp.Data["orders"], &page))
+	require.Equal(t, "o3", *page.NextCursor)
+	require.Len(t, page.Orders, 3)
+	require.Len(t, page.Orders[0].CustomerHistory, 2)
+	require.Equal(t, "o2", page.Orders[1].CustomerHistory[0].OrderUID)
+}
+
+func TestQueryLimits(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repo := storemock.NewMockRepository(ctrl)
+	h := graphqlapi.Handler(repo, &mapCache{m: map[string]model.Order{}})
+	errorsOf := func(q string) []string {
+		body, err := json.Marshal(map[string]string{"query": q})
+		require.NoError(t, err)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(string(body))))
+		var resp gqlResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		var msgs []string
+		for _, e := range resp.Errors {
+			msgs = append(msgs, e.Message)
+		}
+		return msgs
+	}
+
+	// вложенные истории отсекаются до резолверов — в БД ни одного запроса
+	msgs := errorsOf(`{ customerHistory(customerId: "c1") { customerHistory { customerHistory { customerHistory { customerHistory { customerHistory { orderUid } } } } } } }`)
+	require.NotEmpty(t, msgs)
+	require.Contains(t, strings.Join(msgs, "\n"), "exceeds max depth")
+
+	repo.EXPECT().GetOrders(gomock.Any(), []string{"order0001"}).Return([]model.Order{order("order0001", "c1")}, nil)
+	require.Equal(t, []string{"first: 1..50"}, errorsOf(`{ order(id: "order0001") { customerHistory(first: 51) { orderUid } } }`))
+	require.Equal(t, []string{"first: 1..500"}, errorsOf(`{ customerHistory(customerId: "c1", first: 501) { orderUid } }`))
+}
+
+func TestAmountsAboveInt32(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repo := storemock.NewMockRepository(ctrl)
+	o := order("order0001", "c1")
+	o.Payment.Amount = 3_000_000_000
+	o.Items[0].Price, o.Items[0].TotalPrice = 1<<40, 1<<40
+	cache := &mapCache{m: map[string]model.Order{"order0001": o}}
+
+	resp := query(t, graphqlapi.Handler(repo, cache), `{ order(id: "order0001") { payment { amount } items { price totalPrice } } }`)
+	require.JSONEq(t, `{"payment":{"amount":3000000000},"items":[{"price":1099511627776,"totalPrice":1099511627776}]}`, string(resp.Data["order"]))
+}
@@ -0,0 +1,72 @@
+package graphqlapi
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// loader — минимальный dataloader: ключи, запрошенные параллельными резолверами в течение wait,
+// уходят в один вызов fetch. Результаты запоминаются на время запроса.
+type loader[K comparable, V any] struct {
+	wait  time.Duration
+	fetch func(ctx context.Context, keys []K) (map[K]V, error)
+
+	mu    sync.Mutex
+	batch *batch[K, V]
+	done  map[K]*batch[K, V]
+}
+
+type batch[K comparable, V any] struct {
+	keys  []K
+	ready chan struct{}
+	res   map[K]V
+	err   error
+}
+
+func newLoader[K comparable, V any](wait time.Duration, fetch func(context.Context, []K) (map[K]V, error)) *loader[K, V] {
+	return &loader[K, V]{wait: wait, fetch: fetch, done: make(map[K]*batch[K, V])}
+}
+
+// Load возвращает значение по ключу; ok=false, если fetch его не вернул.
+func (l *loader[K, V]) Load(ctx context.Context, key K) (V, bool, error) {
+	l.mu.Lock()
+	b, seen := l.done[key]
+	if !seen {
+		if l.batch == nil {
+			l.batch = &batch[K, V]{ready: make(chan struct{})}
+			// пачка общая: отмена запроса первого вызвавшего не должна ронять остальных
+			go l.run(context.WithoutCancel(ctx), l.batch)
+		}
+		b = l.batch
+		b.keys = append(b.keys, key)
+		l.done[key] = b
+	}
+	l.mu.Unlock()
+
+	select {
+	case <-b.ready:
+	case <-ctx.Done():
+		var zero V
+		return zero, false, ctx.Err()
+	}
+	if b.err != nil {
+		var zero V
+		return zero, false, b.err
+	}
+	v, ok := b.res[key]
+	return v, ok, nil
+}
+
+func (l *loader[K, V]) run(ctx context.Context, b *batch[K, V]) {
+	time.Sleep(l.wait)
+	l.mu.Lock()
+	l.batch = nil // новые ключи пойдут уже в следующую пачку
+	keys := b.keys
+	l.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, batchTimeout)
+	defer cancel()
+	b.res, b.err = l.fetch(ctx, keys)
+	close(b.ready)
+}
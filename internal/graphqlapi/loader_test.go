@@ -0,0 +1,60 @@
+package graphqlapi
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoader_CallerCancelDoesNotFailBatch(t *testing.T) {
+	release := make(chan struct{})
+	l := newLoader(100*time.Millisecond, func(ctx context.Context, keys []string) (map[string]int, error) {
+		<-release
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		out := make(map[string]int, len(keys))
+		for _, k := range keys {
+			out[k] = len(k)
+		}
+		return out, nil
+	})
+
+	// первый вызвавший открывает пачку и уходит
+	first, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_, _, err := l.Load(first, "a")
+		require.ErrorIs(t, err, context.Canceled)
+	}()
+	require.Eventually(t, func() bool {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		return len(l.done) == 1
+	}, time.Second, time.Millisecond)
+
+	var v int
+	var ok bool
+	var err error
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		v, ok, err = l.Load(context.Background(), "bb")
+	}()
+	require.Eventually(t, func() bool {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		return len(l.done) == 2
+	}, time.Second, time.Millisecond)
+	cancel()
+	close(release)
+	wg.Wait()
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, 2, v)
+}
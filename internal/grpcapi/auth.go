@@ -0,0 +1,63 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"demo/orders/internal/auth"
+)
+
+// AuthOptions — перехватчики аутентификации по тем же authenticators, что и у HTTP: ключ или токен
+// передаются в метаданных authorization или x-api-key. Без authenticators аутентификация выключена.
+func AuthOptions(authenticators []auth.Authenticator) []grpc.ServerOption {
+	if len(authenticators) == 0 {
+		return nil
+	}
+	unary := func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := authenticate(ctx, authenticators)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+	stream := func(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := authenticate(ss.Context(), authenticators)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &authStream{ServerStream: ss, ctx: ctx})
+	}
+	return []grpc.ServerOption{grpc.ChainUnaryInterceptor(unary), grpc.ChainStreamInterceptor(stream)}
+}
+
+// authenticate проверяет метаданные вызова как заголовки HTTP и кладёт субъекта в контекст.
+func authenticate(ctx context.Context, authenticators []auth.Authenticator) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	h := make(http.Header, len(md))
+	for k, vs := range md {
+		h[http.CanonicalHeaderKey(k)] = vs
+	}
+	p, err := auth.Authenticate(authenticators, (&http.Request{Header: h}).WithContext(ctx))
+	if err != nil {
+		detail := "authentication required"
+		if !errors.Is(err, auth.ErrNoCredentials) {
+			detail = err.Error()
+		}
+		return nil, status.Error(codes.Unauthenticated, detail)
+	}
+	return auth.NewContext(ctx, p), nil
+}
+
+// authStream подменяет контекст потока на контекст с субъектом.
+type authStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authStream) Context() context.Context { return s.ctx }
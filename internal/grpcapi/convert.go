@@ -0,0 +1,91 @@
+package grpcapi
+
+import (
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"demo/orders/internal/feed"
+	"demo/orders/internal/model"
+	pb "demo/orders/internal/pb/ordersv1"
+	"demo/orders/internal/store"
+)
+
+func toPB(o model.Order) *pb.Order {
+	items := make([]*pb.Item, 0, len(o.Items))
+	for _, it := range o.Items {
+		items = append(items, &pb.Item{
+			ChrtId:      it.ChrtID,
+			TrackNumber: it.TrackNumber,
+			Price:       int64(it.Price),
+			Rid:         it.RID,
+			Name:        it.Name,
+			Sale:        int32(it.Sale),
+			Size:        it.Size,
+			TotalPrice:  int64(it.TotalPrice),
+			NmId:        it.NmID,
+			Brand:       it.Brand,
+			Status:      int32(it.Status),
+		})
+	}
+	return &pb.Order{
+		OrderUid:    o.OrderUID,
+		TrackNumber: o.TrackNumber,
+		Entry:       o.Entry,
+		Delivery: &pb.Delivery{
+			Name:    o.Delivery.Name,
+			Phone:   o.Delivery.Phone,
+			Zip:     o.Delivery.Zip,
+			City:    o.Delivery.City,
+			Address: o.Delivery.Address,
+			Region:  o.Delivery.Region,
+			Email:   o.Delivery.Email,
+		},
+		Payment: &pb.Payment{
+			Transaction:  o.Payment.Transaction,
+			RequestId:    o.Payment.RequestID,
+			Currency:     o.Payment.Currency,
+			Provider:     o.Payment.Provider,
+			Amount:       int64(o.Payment.Amount),
+			PaymentDt:    o.Payment.PaymentDT,
+			Bank:         o.Payment.Bank,
+			DeliveryCost: int64(o.Payment.DeliveryCost),
+			GoodsTotal:   int64(o.Payment.GoodsTotal),
+			CustomFee:    int64(o.Payment.CustomFee),
+		},
+		Items:             items,
+		Locale:            o.Locale,
+		InternalSignature: o.InternalSignature,
+		CustomerId:        o.CustomerID,
+		DeliveryService:   o.DeliveryService,
+		Shardkey:          o.ShardKey,
+		SmId:              int32(o.SmID),
+		DateCreated:       timestamppb.New(o.DateCreated),
+		OofShard:          o.OofShard,
+	}
+}
+
+func tsOrZero(ts *timestamppb.Timestamp) time.Time {
+	if ts == nil {
+		return time.Time{}
+	}
+	return ts.AsTime()
+}
+
+func storeFilter(f *pb.OrderFilter) store.OrderFilter {
+	return store.OrderFilter{
+		CustomerID:      f.GetCustomerId(),
+		DeliveryService: f.GetDeliveryService(),
+		Currency:        f.GetCurrency(),
+		CreatedFrom:     tsOrZero(f.GetCreatedFrom()),
+		CreatedTo:       tsOrZero(f.GetCreatedTo()),
+	}
+}
+
+func feedFilter(f *pb.OrderFilter) feed.Filter {
+	return feed.Filter{
+		CustomerID:      f.GetCustomerId(),
+		DeliveryService: f.GetDeliveryService(),
+		Currency:        f.GetCurrency(),
+	}
+}
@@ -0,0 +1,150 @@
+// Package grpcapi — gRPC-реализация OrdersService поверх того же кэша, репозитория и ленты, что и HTTP.
+// Заказы отдаются в представлении роли вызывающего (internal/redact), как и по HTTP.
+package grpcapi
+
+import (
+	"context"
+	"log/slog"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"demo/orders/internal/feed"
+	"demo/orders/internal/model"
+	pb "demo/orders/internal/pb/ordersv1"
+	"demo/orders/internal/redact"
+	"demo/orders/internal/store"
+)
+
+// Cache — то, что сервер использует из кэша заказов сервиса.
+type Cache interface {
+	GetWithETag(id string) (model.Order, string, bool)
+	Set(id string, v model.Order) string
+}
+
+type Server struct {
+	pb.UnimplementedOrdersServiceServer
+
+	repo     store.Repository
+	cache    Cache
+	hub      *feed.Hub
+	batchMax int
+}
+
+func New(repo store.Repository, cache Cache, hub *feed.Hub, batchMax int) *Server {
+	return &Server{repo: repo, cache: cache, hub: hub, batchMax: batchMax}
+}
+
+// Register вешает сервис на grpc.Server.
+func (s *Server) Register(gs *grpc.Server) { pb.RegisterOrdersServiceServer(gs, s) }
+
+func (s *Server) GetOrder(ctx context.Context, req *pb.GetOrderRequest) (*pb.GetOrderResponse, error) {
+	id := req.GetOrderUid()
+	if id == "" {
+		return nil, status.Error(codes.InvalidArgument, "order_uid: required")
+	}
+	if o, tag, ok := s.cache.GetWithETag(id); ok {
+		return &pb.GetOrderResponse{Order: toPB(redact.Order(ctx, o)), Etag: tag, CacheHit: true}, nil
+	}
+	o, ok, err := s.repo.GetOrder(ctx, id)
+	if err != nil {
+		slog.ErrorContext(ctx, "grpc get order", "order_uid", id, "err", err)
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "order %s not found", id)
+	}
+	tag := s.cache.Set(id, o)
+	return &pb.GetOrderResponse{Order: toPB(redact.Order(ctx, o)), Etag: tag}, nil
+}
+
+func (s *Server) BatchGetOrders(ctx context.Context, req *pb.BatchGetOrdersRequest) (*pb.BatchGetOrdersResponse, error) {
+	seen := make(map[string]struct{}, len(req.GetOrderUids()))
+	var ids []string
+	for _, id := range req.GetOrderUids() {
+		if _, dup := seen[id]; id == "" || dup {
+			continue
+		}
+		seen[id] = struct{}{}
+		ids = append(ids, id)
+	}
+	if len(ids) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "order_uids: required")
+	}
+	if s.batchMax > 0 && len(ids) > s.batchMax {
+		return nil, status.Errorf(codes.InvalidArgument, "order_uids: at most %d ids per request", s.batchMax)
+	}
+
+	found := make(map[string]model.Order, len(ids))
+	var misses []string
+	for _, id := range ids {
+		if o, _, ok := s.cache.GetWithETag(id); ok {
+			found[id] = o
+		} else {
+			misses = append(misses, id)
+		}
+	}
+	if len(misses) > 0 {
+		orders, err := s.repo.GetOrders(ctx, misses)
+		if err != nil {
+			slog.ErrorContext(ctx, "grpc batch get", "err", err)
+			return nil, status.Error(codes.Internal, "internal error")
+		}
+		for _, o := range orders {
+			s.cache.Set(o.OrderUID, o)
+			found[o.OrderUID] = o
+		}
+	}
+
+	view := redact.ViewOf(ctx)
+	resp := &pb.BatchGetOrdersResponse{}
+	for _, id := range ids {
+		if o, ok := found[id]; ok {
+			resp.Orders = append(resp.Orders, toPB(redact.Apply(view, o)))
+		} else {
+			resp.Missing = append(resp.Missing, id)
+		}
+	}
+	return resp, nil
+}
+
+func (s *Server) ListOrders(req *pb.ListOrdersRequest, stream grpc.ServerStreamingServer[pb.ListOrdersResponse]) error {
+	view := redact.ViewOf(stream.Context())
+	err := s.repo.StreamOrders(stream.Context(), storeFilter(req.GetFilter()), func(o model.Order) error {
+		return stream.Send(&pb.ListOrdersResponse{Order: toPB(redact.Apply(view, o))})
+	})
+	if err != nil {
+		if _, ok := status.FromError(err); ok {
+			return err // ошибка отправки клиенту — уже со статусом
+		}
+		slog.ErrorContext(stream.Context(), "grpc list orders", "err", err)
+		return status.Error(codes.Internal, "internal error")
+	}
+	return nil
+}
+
+func (s *Server) WatchOrders(req *pb.WatchOrdersRequest, stream grpc.ServerStreamingServer[pb.WatchOrdersResponse]) error {
+	backlog, events, cancel := s.hub.Subscribe(feedFilter(req.GetFilter()), req.GetLastEventId())
+	defer cancel()
+	view := redact.ViewOf(stream.Context())
+
+	for _, ev := range backlog {
+		if err := stream.Send(&pb.WatchOrdersResponse{Id: ev.ID, Order: toPB(redact.Apply(view, ev.Order))}); err != nil {
+			return err
+		}
+	}
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case ev, ok := <-events:
+			if !ok {
+				return status.Error(codes.Unavailable, "subscriber too slow, resubscribe with last_event_id")
+			}
+			if err := stream.Send(&pb.WatchOrdersResponse{Id: ev.ID, Order: toPB(redact.Apply(view, ev.Order))}); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,258 @@
+// internal/grpcapi/server_test.go
+package grpcapi_test
+
+import (
+	"context"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"demo/orders/internal/auth"
+	"demo/orders/internal/feed"
+	"demo/orders/internal/grpcapi"
+	"demo/orders/internal/model"
+	pb "demo/orders/internal/pb/ordersv1"
+	"demo/orders/internal/store"
+	"demo/orders/internal/store/storemock"
+)
+
+type mapCache struct {
+	mu sync.Mutex
+	m  map[string]model.Order
+}
+
+func (c *mapCache) GetWithETag(id string) (model.Order, string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	o, ok := c.m[id]
+	return o, `"` + id + `"`, ok
+}
+
+func (c *mapCache) Set(id string, v model.Order) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.m[id] = v
+	return `"` + id + `"`
+}
+
+type fixture struct {
+	repo   *storemock.MockRepository
+	cache  *mapCache
+	hub    *feed.Hub
+	client pb.OrdersServiceClient
+}
+
+// newFixture поднимает сервер на in-memory bufconn и возвращает сгенерированный клиент к нему.
+func newFixture(t *testing.T, opts ...grpc.ServerOption) *fixture {
+	ctrl := gomock.NewController(t)
+	f := &fixture{
+		repo:  storemock.NewMockRepository(ctrl),
+		cache: &mapCache{m: map[string]model.Order{}},
+		hub:   feed.NewHub(16, 16),
+	}
+
+	lis := bufconn.Listen(1 << 20)
+	gs := grpc.NewServer(opts...)
+	grpcapi.New(f.repo, f.cache, f.hub, 3).Register(gs)
+	go func() { _ = gs.Serve(lis) }()
+	t.Cleanup(gs.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	f.client = pb.NewOrdersServiceClient(conn)
+	return f
+}
+
+func order(id string) model.Order {
+	return model.Order{
+		OrderUID:    id,
+		CustomerID:  "test",
+		DateCreated: time.Date(2021, 11, 26, 6, 22, 19, 0, time.UTC),
+		Payment:     model.Payment{Currency: "USD", Amount: 1817},
+		Items:       []model.Item{{ChrtID: 9934930, Name: "Mascaras", Price: 453}},
+	}
+}
+
+func TestGetOrder(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+	f.repo.EXPECT().GetOrder(gomock.Any(), "order0001").Return(order("order0001"), true, nil)
+
+	resp, err := f.client.GetOrder(ctx, &pb.GetOrderRequest{OrderUid: "order0001"})
+	require.NoError(t, err)
+	require.False(t, resp.GetCacheHit())
+	require.Equal(t, int64(1817), resp.GetOrder().GetPayment().GetAmount())
+	require.Equal(t, "Mascaras", resp.GetOrder().GetItems()[0].GetName())
+	require.True(t, resp.GetOrder().GetDateCreated().AsTime().Equal(order("x").DateCreated))
+
+	// второй раз — из кэша, в репозиторий не ходим
+	resp, err = f.client.GetOrder(ctx, &pb.GetOrderRequest{OrderUid: "order0001"})
+	require.NoError(t, err)
+	require.True(t, resp.GetCacheHit())
+}
+
+func TestGetOrder_Errors(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+	f.repo.EXPECT().GetOrder(gomock.Any(), "missing01").Return(model.Order{}, false, nil)
+
+	_, err := f.client.GetOrder(ctx, &pb.GetOrderRequest{OrderUid: "missing01"})
+	require.Equal(t, codes.NotFound, status.Code(err))
+	_, err = f.client.GetOrder(ctx, &pb.GetOrderRequest{})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestBatchGetOrders(t *testing.T) {
+	f := newFixture(t)
+	f.cache.Set("cached0001", order("cached0001"))
+	f.repo.EXPECT().GetOrders(gomock.Any(), []string{"fromdb0001", "missing001"}).Return([]model.Order{order("fromdb0001")}, nil)
+
+	resp, err := f.client.BatchGetOrders(context.Background(), &pb.BatchGetOrdersRequest{
+		OrderUids: []string{"fromdb0001", "cached0001", "missing001"},
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.GetOrders(), 2)
+	require.Equal(t, "fromdb0001", resp.GetOrders()[0].GetOrderUid())
+	require.Equal(t, []string{"missing001"}, resp.GetMissing())
+
+	_, err = f.client.BatchGetOrders(context.Background(), &pb.BatchGetOrdersRequest{OrderUids: []string{"a", "b", "c", "d"}})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestListOrders(t *testing.T) {
+	f := newFixture(t)
+	f.repo.EXPECT().StreamOrders(gomock.Any(), store.OrderFilter{Currency: "USD"}, gomock.Any()).
+		DoAndReturn(func(_ any, _ store.OrderFilter, fn func(model.Order) error) error {
+			for _, id := range []string{"order0001", "order0002"} {
+				if err := fn(order(id)); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+
+	stream, err := f.client.ListOrders(context.Background(), &pb.ListOrdersRequest{Filter: &pb.OrderFilter{Currency: "USD"}})
+	require.NoError(t, err)
+	var got []string
+	for {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		got = append(got, resp.GetOrder().GetOrderUid())
+	}
+	require.Equal(t, []string{"order0001", "order0002"}, got)
+}
+
+func TestWatchOrders_Resume(t *testing.T) {
+	f := newFixture(t)
+	first := f.hub.Publish(order("order0001"))
+	f.hub.Publish(order("order0002"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	stream, err := f.client.WatchOrders(ctx, &pb.WatchOrdersRequest{LastEventId: first.ID})
+	require.NoError(t, err)
+
+	resp, err := stream.Recv()
+	require.NoError(t, err)
+	require.Equal(t, "order0002", resp.GetOrder().GetOrderUid())
+	require.Greater(t, resp.GetId(), first.ID)
+}
+
+func TestAuth(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys")
+	require.NoError(t, os.WriteFile(path, []byte(auth.HashAPIKey("s3cret")+" support-bot support\n"), 0o600))
+	keys, err := auth.LoadAPIKeys(path)
+	require.NoError(t, err)
+	f := newFixture(t, grpcapi.AuthOptions([]auth.Authenticator{keys})...)
+	f.cache.Set("order0001", order("order0001"))
+
+	_, err = f.client.GetOrder(context.Background(), &pb.GetOrderRequest{OrderUid: "order0001"})
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+	bad := metadata.AppendToOutgoingContext(context.Background(), "x-api-key", "wrong")
+	_, err = f.client.GetOrder(bad, &pb.GetOrderRequest{OrderUid: "order0001"})
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+	require.ErrorContains(t, err, "unknown API key")
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "ApiKey s3cret")
+	resp, err := f.client.GetOrder(ctx, &pb.GetOrderRequest{OrderUid: "order0001"})
+	require.NoError(t, err)
+	require.Equal(t, "order0001", resp.GetOrder().GetOrderUid())
+
+	// потоки проверяются так же
+	stream, err := f.client.ListOrders(context.Background(), &pb.ListOrdersRequest{})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestRedactByRole(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys")
+	lines := auth.HashAPIKey("s3cret") + " support-bot support\n" +
+		auth.HashAPIKey("bi") + " dashboards analytics\n" +
+		auth.HashAPIKey("tracking") + " tracking-page public\n"
+	require.NoError(t, os.WriteFile(path, []byte(lines), 0o600))
+	keys, err := auth.LoadAPIKeys(path)
+	require.NoError(t, err)
+	f := newFixture(t, grpcapi.AuthOptions([]auth.Authenticator{keys})...)
+	o := order("order0001")
+	o.Delivery = model.Delivery{Name: "Test Testov", Phone: "+9720000000", Email: "test@gmail.com"}
+	f.cache.Set(o.OrderUID, o)
+	as := func(key string) context.Context {
+		return metadata.AppendToOutgoingContext(context.Background(), "x-api-key", key)
+	}
+
+	resp, err := f.client.GetOrder(as("s3cret"), &pb.GetOrderRequest{OrderUid: o.OrderUID})
+	require.NoError(t, err)
+	require.Equal(t, "+9720000000", resp.GetOrder().GetDelivery().GetPhone())
+
+	resp, err = f.client.GetOrder(as("bi"), &pb.GetOrderRequest{OrderUid: o.OrderUID})
+	require.NoError(t, err)
+	require.Equal(t, "+********00", resp.GetOrder().GetDelivery().GetPhone())
+	require.Equal(t, "t***@gmail.com", resp.GetOrder().GetDelivery().GetEmail())
+	cached, _, _ := f.cache.GetWithETag(o.OrderUID)
+	require.Equal(t, "+9720000000", cached.Delivery.Phone, "в кэше заказ целиком")
+
+	batch, err := f.client.BatchGetOrders(as("tracking"), &pb.BatchGetOrdersRequest{OrderUids: []string{o.OrderUID}})
+	require.NoError(t, err)
+	require.Empty(t, batch.GetOrders()[0].GetDelivery().GetPhone())
+	require.Empty(t, batch.GetOrders()[0].GetCustomerId())
+
+	// выгрузка и лента — тоже
+	f.repo.EXPECT().StreamOrders(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ any, _ store.OrderFilter, fn func(model.Order) error) error { return fn(o) })
+	list, err := f.client.ListOrders(as("bi"), &pb.ListOrdersRequest{})
+	require.NoError(t, err)
+	item, err := list.Recv()
+	require.NoError(t, err)
+	require.Equal(t, "+********00", item.GetOrder().GetDelivery().GetPhone())
+
+	first := f.hub.Publish(order("order0000"))
+	f.hub.Publish(o)
+	ctx, cancel := context.WithTimeout(as("tracking"), 5*time.Second)
+	defer cancel()
+	watch, err := f.client.WatchOrders(ctx, &pb.WatchOrdersRequest{LastEventId: first.ID})
+	require.NoError(t, err)
+	ev, err := watch.Recv()
+	require.NoError(t, err)
+	require.Empty(t, ev.GetOrder().GetDelivery().GetEmail())
+	require.Equal(t, "Mascaras", ev.GetOrder().GetItems()[0].GetName())
+}
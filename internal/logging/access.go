@@ -0,0 +1,127 @@
+package logging
+
+import (
+	"bufio"
+	"context"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type annotationsKey struct{}
+
+type annotations struct {
+	mu    sync.Mutex
+	attrs []slog.Attr
+}
+
+// Annotate добавляет поля в access-лог текущего запроса — например, order_uid из пути.
+// Вне AccessLog ничего не делает.
+func Annotate(ctx context.Context, attrs ...slog.Attr) {
+	a, ok := ctx.Value(annotationsKey{}).(*annotations)
+	if !ok {
+		return
+	}
+	a.mu.Lock()
+	a.attrs = append(a.attrs, attrs...)
+	a.mu.Unlock()
+}
+
+// AccessLog пишет по записи на запрос: метод, путь, статус, размер ответа, время, результат кэша
+// (X-Cache) и поля из Annotate. 5xx — уровнем error, остальное — info. Статус заодно ставится
+// на спан запроса, если он есть (tracing.HTTP снаружи). Оборванный паникой ответ (в том числе
+// http.ErrAbortHandler посреди выгрузки) тоже попадает в лог — с aborted и уровнем error, —
+// после чего паника идёт дальше, к net/http.
+func AccessLog(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &recorder{ResponseWriter: w}
+		ann := &annotations{}
+		defer func() {
+			p := recover()
+			status := rec.status
+			if status == 0 {
+				status = http.StatusOK
+				if p != nil {
+					status = http.StatusInternalServerError // ответа не было вовсе
+				}
+			}
+			attrs := []slog.Attr{
+				slog.String("method", r.Method),
+				slog.String("path", r.URL.Path),
+				slog.Int("status", status),
+				slog.Int64("bytes", rec.bytes),
+				slog.Float64("latency_ms", float64(time.Since(start).Microseconds())/1000),
+				slog.String("remote", r.RemoteAddr),
+			}
+			if c := w.Header().Get("X-Cache"); c != "" {
+				attrs = append(attrs, slog.String("cache", c))
+			}
+			ann.mu.Lock()
+			attrs = append(attrs, ann.attrs...)
+			ann.mu.Unlock()
+
+			level := slog.LevelInfo
+			span := trace.SpanFromContext(r.Context())
+			span.SetAttributes(attribute.Int("http.response.status_code", status))
+			switch {
+			case p != nil:
+				level = slog.LevelError
+				attrs = append(attrs, slog.Bool("aborted", true))
+				span.SetStatus(codes.Error, "response aborted")
+			case status >= 500:
+				level = slog.LevelError
+				span.SetStatus(codes.Error, http.StatusText(status))
+			}
+			slog.LogAttrs(r.Context(), level, "http request", attrs...)
+			if p != nil {
+				panic(p)
+			}
+		}()
+		next.ServeHTTP(rec, r.WithContext(context.WithValue(r.Context(), annotationsKey{}, ann)))
+	})
+}
+
+// recorder запоминает статус и размер ответа. Flush и Hijack пробрасываются (лента, WebSocket),
+// Unwrap — для http.ResponseController (дедлайны записи).
+type recorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (w *recorder) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *recorder) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += int64(n)
+	return n, err
+}
+
+func (w *recorder) Flush() {
+	_ = http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+func (w *recorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := http.NewResponseController(w.ResponseWriter).Hijack()
+	if err == nil && w.status == 0 {
+		w.status = http.StatusSwitchingProtocols
+	}
+	return conn, rw, err
+}
+
+func (w *recorder) Unwrap() http.ResponseWriter { return w.ResponseWriter }
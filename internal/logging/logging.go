@@ -0,0 +1,67 @@
+// Package logging — структурные логи на log/slog: JSON или текст, уровень из конфигурации,
+// ID запроса из контекста в каждой записи и access-лог HTTP.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"demo/orders/internal/requestid"
+)
+
+// ParseLevel разбирает LOG_LEVEL: debug|info|warn|error, пусто — info.
+func ParseLevel(s string) (slog.Level, error) {
+	switch strings.ToLower(s) {
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "debug":
+		return slog.LevelDebug, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	}
+	return 0, fmt.Errorf("unknown LOG_LEVEL=%q (want debug|info|warn|error)", s)
+}
+
+// New создаёт логгер: format json (по умолчанию) или text. Записи, сделанные с контекстом
+// (slog.InfoContext и т.п.), получают поля request_id, trace_id и span_id.
+func New(w io.Writer, format string, level slog.Leveler) (*slog.Logger, error) {
+	opts := &slog.HandlerOptions{Level: level}
+	var h slog.Handler
+	switch format {
+	case "", "json":
+		h = slog.NewJSONHandler(w, opts)
+	case "text":
+		h = slog.NewTextHandler(w, opts)
+	default:
+		return nil, fmt.Errorf("unknown LOG_FORMAT=%q (want json|text)", format)
+	}
+	return slog.New(contextHandler{h}), nil
+}
+
+// contextHandler дописывает в запись ID запроса и, если запрос трассируется, trace_id/span_id.
+type contextHandler struct{ slog.Handler }
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id := requestid.FromContext(ctx); id != "" {
+		r.AddAttrs(slog.String("request_id", id))
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		r.AddAttrs(slog.String("trace_id", sc.TraceID().String()), slog.String("span_id", sc.SpanID().String()))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
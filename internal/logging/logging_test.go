@@ -0,0 +1,199 @@
+// internal/logging/logging_test.go
+package logging_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"demo/orders/internal/logging"
+	"demo/orders/internal/requestid"
+)
+
+// capture подменяет логгер по умолчанию и возвращает записанные JSON-записи.
+func capture(t *testing.T, level slog.Level) func() []map[string]any {
+	t.Helper()
+	var buf bytes.Buffer
+	l, err := logging.New(&buf, "json", level)
+	require.NoError(t, err)
+	prev := slog.Default()
+	slog.SetDefault(l)
+	t.Cleanup(func() { slog.SetDefault(prev) })
+	return func() []map[string]any {
+		var out []map[string]any
+		for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+			if line == "" {
+				continue
+			}
+			var m map[string]any
+			require.NoError(t, json.Unmarshal([]byte(line), &m), line)
+			out = append(out, m)
+		}
+		return out
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	for in, want := range map[string]slog.Level{"": slog.LevelInfo, "debug": slog.LevelDebug, "WARN": slog.LevelWarn, "error": slog.LevelError} {
+		got, err := logging.ParseLevel(in)
+		require.NoError(t, err, in)
+		require.Equal(t, want, got, in)
+	}
+	_, err := logging.ParseLevel("verbose")
+	require.Error(t, err)
+	_, err = logging.New(&bytes.Buffer{}, "xml", slog.LevelInfo)
+	require.Error(t, err)
+}
+
+func TestNew_RequestIDAndLevel(t *testing.T) {
+	records := capture(t, slog.LevelInfo)
+	ctx := requestid.NewContext(context.Background(), "req-1")
+	slog.InfoContext(ctx, "hello", "order_uid", "o1")
+	slog.DebugContext(ctx, "hidden")
+	slog.With("component", "x").InfoContext(ctx, "with attrs")
+	slog.Info("no context")
+
+	got := records()
+	require.Len(t, got, 3)
+	require.Equal(t, "hello", got[0]["msg"])
+	require.Equal(t, "req-1", got[0]["request_id"])
+	require.Equal(t, "o1", got[0]["order_uid"])
+	require.Equal(t, "req-1", got[1]["request_id"])
+	require.Equal(t, "x", got[1]["component"])
+	require.NotContains(t, got[2], "request_id")
+}
+
+func TestAccessLog(t *testing.T) {
+	records := capture(t, slog.LevelInfo)
+	h := requestid.Middleware(logging.AccessLog(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logging.Annotate(r.Context(), slog.String("order_uid", "o1"))
+		w.Header().Set("X-Cache", "HIT")
+		require.NoError(t, http.NewResponseController(w).Flush(), "Flush доходит до настоящего ResponseWriter")
+		_, _ = w.Write([]byte("hello"))
+	})))
+	req := httptest.NewRequest(http.MethodGet, "/order/o1", nil)
+	req.Header.Set(requestid.Header, "req-2")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	fail := logging.AccessLog(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "boom", http.StatusServiceUnavailable)
+	}))
+	fail.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/graphql", nil))
+
+	got := records()
+	require.Len(t, got, 2)
+	ok := got[0]
+	require.Equal(t, "http request", ok["msg"])
+	require.Equal(t, "INFO", ok["level"])
+	require.Equal(t, "GET", ok["method"])
+	require.Equal(t, "/order/o1", ok["path"])
+	require.EqualValues(t, 200, ok["status"])
+	require.EqualValues(t, 5, ok["bytes"])
+	require.Equal(t, "HIT", ok["cache"])
+	require.Equal(t, "o1", ok["order_uid"])
+	require.Equal(t, "req-2", ok["request_id"])
+	require.Contains(t, ok, "latency_ms")
+
+	require.Equal(t, "ERROR", got[1]["level"])
+	require.EqualValues(t, 503, got[1]["status"])
+	require.NotContains(t, got[1], "cache")
+}
+
+func TestAccessLog_Hijack(t *testing.T) {
+	records := capture(t, slog.LevelInfo)
+	h := logging.AccessLog(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hj, ok := w.(http.Hijacker) // так апгрейдит gorilla/websocket
+		if !ok {
+			http.Error(w, "no hijacker", http.StatusInternalServerError)
+			return
+		}
+		conn, rw, err := hj.Hijack()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_, _ = rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: test\r\n\r\n")
+		_ = rw.Flush()
+	}))
+	done := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer close(done)
+		h.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+	<-done
+
+	got := records()
+	require.Len(t, got, 1)
+	require.EqualValues(t, 101, got[0]["status"])
+}
+
+func TestAccessLog_TraceSpan(t *testing.T) {
+	records := capture(t, slog.LevelInfo)
+	rec := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec))
+
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := tp.Tracer("test").Start(r.Context(), "GET /order/")
+		defer span.End()
+		logging.AccessLog(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadGateway)
+		})).ServeHTTP(w, r.WithContext(ctx))
+	})
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/order/o1", nil))
+
+	spans := rec.Ended()
+	require.Len(t, spans, 1)
+	require.Equal(t, codes.Error, spans[0].Status().Code)
+	require.Contains(t, spans[0].Attributes(), attribute.Int("http.response.status_code", 502))
+
+	got := records()
+	require.Len(t, got, 1)
+	require.Equal(t, spans[0].SpanContext().TraceID().String(), got[0]["trace_id"])
+	require.Equal(t, spans[0].SpanContext().SpanID().String(), got[0]["span_id"])
+}
+
+func TestAccessLog_Aborted(t *testing.T) {
+	records := capture(t, slog.LevelInfo)
+	rec := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec))
+
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := tp.Tracer("test").Start(r.Context(), "GET /orders/export")
+		defer span.End()
+		logging.AccessLog(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte("{}\n"))
+			panic(http.ErrAbortHandler) // выгрузка оборвалась после первой строки
+		})).ServeHTTP(w, r.WithContext(ctx))
+	})
+	require.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/orders/export", nil))
+	})
+
+	spans := rec.Ended()
+	require.Len(t, spans, 1)
+	require.Equal(t, codes.Error, spans[0].Status().Code)
+
+	got := records()
+	require.Len(t, got, 1)
+	require.Equal(t, "ERROR", got[0]["level"])
+	require.EqualValues(t, 200, got[0]["status"])
+	require.EqualValues(t, 3, got[0]["bytes"])
+	require.Equal(t, true, got[0]["aborted"])
+}
@@ -12,6 +12,8 @@ type Delivery struct {
 	Email   string `json:"email"`
 }
 
+// Payment — оплата. Суммы (amount, delivery_cost, goods_total, custom_fee и цены товаров)
+// — целые числа в минимальных единицах Currency: копейки, центы.
 type Payment struct {
 	Transaction  string `json:"transaction"`
 	RequestID    string `json:"request_id"`
@@ -54,4 +56,42 @@ type Order struct {
 	SmID              int       `json:"sm_id"`
 	DateCreated       time.Time `json:"date_created"`
 	OofShard          string    `json:"oof_shard"`
+
+	// UpdatedAt — время последней записи в БД; в JSON заказа не входит и на ETag не влияет.
+	UpdatedAt time.Time `json:"-"`
+	// Warnings — предупреждения проверки, с которыми заказ принят (карантин). Пишутся вместе
+	// с заказом и заменяются при каждой записи; читаются отдельно, в JSON заказа не входят.
+	Warnings []Warning `json:"-"`
+	// Risk — оценка риска, посчитанная консьюмером при приёме; nil — заказ не оценивался
+	// (оценка выключена или версия записана через HTTP). В JSON заказа не входит.
+	Risk *Risk `json:"-"`
+}
+
+// Warning — нарушение мягкого правила: заказ сохранён, но помечен.
+type Warning struct {
+	Field   string `json:"field"`
+	Pointer string `json:"pointer"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Risk — оценка риска заказа: сумма вкладов сработавших признаков.
+type Risk struct {
+	Score   float64      `json:"score"`
+	Signals []RiskSignal `json:"signals"`
+}
+
+// RiskSignal — сработавший признак риска.
+type RiskSignal struct {
+	Name   string  `json:"name"`
+	Score  float64 `json:"score"` // вклад в Risk.Score: вес признака × его сила (0..1)
+	Reason string  `json:"reason"`
+}
+
+// Review — заказ, отложенный консьюмером на ручную проверку вместо записи в orders.
+type Review struct {
+	Order    Order     `json:"order"`
+	Risk     Risk      `json:"risk"`
+	Warnings []Warning `json:"warnings"`
+	QueuedAt time.Time `json:"queued_at"`
 }
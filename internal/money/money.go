@@ -0,0 +1,130 @@
+// Package money — валюты ISO 4217 и суммы в минимальных единицах (копейки, центы).
+// Суммы заказа хранятся целыми числами в минимальных единицах payment.currency;
+// сколько знаков после запятой у валюты, знает встроенная таблица.
+package money
+
+import (
+	_ "embed"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+//go:embed iso4217.csv
+var iso4217 string
+
+// Currency — валюта из таблицы ISO 4217.
+type Currency struct {
+	Code   string // буквенный код: USD
+	Number string // цифровой код: 840
+	Minor  int    // знаков после запятой: 2 у USD, 0 у JPY, 3 у KWD
+	Name   string
+}
+
+var currencies = mustParse(iso4217)
+
+func mustParse(s string) map[string]Currency {
+	rows, err := csv.NewReader(strings.NewReader(s)).ReadAll()
+	if err != nil {
+		panic("money: iso4217.csv: " + err.Error())
+	}
+	m := make(map[string]Currency, len(rows))
+	for _, r := range rows[1:] { // первая строка — заголовок
+		minor, err := strconv.Atoi(r[2])
+		if err != nil {
+			panic(fmt.Sprintf("money: iso4217.csv: %s: %v", r[0], err))
+		}
+		m[r[0]] = Currency{Code: r[0], Number: r[1], Minor: minor, Name: r[3]}
+	}
+	return m
+}
+
+// Lookup ищет валюту по буквенному коду без учёта регистра.
+func Lookup(code string) (Currency, bool) {
+	c, ok := currencies[strings.ToUpper(strings.TrimSpace(code))]
+	return c, ok
+}
+
+// Known — есть ли код в таблице ISO 4217.
+func Known(code string) bool {
+	_, ok := Lookup(code)
+	return ok
+}
+
+// ErrUnknownCurrency — кода нет в таблице ISO 4217.
+var ErrUnknownCurrency = errors.New("unknown ISO 4217 currency code")
+
+// Money — сумма в минимальных единицах валюты.
+type Money struct {
+	Minor    int64
+	Currency Currency
+}
+
+// New — сумма minor минимальных единиц валюты code.
+func New(minor int64, code string) (Money, error) {
+	c, ok := Lookup(code)
+	if !ok {
+		return Money{}, fmt.Errorf("%w: %q", ErrUnknownCurrency, code)
+	}
+	return Money{Minor: minor, Currency: c}, nil
+}
+
+// Decimal — сумма десятичной строкой с числом знаков валюты: 1817 USD → "18.17", 1817 JPY → "1817".
+func (m Money) Decimal() string {
+	n := m.Minor
+	sign := ""
+	if n < 0 {
+		sign, n = "-", -n
+	}
+	s := strconv.FormatUint(uint64(n), 10)
+	d := m.Currency.Minor
+	if d == 0 {
+		return sign + s
+	}
+	if len(s) <= d {
+		s = strings.Repeat("0", d-len(s)+1) + s
+	}
+	return sign + s[:len(s)-d] + "." + s[len(s)-d:]
+}
+
+// String — "18.17 USD".
+func (m Money) String() string { return m.Decimal() + " " + m.Currency.Code }
+
+// Parse разбирает десятичную строку в сумму валюты code: "18.17" USD → 1817.
+// Знаков после запятой больше, чем у валюты, — ошибка: округлять деньги молча нельзя.
+func Parse(s, code string) (Money, error) {
+	m, err := New(0, code)
+	if err != nil {
+		return Money{}, err
+	}
+	num := strings.TrimSpace(s)
+	neg := strings.HasPrefix(num, "-")
+	num = strings.TrimPrefix(num, "-")
+	whole, frac, _ := strings.Cut(num, ".")
+	if whole == "" || !digits(whole) || !digits(frac) || strings.HasSuffix(num, ".") {
+		return Money{}, fmt.Errorf("invalid amount %q", s)
+	}
+	if len(frac) > m.Currency.Minor {
+		return Money{}, fmt.Errorf("amount %q: %s allows %d decimal places", s, m.Currency.Code, m.Currency.Minor)
+	}
+	n, err := strconv.ParseInt(whole+frac+strings.Repeat("0", m.Currency.Minor-len(frac)), 10, 64)
+	if err != nil {
+		return Money{}, fmt.Errorf("invalid amount %q: %w", s, err)
+	}
+	if neg {
+		n = -n
+	}
+	m.Minor = n
+	return m, nil
+}
+
+func digits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
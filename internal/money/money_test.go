@@ -0,0 +1,90 @@
+// internal/money/money_test.go
+package money_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"demo/orders/internal/model"
+	"demo/orders/internal/money"
+)
+
+func TestLookup(t *testing.T) {
+	c, ok := money.Lookup(" usd")
+	require.True(t, ok)
+	require.Equal(t, money.Currency{Code: "USD", Number: "840", Minor: 2, Name: "US Dollar"}, c)
+
+	for code, minor := range map[string]int{"RUB": 2, "JPY": 0, "KWD": 3, "CLF": 4} {
+		c, ok := money.Lookup(code)
+		require.True(t, ok, code)
+		require.Equal(t, minor, c.Minor, code)
+	}
+	for _, code := range []string{"", "USDX", "ZZZ", "XAU", "HRK"} {
+		require.False(t, money.Known(code), code)
+	}
+}
+
+func TestDecimalAndParse(t *testing.T) {
+	for _, tc := range []struct {
+		minor int64
+		code  string
+		want  string
+	}{
+		{1817, "USD", "18.17"},
+		{5, "EUR", "0.05"},
+		{-5, "EUR", "-0.05"},
+		{0, "RUB", "0.00"},
+		{1817, "JPY", "1817"},
+		{1817, "KWD", "1.817"},
+		{7, "CLF", "0.0007"},
+	} {
+		m, err := money.New(tc.minor, tc.code)
+		require.NoError(t, err)
+		require.Equal(t, tc.want, m.Decimal())
+		back, err := money.Parse(tc.want, tc.code)
+		require.NoError(t, err)
+		require.Equal(t, m, back, tc.want)
+	}
+
+	m, err := money.Parse("18.1", "USD")
+	require.NoError(t, err)
+	require.Equal(t, int64(1810), m.Minor)
+	require.Equal(t, "18.10 USD", m.String())
+
+	for _, s := range []string{"", "-", "1.", ".5", "1,5", "1e3", "18.171", "+1"} {
+		_, err := money.Parse(s, "USD")
+		require.Error(t, err, s)
+	}
+	_, err = money.Parse("1.5", "JPY")
+	require.ErrorContains(t, err, "JPY allows 0 decimal places")
+	_, err = money.New(1, "XXY")
+	require.ErrorIs(t, err, money.ErrUnknownCurrency)
+}
+
+func TestDecimalOrder(t *testing.T) {
+	o := model.Order{
+		OrderUID: "b563feb7b2b84b6test",
+		Payment:  model.Payment{Currency: "USD", Amount: 1817, DeliveryCost: 1500, GoodsTotal: 317, Bank: "alpha"},
+		Items:    []model.Item{{ChrtID: 9934930, Price: 453, Sale: 30, TotalPrice: 317}},
+	}
+	b, err := json.Marshal(money.DecimalOrder(o))
+	require.NoError(t, err)
+	var got map[string]any
+	require.NoError(t, json.Unmarshal(b, &got))
+	require.Equal(t, "b563feb7b2b84b6test", got["order_uid"])
+	p := got["payment"].(map[string]any)
+	require.Equal(t, "18.17", p["amount"])
+	require.Equal(t, "15.00", p["delivery_cost"])
+	require.Equal(t, "0.00", p["custom_fee"])
+	require.Equal(t, "alpha", p["bank"])
+	it := got["items"].([]any)[0].(map[string]any)
+	require.Equal(t, "4.53", it["price"])
+	require.Equal(t, "3.17", it["total_price"])
+	require.EqualValues(t, 30, it["sale"])
+
+	// неизвестная валюта — суммы остаются целыми
+	o.Payment.Currency = "XXY"
+	require.Equal(t, o, money.DecimalOrder(o))
+}
@@ -0,0 +1,47 @@
+package money
+
+import "demo/orders/internal/model"
+
+// DecimalOrder — заказ для JSON-ответа, где суммы оплаты и товаров — десятичные строки
+// в валюте заказа ("18.17" вместо 1817). Валюта не из ISO 4217 — заказ возвращается как есть.
+func DecimalOrder(o model.Order) any {
+	c, ok := Lookup(o.Payment.Currency)
+	if !ok {
+		return o
+	}
+	dec := func(n int) string { return Money{Minor: int64(n), Currency: c}.Decimal() }
+	p := o.Payment
+	out := decimalOrder{
+		Order: o,
+		Payment: decimalPayment{
+			Payment: p, Amount: dec(p.Amount), DeliveryCost: dec(p.DeliveryCost),
+			GoodsTotal: dec(p.GoodsTotal), CustomFee: dec(p.CustomFee),
+		},
+		Items: make([]decimalItem, len(o.Items)),
+	}
+	for i, it := range o.Items {
+		out.Items[i] = decimalItem{Item: it, Price: dec(it.Price), TotalPrice: dec(it.TotalPrice)}
+	}
+	return out
+}
+
+// Поля внешней структуры затеняют одноимённые поля встроенной: encoding/json берёт менее вложенное.
+type decimalOrder struct {
+	model.Order
+	Payment decimalPayment `json:"payment"`
+	Items   []decimalItem  `json:"items"`
+}
+
+type decimalPayment struct {
+	model.Payment
+	Amount       string `json:"amount"`
+	DeliveryCost string `json:"delivery_cost"`
+	GoodsTotal   string `json:"goods_total"`
+	CustomFee    string `json:"custom_fee"`
+}
+
+type decimalItem struct {
+	model.Item
+	Price      string `json:"price"`
+	TotalPrice string `json:"total_price"`
+}
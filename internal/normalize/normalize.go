@@ -0,0 +1,117 @@
+// Package normalize приводит заказ к каноническому виду перед проверкой и записью:
+// трек-номера и валюта — в верхнем регистре, телефоны — E.164, email — в нижнем регистре,
+// имена без лишних пробелов, время — в UTC. Что поменялось, возвращается отчётом.
+package normalize
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"demo/orders/internal/model"
+	"demo/orders/internal/validate"
+)
+
+// Change — одно исправление поля.
+type Change struct {
+	Field   string `json:"field"`   // путь: delivery.phone, items[0].track_number
+	Pointer string `json:"pointer"` // JSON Pointer: /delivery/phone
+	From    string `json:"from"`
+	To      string `json:"to"`
+}
+
+// Options — настройки нормализации.
+type Options struct {
+	// PhoneCountry — код страны (без +) для номеров без международного префикса:
+	// "7" превращает 8 (999) 123-45-67 в +79991234567. Пусто — такие номера не трогаются.
+	PhoneCountry string
+}
+
+// Order возвращает нормализованную копию заказа и список изменений (nil — заказ уже канонический).
+func Order(o model.Order, opts Options) (model.Order, []Change) {
+	n := normalizer{}
+	o.TrackNumber = n.str("track_number", o.TrackNumber, upper)
+	o.Payment.Currency = n.str("payment.currency", o.Payment.Currency, upper)
+	o.Delivery.Name = n.str("delivery.name", o.Delivery.Name, collapse)
+	o.Delivery.City = n.str("delivery.city", o.Delivery.City, collapse)
+	o.Delivery.Address = n.str("delivery.address", o.Delivery.Address, collapse)
+	o.Delivery.Region = n.str("delivery.region", o.Delivery.Region, collapse)
+	o.Delivery.Email = n.str("delivery.email", o.Delivery.Email, lowerEmail)
+	o.Delivery.Phone = n.str("delivery.phone", o.Delivery.Phone, func(s string) string { return Phone(s, opts.PhoneCountry) })
+	if o.DateCreated.Location() != time.UTC {
+		utc := o.DateCreated.UTC()
+		n.add("date_created", o.DateCreated.Format(time.RFC3339Nano), utc.Format(time.RFC3339Nano))
+		o.DateCreated = utc
+	}
+
+	o.Items = slices.Clone(o.Items) // не трогаем слайс вызывающего
+	for i := range o.Items {
+		it := &o.Items[i]
+		it.TrackNumber = n.str(fmt.Sprintf("items[%d].track_number", i), it.TrackNumber, upper)
+		it.Name = n.str(fmt.Sprintf("items[%d].name", i), it.Name, collapse)
+		it.Brand = n.str(fmt.Sprintf("items[%d].brand", i), it.Brand, collapse)
+	}
+	return o, n.changes
+}
+
+type normalizer struct{ changes []Change }
+
+func (n *normalizer) str(field, v string, fn func(string) string) string {
+	out := fn(v)
+	if out != v {
+		n.add(field, v, out)
+	}
+	return out
+}
+
+func (n *normalizer) add(field, from, to string) {
+	n.changes = append(n.changes, Change{Field: field, Pointer: validate.Pointer(field), From: from, To: to})
+}
+
+func upper(s string) string { return strings.ToUpper(strings.TrimSpace(s)) }
+
+// collapse убирает пробелы по краям и схлопывает повторы внутри.
+func collapse(s string) string { return strings.Join(strings.Fields(s), " ") }
+
+func lowerEmail(s string) string { return strings.ToLower(strings.TrimSpace(s)) }
+
+// Phone приводит номер к E.164 (+ и 8..15 цифр), убирая пробелы, скобки, дефисы и точки.
+// 00 в начале — международный префикс. Номер без префикса получает код country, если он задан,
+// вместо национального префикса 0 (или 8 для кода 7). Номер, который не удаётся уверенно
+// привести, возвращается как есть.
+func Phone(s, country string) string {
+	var b strings.Builder
+	for i, r := range strings.TrimSpace(s) {
+		switch {
+		case r >= '0' && r <= '9':
+			b.WriteRune(r)
+		case r == '+' && i == 0:
+			b.WriteRune(r)
+		case r == ' ' || r == '-' || r == '(' || r == ')' || r == '.':
+		default:
+			return s // добавочные, буквы и прочее — не наш формат
+		}
+	}
+	d := b.String()
+	switch {
+	case strings.HasPrefix(d, "+"):
+		d = d[1:]
+	case strings.HasPrefix(d, "00"):
+		d = d[2:]
+	case country == "":
+		return s
+	case country == "7" && len(d) == 11 && d[0] == '8':
+		d = country + d[1:]
+	case strings.HasPrefix(d, "0"):
+		d = country + d[1:]
+	case country == "1" && len(d) == 10:
+		d = country + d
+	default:
+		return s
+	}
+	if len(d) < 8 || len(d) > 15 || d[0] == '0' {
+		return s
+	}
+	return "+" + d
+}
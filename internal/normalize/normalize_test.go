@@ -0,0 +1,74 @@
+// internal/normalize/normalize_test.go
+package normalize_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"demo/orders/internal/model"
+	"demo/orders/internal/normalize"
+)
+
+func TestPhone(t *testing.T) {
+	for _, tc := range []struct{ in, country, want string }{
+		{"+972 (55) 555-55-55", "", "+972555555555"},
+		{"00 44 20 7946 0958", "", "+442079460958"},
+		{"8 (999) 123-45-67", "7", "+79991234567"},
+		{"8 (999) 123-45-67", "", "8 (999) 123-45-67"}, // без кода страны не угадываем
+		{"030 1234567", "49", "+49301234567"},
+		{"(555) 123-4567", "1", "+15551234567"},
+		{"+7 999 123", "", "+7 999 123"}, // короче 8 цифр
+		{"+1 555 123 4567 ext. 12", "", "+1 555 123 4567 ext. 12"},
+		{"12+34", "", "12+34"},
+		{"", "7", ""},
+	} {
+		require.Equal(t, tc.want, normalize.Phone(tc.in, tc.country), tc.in)
+	}
+}
+
+func TestOrder(t *testing.T) {
+	msk := time.FixedZone("MSK", 3*3600)
+	in := model.Order{
+		OrderUID:    "b563feb7b2b84b6test",
+		TrackNumber: " wbilmtesttrack",
+		DateCreated: time.Date(2021, 11, 26, 9, 22, 19, 0, msk),
+		Delivery: model.Delivery{
+			Name:  "  Test   Testov ",
+			Phone: "+972 (55) 555-55-55",
+			Email: " Test@Gmail.COM",
+			City:  "Kiryat Mozkin",
+		},
+		Payment: model.Payment{Currency: "usd"},
+		Items:   []model.Item{{TrackNumber: "wbilmtesttrack", Name: "Mascaras", Brand: "Vivienne  Sabo"}},
+	}
+	out, changes := normalize.Order(in, normalize.Options{})
+
+	require.Equal(t, "WBILMTESTTRACK", out.TrackNumber)
+	require.Equal(t, "USD", out.Payment.Currency)
+	require.Equal(t, "Test Testov", out.Delivery.Name)
+	require.Equal(t, "+972555555555", out.Delivery.Phone)
+	require.Equal(t, "test@gmail.com", out.Delivery.Email)
+	require.Equal(t, time.UTC, out.DateCreated.Location())
+	require.True(t, in.DateCreated.Equal(out.DateCreated))
+	require.Equal(t, "WBILMTESTTRACK", out.Items[0].TrackNumber)
+	require.Equal(t, "Vivienne Sabo", out.Items[0].Brand)
+	require.Equal(t, "wbilmtesttrack", in.Items[0].TrackNumber, "исходный заказ не меняется")
+
+	got := map[string]normalize.Change{}
+	for _, c := range changes {
+		got[c.Pointer] = c
+	}
+	require.Len(t, changes, 8)
+	require.Equal(t, normalize.Change{Field: "delivery.email", Pointer: "/delivery/email", From: " Test@Gmail.COM", To: "test@gmail.com"}, got["/delivery/email"])
+	require.Equal(t, "2021-11-26T09:22:19+03:00", got["/date_created"].From)
+	require.Equal(t, "2021-11-26T06:22:19Z", got["/date_created"].To)
+	require.Contains(t, got, "/items/0/track_number")
+	require.NotContains(t, got, "/delivery/city")
+
+	// повторная нормализация ничего не меняет
+	again, changes := normalize.Order(out, normalize.Options{})
+	require.Nil(t, changes)
+	require.Equal(t, out, again)
+}
@@ -0,0 +1,210 @@
+// Package orderschema строит JSON Schema (draft 2020-12) заказа: типы и набор полей — из Go-типов
+// model.Order (по JSON-тегам), ограничения — из правил validate. По умолчанию неизвестные поля
+// запрещены на всех уровнях, как в строгом режиме консьюмера; Options описывают мягкий режим.
+package orderschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"demo/orders/internal/model"
+	"demo/orders/internal/validate"
+)
+
+// Draft — версия JSON Schema.
+const Draft = "https://json-schema.org/draft/2020-12/schema"
+
+type node = map[string]any
+
+// Options — чем режим, в котором сервис принимает заказы, мягче строгой схемы.
+type Options struct {
+	// AllowUnknown — неизвестные поля отбрасываются, а не отклоняются (KAFKA_STRICT_DECODE=0):
+	// additionalProperties: false не ставится.
+	AllowUnknown bool
+	// Warn — поля validate.Options.Warn (VALIDATE_WARN): их нарушения — предупреждения, поэтому
+	// их правила в схему не попадают; сам список — в аннотации x-warn.
+	Warn []string
+}
+
+// Order — строгая схема заказа по встроенным правилам (validate.BuiltinRules).
+func Order() ([]byte, error) { return Generate(validate.BuiltinRules(), Options{}) }
+
+// Generate строит схему заказа с ограничениями из rules. Правила с severity warn и правила полей
+// из opts.Warn не попадают в схему — такие нарушения заказ не отклоняют. expr переносится как
+// аннотация x-expr: проверяет его только сервис.
+func Generate(rules []validate.Rule, opts Options) ([]byte, error) {
+	root := fromType(reflect.TypeOf(model.Order{}), !opts.AllowUnknown)
+	root["$schema"] = Draft
+	root["title"] = "Order"
+	desc := "Заказ в Kafka и HTTP API. Суммы — целые, в минимальных единицах payment.currency."
+	if opts.AllowUnknown {
+		desc += " Неизвестные поля принимаются и отбрасываются."
+	}
+	root["description"] = desc
+	if len(opts.Warn) > 0 {
+		root["x-warn"] = opts.Warn
+	}
+	for _, r := range rules {
+		if r.Severity == validate.SeverityWarn || slices.Contains(opts.Warn, r.Field) {
+			continue
+		}
+		if err := apply(root, r); err != nil {
+			return nil, fmt.Errorf("rule %s: %w", r.Field, err)
+		}
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false) // x-expr читается людьми: <= вместо \u003c=
+	if err := enc.Encode(root); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// fromType — схема Go-типа; strict — запретить неизвестные поля объектов.
+func fromType(t reflect.Type, strict bool) node {
+	switch {
+	case t == timeType:
+		return node{"type": "string", "format": "date-time"}
+	case t.Kind() == reflect.Struct:
+		props := node{}
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if name == "-" || name == "" || !f.IsExported() {
+				continue
+			}
+			props[name] = fromType(f.Type, strict)
+		}
+		n := node{"type": "object", "properties": props}
+		if strict {
+			n["additionalProperties"] = false
+		}
+		return n
+	case t.Kind() == reflect.Slice:
+		return node{"type": "array", "items": fromType(t.Elem(), strict)}
+	case t.Kind() == reflect.String:
+		return node{"type": "string"}
+	case t.Kind() == reflect.Bool:
+		return node{"type": "boolean"}
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Uint64:
+		return node{"type": "integer"}
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		return node{"type": "number"}
+	}
+	return node{}
+}
+
+// apply переносит проверки правила в схему поля r.Field.
+func apply(root node, r validate.Rule) error {
+	parent, name, n, err := lookup(root, r.Field)
+	if err != nil {
+		return err
+	}
+	typ, _ := n["type"].(string)
+	if r.Required && parent != nil {
+		req, _ := parent["required"].([]string)
+		if !slices.Contains(req, name) {
+			req = append(req, name)
+			slices.Sort(req)
+			parent["required"] = req
+		}
+		if typ == "string" {
+			constrain(n, "minLength", 1)
+		}
+	}
+	switch r.Format {
+	case "email":
+		constrain(n, "format", "email")
+	case "currency":
+		constrain(n, "pattern", "^[A-Za-z]{3}$")
+		n["description"] = "Код валюты ISO 4217"
+	}
+	if r.Regex != "" {
+		constrain(n, "pattern", ecmaPattern(r.Regex))
+	}
+	if len(r.Enum) > 0 {
+		enum := make([]any, len(r.Enum))
+		for i, v := range r.Enum {
+			enum[i] = v
+			if typ == "integer" || typ == "number" {
+				f, err := strconv.ParseFloat(v, 64)
+				if err != nil {
+					return fmt.Errorf("enum %q: not a number", v)
+				}
+				enum[i] = f
+			}
+		}
+		constrain(n, "enum", enum)
+	}
+	if r.Min != nil {
+		constrain(n, "minimum", *r.Min)
+	}
+	if r.Max != nil {
+		constrain(n, "maximum", *r.Max)
+	}
+	if r.MinItems > 0 {
+		constrain(n, "minItems", r.MinItems)
+	}
+	if r.Expr != "" {
+		exprs, _ := n["x-expr"].([]string)
+		n["x-expr"] = append(exprs, r.Expr)
+	}
+	return nil
+}
+
+// lookup находит схему поля по JSON-пути (items[*].price) и схему объекта, в котором оно лежит.
+func lookup(root node, path string) (parent node, name string, n node, err error) {
+	n = root
+	for _, seg := range strings.Split(path, ".") {
+		seg, all := strings.CutSuffix(seg, "[*]")
+		props, ok := n["properties"].(node)
+		if !ok {
+			return nil, "", nil, fmt.Errorf("%s: not an object", seg)
+		}
+		child, ok := props[seg].(node)
+		if !ok {
+			return nil, "", nil, fmt.Errorf("unknown field %q", seg)
+		}
+		parent, name, n = n, seg, child
+		if all {
+			if n, ok = n["items"].(node); !ok {
+				return nil, "", nil, fmt.Errorf("%s: [*] on a non-array", seg)
+			}
+			parent = nil // required у элемента массива не выражается
+		}
+	}
+	return parent, name, n, nil
+}
+
+// constrain задаёт ключевое слово; если у поля оно уже есть с другим значением — добавляет
+// ещё одно через allOf, чтобы выполнялись оба.
+func constrain(n node, key string, v any) {
+	old, ok := n[key]
+	switch {
+	case !ok:
+		n[key] = v
+	case !reflect.DeepEqual(old, v):
+		all, _ := n["allOf"].([]node)
+		n["allOf"] = append(all, node{key: v})
+	}
+}
+
+// ecmaPattern переводит regex Go в диалект JSON Schema (ECMA-262). Флаг (?i) там не поддерживается:
+// для шаблонов из классов вида [A-Z0-9] он заменяется на явные диапазоны обоих регистров.
+func ecmaPattern(re string) string {
+	rest, ok := strings.CutPrefix(re, "(?i)")
+	if !ok {
+		return re
+	}
+	return strings.NewReplacer("A-Z", "A-Za-z", "a-z", "a-zA-Z").Replace(rest)
+}
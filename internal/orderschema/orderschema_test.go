@@ -0,0 +1,145 @@
+// internal/orderschema/orderschema_test.go
+package orderschema_test
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"sort"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/stretchr/testify/require"
+
+	"demo/orders/internal/apispec"
+	"demo/orders/internal/orderschema"
+	"demo/orders/internal/validate"
+)
+
+var update = flag.Bool("update", false, "rewrite api/order.schema.json")
+
+const published = "../../api/order.schema.json"
+
+// Схема, которую видят продюсеры, должна совпадать с тем, что строится из model.Order и правил:
+// после изменения модели или builtin.yaml — go test ./internal/orderschema -update.
+func TestOrder_InSync(t *testing.T) {
+	got, err := orderschema.Order()
+	require.NoError(t, err)
+	if *update {
+		require.NoError(t, os.WriteFile(published, got, 0o644))
+	}
+	want, err := os.ReadFile(published)
+	require.NoError(t, err)
+	require.Equal(t, string(want), string(got), "api/order.schema.json устарел: go test ./internal/orderschema -update")
+}
+
+// Набор полей схемы совпадает с Order в OpenAPI — вплоть до вложенных объектов.
+func TestOrder_MatchesOpenAPI(t *testing.T) {
+	b, err := orderschema.Order()
+	require.NoError(t, err)
+	var gen openapi3.Schema
+	require.NoError(t, json.Unmarshal(b, &gen))
+	doc, err := apispec.Load()
+	require.NoError(t, err)
+	require.Equal(t, fields("", &gen), fields("", doc.Components.Schemas["Order"].Value))
+}
+
+func fields(prefix string, s *openapi3.Schema) []string {
+	var out []string
+	if s.Items != nil {
+		return fields(prefix+"[*]", s.Items.Value)
+	}
+	for name, p := range s.Properties {
+		out = append(out, prefix+"."+name)
+		out = append(out, fields(prefix+"."+name, p.Value)...)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func order(t *testing.T, mutate func(m map[string]any)) any {
+	t.Helper()
+	var m map[string]any
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"order_uid": "b563feb7b2b84b6test", "track_number": "wbilmtesttrack", "entry": "WBIL", "customer_id": "test",
+		"date_created": "2021-11-26T06:22:19Z",
+		"delivery": {"name": "Test Testov", "phone": "+9720000000", "email": "test@gmail.com"},
+		"payment": {"transaction": "b563feb7b2b84b6test", "currency": "usd", "amount": 1817, "payment_dt": 1637907727},
+		"items": [{"chrt_id": 9934930, "track_number": "WBILMTESTTRACK", "price": 453, "name": "Mascaras",
+		           "sale": 30, "size": "0", "total_price": 317, "nm_id": 2389212, "status": 202}]
+	}`), &m))
+	if mutate != nil {
+		mutate(m)
+	}
+	return m
+}
+
+func TestOrder_Validates(t *testing.T) {
+	b, err := orderschema.Order()
+	require.NoError(t, err)
+	var s openapi3.Schema
+	require.NoError(t, json.Unmarshal(b, &s))
+
+	require.NoError(t, s.VisitJSON(order(t, nil)))
+	for name, mutate := range map[string]func(map[string]any){
+		"unknown field":  func(m map[string]any) { m["promo"] = "x" },
+		"unknown nested": func(m map[string]any) { m["payment"].(map[string]any)["tip"] = 1 },
+		"wrong type":     func(m map[string]any) { m["payment"].(map[string]any)["amount"] = "18.17" },
+		"negative price": func(m map[string]any) { m["items"].([]any)[0].(map[string]any)["price"] = -1 },
+		"no items":       func(m map[string]any) { m["items"] = []any{} },
+		"item no name":   func(m map[string]any) { delete(m["items"].([]any)[0].(map[string]any), "name") },
+		"no entry":       func(m map[string]any) { delete(m, "entry") },
+		"bad currency":   func(m map[string]any) { m["payment"].(map[string]any)["currency"] = "dollars" },
+	} {
+		require.Error(t, s.VisitJSON(order(t, mutate)), name)
+	}
+}
+
+func TestGenerate_Rules(t *testing.T) {
+	five := 5.0
+	b, err := orderschema.Generate([]validate.Rule{
+		{Field: "sm_id", Enum: []string{"1", "2"}},
+		{Field: "entry", Regex: "^WB"},
+		{Field: "entry", Regex: "^[A-Z]+$"},
+		{Field: "items[*].sale", Max: &five, Severity: validate.SeverityWarn},
+		{Field: "payment.amount", Expr: "value > 0"},
+	}, orderschema.Options{})
+	require.NoError(t, err)
+	var s struct {
+		Properties map[string]struct {
+			Enum       []any
+			Pattern    string
+			AllOf      []map[string]any
+			Properties map[string]map[string]any
+			Items      struct{ Properties map[string]map[string]any }
+		}
+	}
+	require.NoError(t, json.Unmarshal(b, &s))
+	require.Equal(t, []any{1.0, 2.0}, s.Properties["sm_id"].Enum)
+	require.Equal(t, "^WB", s.Properties["entry"].Pattern)
+	require.Equal(t, []map[string]any{{"pattern": "^[A-Z]+$"}}, s.Properties["entry"].AllOf)
+	require.NotContains(t, s.Properties["items"].Items.Properties["sale"], "maximum", "warn не отклоняет — в схему не идёт")
+	require.Equal(t, []any{"value > 0"}, s.Properties["payment"].Properties["amount"]["x-expr"])
+
+	// мягкий режим: неизвестные поля разрешены, правила полей из VALIDATE_WARN не попадают
+	b, err = orderschema.Generate([]validate.Rule{
+		{Field: "entry", Regex: "^WB"},
+		{Field: "delivery.phone", Regex: "^\\+[0-9]+$"},
+	}, orderschema.Options{AllowUnknown: true, Warn: []string{"delivery.phone"}})
+	require.NoError(t, err)
+	var soft map[string]any
+	require.NoError(t, json.Unmarshal(b, &soft))
+	require.NotContains(t, soft, "additionalProperties")
+	require.Contains(t, soft["description"], "Неизвестные поля принимаются и отбрасываются")
+	require.Equal(t, []any{"delivery.phone"}, soft["x-warn"])
+	props := soft["properties"].(map[string]any)
+	require.Equal(t, "^WB", props["entry"].(map[string]any)["pattern"])
+	delivery := props["delivery"].(map[string]any)
+	require.NotContains(t, delivery, "additionalProperties")
+	require.NotContains(t, delivery["properties"].(map[string]any)["phone"], "pattern")
+
+	for _, r := range []validate.Rule{{Field: "nope"}, {Field: "items.name"}, {Field: "sm_id", Enum: []string{"x"}}} {
+		_, err := orderschema.Generate([]validate.Rule{r}, orderschema.Options{})
+		require.Error(t, err, r.Field)
+	}
+}
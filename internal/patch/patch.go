@@ -0,0 +1,81 @@
+// Package patch применяет JSON Merge Patch (RFC 7396) и JSON Patch (RFC 6902) к model.Order.
+package patch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+
+	"demo/orders/internal/model"
+)
+
+const (
+	MergePatchType = "application/merge-patch+json"
+	JSONPatchType  = "application/json-patch+json"
+)
+
+var (
+	// ErrUnsupportedMediaType — Content-Type не похож ни на один из поддерживаемых форматов.
+	ErrUnsupportedMediaType = errors.New("unsupported patch media type")
+	// ErrTestFailed — операция "test" из JSON Patch не прошла.
+	ErrTestFailed = errors.New("patch test operation failed")
+	// ErrInvalid — патч не разобрать или после применения получился некорректный заказ.
+	ErrInvalid = errors.New("invalid patch")
+)
+
+// Apply применяет body к заказу o согласно contentType.
+// Обычный application/json трактуется как merge patch.
+func Apply(o model.Order, contentType string, body []byte) (model.Order, error) {
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return model.Order{}, ErrUnsupportedMediaType
+	}
+
+	doc, err := json.Marshal(o)
+	if err != nil {
+		return model.Order{}, fmt.Errorf("marshal order: %w", err)
+	}
+
+	var out []byte
+	switch mt {
+	case MergePatchType, "application/json":
+		out, err = jsonpatch.MergePatch(doc, body)
+		if err != nil {
+			return model.Order{}, fmt.Errorf("%w: %v", ErrInvalid, err)
+		}
+	case JSONPatchType:
+		p, derr := jsonpatch.DecodePatch(body)
+		if derr != nil {
+			return model.Order{}, fmt.Errorf("%w: %v", ErrInvalid, derr)
+		}
+		out, err = p.Apply(doc)
+		if err != nil {
+			if errors.Is(err, jsonpatch.ErrTestFailed) {
+				return model.Order{}, ErrTestFailed
+			}
+			return model.Order{}, fmt.Errorf("%w: %v", ErrInvalid, err)
+		}
+	default:
+		return model.Order{}, ErrUnsupportedMediaType
+	}
+
+	return Decode(out)
+}
+
+// Decode строго разбирает JSON заказа: неизвестные поля и лишние данные — ошибка.
+func Decode(b []byte) (model.Order, error) {
+	var res model.Order
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&res); err != nil {
+		return model.Order{}, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	if dec.More() {
+		return model.Order{}, fmt.Errorf("%w: trailing data after order", ErrInvalid)
+	}
+	return res, nil
+}
@@ -0,0 +1,54 @@
+// internal/patch/patch_test.go
+package patch_test
+
+import (
+	"testing"
+
+	"demo/orders/internal/model"
+	"demo/orders/internal/patch"
+
+	"github.com/stretchr/testify/require"
+)
+
+func baseOrder() model.Order {
+	return model.Order{
+		OrderUID:    "order01",
+		TrackNumber: "ABC123",
+		Delivery:    model.Delivery{City: "Moscow", Phone: "+79990000000"},
+		Items:       []model.Item{{ChrtID: 1, Name: "Product"}},
+	}
+}
+
+func TestApply_MergePatch(t *testing.T) {
+	got, err := patch.Apply(baseOrder(), patch.MergePatchType, []byte(`{"delivery":{"city":"Kazan"}}`))
+	require.NoError(t, err)
+	require.Equal(t, "Kazan", got.Delivery.City)
+	require.Equal(t, "+79990000000", got.Delivery.Phone)
+	require.Equal(t, "ABC123", got.TrackNumber)
+}
+
+func TestApply_JSONPatch(t *testing.T) {
+	ops := `[
+		{"op":"test","path":"/track_number","value":"ABC123"},
+		{"op":"replace","path":"/items/0/name","value":"Other"}
+	]`
+	got, err := patch.Apply(baseOrder(), patch.JSONPatchType, []byte(ops))
+	require.NoError(t, err)
+	require.Equal(t, "Other", got.Items[0].Name)
+}
+
+func TestApply_JSONPatchTestFailed(t *testing.T) {
+	ops := `[{"op":"test","path":"/track_number","value":"NOPE"}]`
+	_, err := patch.Apply(baseOrder(), patch.JSONPatchType, []byte(ops))
+	require.ErrorIs(t, err, patch.ErrTestFailed)
+}
+
+func TestApply_UnknownFieldRejected(t *testing.T) {
+	_, err := patch.Apply(baseOrder(), patch.MergePatchType, []byte(`{"bogus":1}`))
+	require.ErrorIs(t, err, patch.ErrInvalid)
+}
+
+func TestApply_UnsupportedMediaType(t *testing.T) {
+	_, err := patch.Apply(baseOrder(), "text/plain", []byte(`{}`))
+	require.ErrorIs(t, err, patch.ErrUnsupportedMediaType)
+}
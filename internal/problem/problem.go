@@ -0,0 +1,87 @@
+// Package problem — ошибки HTTP API в формате application/problem+json (RFC 7807).
+package problem
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"demo/orders/internal/requestid"
+)
+
+// ContentType — media type ответа с ошибкой.
+const ContentType = "application/problem+json"
+
+// Типы проблем. Если ошибку полностью описывает HTTP-статус, type — about:blank,
+// а title — стандартный текст статуса.
+const (
+	TypeBlank           = "about:blank"
+	TypeInvalidRequest  = "urn:orders:problem:invalid-request"   // запрос не соответствует API (400)
+	TypeValidation      = "urn:orders:problem:validation"        // заказ не прошёл валидацию (422)
+	TypeETagMismatch    = "urn:orders:problem:etag-mismatch"     // If-Match не совпал (412)
+	TypePatchTestFailed = "urn:orders:problem:patch-test-failed" // не прошла операция test JSON Patch (409)
+)
+
+var titles = map[string]string{
+	TypeInvalidRequest:  "Request does not match the API",
+	TypeValidation:      "Order validation failed",
+	TypeETagMismatch:    "ETag mismatch",
+	TypePatchTestFailed: "Patch test operation failed",
+}
+
+// FieldError — нарушение в одном поле запроса или заказа.
+type FieldError struct {
+	Field   string `json:"field"`
+	Pointer string `json:"pointer,omitempty"` // JSON Pointer на поле тела: /payment/currency
+	Code    string `json:"code,omitempty"`    // машинный код нарушения: required, format, range...
+	Message string `json:"message"`
+}
+
+// Problem — тело ответа с ошибкой.
+type Problem struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail,omitempty"`
+	Instance  string       `json:"instance,omitempty"`
+	RequestID string       `json:"request_id,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+}
+
+// Write отправляет p; пустые type, title, instance и request_id заполняются сами.
+func Write(w http.ResponseWriter, r *http.Request, p Problem) {
+	if p.Type == "" {
+		p.Type = TypeBlank
+	}
+	if p.Title == "" {
+		p.Title = titles[p.Type]
+		if p.Title == "" {
+			p.Title = http.StatusText(p.Status)
+		}
+	}
+	if p.Instance == "" {
+		p.Instance = r.URL.Path
+	}
+	if p.RequestID == "" {
+		p.RequestID = requestid.FromContext(r.Context())
+	}
+
+	h := w.Header()
+	h.Del("Content-Length")
+	h.Set("Content-Type", ContentType)
+	h.Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(p.Status)
+	if err := json.NewEncoder(w).Encode(p); err != nil {
+		slog.ErrorContext(r.Context(), "problem encode", "err", err)
+	}
+}
+
+// Error — замена http.Error: проблема about:blank со статусом и пояснением.
+func Error(w http.ResponseWriter, r *http.Request, status int, detail string) {
+	Write(w, r, Problem{Status: status, Detail: detail})
+}
+
+// InvalidRequest — 400: запрос не разобрать или он не соответствует API.
+func InvalidRequest(w http.ResponseWriter, r *http.Request, detail string, fields ...FieldError) {
+	Write(w, r, Problem{Type: TypeInvalidRequest, Status: http.StatusBadRequest, Detail: detail, Errors: fields})
+}
@@ -0,0 +1,50 @@
+// internal/problem/problem_test.go
+package problem_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"demo/orders/internal/problem"
+	"demo/orders/internal/requestid"
+)
+
+func decode(t *testing.T, rec *httptest.ResponseRecorder) problem.Problem {
+	t.Helper()
+	require.Equal(t, problem.ContentType, rec.Header().Get("Content-Type"))
+	var p problem.Problem
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &p))
+	return p
+}
+
+func TestError_Defaults(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/order/x", nil)
+	req = req.WithContext(requestid.NewContext(req.Context(), "req-1"))
+	rec := httptest.NewRecorder()
+	problem.Error(rec, req, http.StatusNotFound, "order not found")
+
+	require.Equal(t, http.StatusNotFound, rec.Code)
+	require.Equal(t, problem.Problem{
+		Type: problem.TypeBlank, Title: "Not Found", Status: http.StatusNotFound,
+		Detail: "order not found", Instance: "/order/x", RequestID: "req-1",
+	}, decode(t, rec))
+}
+
+func TestWrite_TypedWithFields(t *testing.T) {
+	rec := httptest.NewRecorder()
+	problem.Write(rec, httptest.NewRequest(http.MethodPut, "/order/x", nil), problem.Problem{
+		Type:   problem.TypeValidation,
+		Status: http.StatusUnprocessableEntity,
+		Errors: []problem.FieldError{{Field: "payment.currency", Message: "must be 3-letter ISO code"}},
+	})
+
+	p := decode(t, rec)
+	require.Equal(t, "Order validation failed", p.Title)
+	require.Equal(t, http.StatusUnprocessableEntity, p.Status)
+	require.Len(t, p.Errors, 1)
+	require.Empty(t, p.RequestID)
+}
@@ -0,0 +1,360 @@
+// Package ratelimit — token bucket на клиента и маршрут. Клиент — аутентифицированный субъект
+// (API-ключ или JWT), для анонимных запросов — IP. Отдельно по IP считаются неудачные
+// аутентификации (перебор ключей). Лимиты читаются из JSON-файла и перечитываются без рестарта.
+package ratelimit
+
+import (
+	"bufio"
+	"bytes"
+	"container/list"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"net/netip"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"demo/orders/internal/auth"
+	"demo/orders/internal/problem"
+)
+
+// Limit — скорость пополнения (запросов в секунду) и ёмкость ведра. RPS <= 0 — без ограничений.
+type Limit struct {
+	RPS   float64 `json:"rps"`
+	Burst int     `json:"burst"`
+}
+
+// Config — содержимое файла лимитов.
+type Config struct {
+	Default Limit `json:"default"`
+	// Routes — лимиты по шаблону маршрута ServeMux ("GET /order/", "GET /orders/export").
+	Routes map[string]Limit `json:"routes"`
+	// TrustedProxies — сети прокси, которым можно верить в X-Forwarded-For.
+	TrustedProxies []string `json:"trusted_proxies"`
+	// AuthFailures — ответы 401 с одного IP; когда ведро пусто, запросы с него отклоняются
+	// до проверки учётных данных. Не задан — DefaultAuthFailures.
+	AuthFailures *Limit `json:"auth_failures"`
+}
+
+// DefaultAuthFailures — десять неудачных попыток подряд, дальше одна в секунду.
+var DefaultAuthFailures = Limit{RPS: 1, Burst: 10}
+
+type state struct {
+	cfg     Config
+	proxies []netip.Prefix
+}
+
+// Limiter — набор вёдер и текущая конфигурация.
+type Limiter struct {
+	path  string
+	route func(*http.Request) string
+	now   func() time.Time
+
+	mu      sync.Mutex
+	st      state
+	raw     []byte
+	modTime time.Time
+	buckets map[bucketKey]*list.Element
+	lru     *list.List // *bucket; спереди — недавно использованные
+	max     int
+}
+
+type bucketKey struct{ route, client string }
+
+type bucket struct {
+	key      bucketKey
+	lim      *rate.Limiter
+	lastSeen time.Time
+}
+
+const (
+	// idleTTL — через сколько простоя ведро забывается (к этому времени оно давно полное).
+	idleTTL = 10 * time.Minute
+	// maxBuckets — сколько вёдер держать; сверх этого забываются давно не использованные.
+	maxBuckets = 100_000
+	// authFailRoute — «маршрут» вёдер неудачных аутентификаций.
+	authFailRoute = "\x00auth_failures"
+)
+
+// Load читает конфигурацию из path. route возвращает ключ маршрута запроса
+// (обычно шаблон ServeMux); маршруты без своего лимита получают Default.
+func Load(path string, route func(*http.Request) string) (*Limiter, error) {
+	l := &Limiter{path: path, route: route, now: time.Now, buckets: make(map[bucketKey]*list.Element), lru: list.New(), max: maxBuckets}
+	if _, err := l.Reload(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func parse(b []byte) (state, error) {
+	var cfg Config
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&cfg); err != nil {
+		return state{}, err
+	}
+	check := func(name string, l Limit) error {
+		if l.RPS > 0 && l.Burst < 1 {
+			return fmt.Errorf("%s: burst must be >= 1", name)
+		}
+		return nil
+	}
+	if err := check("default", cfg.Default); err != nil {
+		return state{}, err
+	}
+	if cfg.AuthFailures == nil {
+		d := DefaultAuthFailures
+		cfg.AuthFailures = &d
+	} else if err := check("auth_failures", *cfg.AuthFailures); err != nil {
+		return state{}, err
+	}
+	for r, l := range cfg.Routes {
+		if err := check(fmt.Sprintf("routes[%q]", r), l); err != nil {
+			return state{}, err
+		}
+	}
+	st := state{cfg: cfg}
+	for _, s := range cfg.TrustedProxies {
+		p, err := netip.ParsePrefix(s)
+		if err != nil {
+			a, aerr := netip.ParseAddr(s)
+			if aerr != nil {
+				return state{}, fmt.Errorf("trusted_proxies: %w", err)
+			}
+			p = netip.PrefixFrom(a, a.BitLen())
+		}
+		st.proxies = append(st.proxies, p.Masked())
+	}
+	return st, nil
+}
+
+// Reload перечитывает файл. Если содержимое не изменилось — ничего не делает (changed=false);
+// если изменилось — вёдра сбрасываются. Битый файл не применяется, остаются прежние лимиты.
+func (l *Limiter) Reload() (changed bool, err error) {
+	fi, err := os.Stat(l.path)
+	if err != nil {
+		return false, fmt.Errorf("rate limits: %w", err)
+	}
+	b, err := os.ReadFile(l.path)
+	if err != nil {
+		return false, fmt.Errorf("rate limits: %w", err)
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.modTime = fi.ModTime()
+	if l.raw != nil && bytes.Equal(b, l.raw) {
+		return false, nil
+	}
+	st, err := parse(b)
+	if err != nil {
+		return false, fmt.Errorf("rate limits %s: %w", l.path, err)
+	}
+	l.st, l.raw = st, b
+	l.buckets = make(map[bucketKey]*list.Element)
+	l.lru.Init()
+	return true, nil
+}
+
+// Stale — изменился ли файл с последнего Reload (по mtime); для периодической проверки.
+func (l *Limiter) Stale() bool {
+	fi, err := os.Stat(l.path)
+	if err != nil {
+		return false
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return !fi.ModTime().Equal(l.modTime)
+}
+
+// Allow списывает токен; если его нет — возвращает, через сколько он появится.
+func (l *Limiter) Allow(r *http.Request) (ok bool, retryAfter time.Duration) {
+	route := l.route(r)
+	now := l.now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	lim, ok := l.st.cfg.Routes[route]
+	if !ok {
+		lim = l.st.cfg.Default
+	}
+	if lim.RPS <= 0 {
+		return true, 0
+	}
+	b := l.bucket(bucketKey{route: route, client: l.clientKey(r)}, lim, now)
+	res := b.lim.ReserveN(now, 1)
+	if d := res.DelayFrom(now); d > 0 {
+		res.CancelAt(now)
+		return false, d
+	}
+	return true, 0
+}
+
+// bucket — ведро по ключу (новое — полное). Заодно забывает вёдра с конца LRU: простаивающие
+// дольше idleTTL и всё, что сверх l.max. Вызывается под l.mu.
+func (l *Limiter) bucket(key bucketKey, lim Limit, now time.Time) *bucket {
+	if e, ok := l.buckets[key]; ok {
+		l.lru.MoveToFront(e)
+		b := e.Value.(*bucket)
+		b.lastSeen = now
+		return b
+	}
+	b := &bucket{key: key, lim: rate.NewLimiter(rate.Limit(lim.RPS), lim.Burst), lastSeen: now}
+	l.buckets[key] = l.lru.PushFront(b)
+	for e := l.lru.Back(); e != nil && e.Value != b; e = l.lru.Back() {
+		old := e.Value.(*bucket)
+		if l.lru.Len() <= l.max && now.Sub(old.lastSeen) <= idleTTL {
+			break
+		}
+		l.lru.Remove(e)
+		delete(l.buckets, old.key)
+	}
+	return b
+}
+
+// authBlocked — пусто ли ведро неудачных аутентификаций IP; если да — через сколько появится токен.
+func (l *Limiter) authBlocked(ip string) (time.Duration, bool) {
+	now := l.now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	lim := *l.st.cfg.AuthFailures
+	if lim.RPS <= 0 {
+		return 0, false
+	}
+	b := l.bucket(bucketKey{route: authFailRoute, client: "ip:" + ip}, lim, now)
+	if tokens := b.lim.TokensAt(now); tokens < 1 {
+		return time.Duration((1 - tokens) / lim.RPS * float64(time.Second)), true
+	}
+	return 0, false
+}
+
+// authFailed списывает токен из ведра неудачных аутентификаций IP.
+func (l *Limiter) authFailed(ip string) {
+	now := l.now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	lim := *l.st.cfg.AuthFailures
+	if lim.RPS <= 0 {
+		return
+	}
+	l.bucket(bucketKey{route: authFailRoute, client: "ip:" + ip}, lim, now).lim.AllowN(now, 1)
+}
+
+// clientKey — субъект, если запрос аутентифицирован, иначе IP клиента.
+func (l *Limiter) clientKey(r *http.Request) string {
+	if p, ok := auth.FromContext(r.Context()); ok {
+		return "sub:" + p.Method + ":" + p.Subject
+	}
+	return "ip:" + l.clientIP(r)
+}
+
+// clientIP: адрес соединения; если это доверенный прокси — самый правый адрес в
+// X-Forwarded-For, который не принадлежит доверенным прокси (левее клиент может написать что угодно).
+func (l *Limiter) clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil || !l.trusted(addr) {
+		return host
+	}
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		a, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			break
+		}
+		if !l.trusted(a) {
+			return a.Unmap().String()
+		}
+	}
+	return host
+}
+
+func (l *Limiter) trusted(a netip.Addr) bool {
+	a = a.Unmap()
+	for _, p := range l.st.proxies {
+		if p.Contains(a) {
+			return true
+		}
+	}
+	return false
+}
+
+// Middleware отвечает 429 с Retry-After, когда ведро клиента на маршруте пусто.
+func (l *Limiter) Middleware(next http.Handler) http.Handler {
+	if l == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ok, wait := l.Allow(r); !ok {
+			tooMany(w, r, wait)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// AuthFailures ставится перед аутентификацией: считает её отказы (401) по IP клиента и, когда
+// ведро пусто, отвечает 429, не проверяя учётные данные, — перебор ключей и токенов упирается в лимит.
+func (l *Limiter) AuthFailures(next http.Handler) http.Handler {
+	if l == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		l.mu.Lock()
+		ip := l.clientIP(r)
+		l.mu.Unlock()
+		if wait, blocked := l.authBlocked(ip); blocked {
+			tooMany(w, r, wait)
+			return
+		}
+		sw := &statusWriter{ResponseWriter: w}
+		next.ServeHTTP(sw, r)
+		if sw.status == http.StatusUnauthorized {
+			l.authFailed(ip)
+		}
+	})
+}
+
+func tooMany(w http.ResponseWriter, r *http.Request, wait time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	problem.Error(w, r, http.StatusTooManyRequests, "rate limit exceeded, retry later")
+}
+
+// statusWriter запоминает статус ответа; Flush, Hijack и Unwrap пробрасываются (лента, WebSocket).
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *statusWriter) Flush() {
+	_ = http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+func (w *statusWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(w.ResponseWriter).Hijack()
+}
+
+func (w *statusWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
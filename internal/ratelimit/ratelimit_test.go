@@ -0,0 +1,205 @@
+// internal/ratelimit/ratelimit_test.go
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"demo/orders/internal/auth"
+	"demo/orders/internal/problem"
+)
+
+const cfg = `{
+  "default": {"rps": 1, "burst": 2},
+  "routes": {"/healthz": {"rps": 0}, "/export": {"rps": 0.5, "burst": 1}},
+  "trusted_proxies": ["10.0.0.0/8"]
+}`
+
+// newLimiter — лимитер со сдвигаемыми часами; маршрут — путь запроса.
+func newLimiter(t *testing.T, content string) (*Limiter, string, *time.Time) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "limits.json")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	l, err := Load(path, func(r *http.Request) string { return r.URL.Path })
+	require.NoError(t, err)
+	now := time.Unix(1_700_000_000, 0)
+	l.now = func() time.Time { return now }
+	return l, path, &now
+}
+
+func req(path, remote string) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, path, nil)
+	r.RemoteAddr = remote
+	return r
+}
+
+func TestAllow_BucketPerClientAndRoute(t *testing.T) {
+	l, _, now := newLimiter(t, cfg)
+	a := req("/order/x", "192.0.2.1:1000")
+
+	for i := 0; i < 2; i++ {
+		ok, _ := l.Allow(a)
+		require.True(t, ok, i)
+	}
+	ok, wait := l.Allow(a)
+	require.False(t, ok)
+	require.Equal(t, time.Second, wait)
+
+	// другой клиент и другой маршрут — свои вёдра; без лимита — всегда можно
+	ok, _ = l.Allow(req("/order/x", "192.0.2.2:1000"))
+	require.True(t, ok)
+	ok, _ = l.Allow(req("/export", "192.0.2.1:1000"))
+	require.True(t, ok)
+	ok, wait = l.Allow(req("/export", "192.0.2.1:1000"))
+	require.False(t, ok)
+	require.Equal(t, 2*time.Second, wait)
+	for i := 0; i < 10; i++ {
+		ok, _ = l.Allow(req("/healthz", "192.0.2.1:1000"))
+		require.True(t, ok)
+	}
+
+	// отказ токен не тратит: через секунду ровно один запрос
+	*now = now.Add(time.Second)
+	ok, _ = l.Allow(a)
+	require.True(t, ok)
+	ok, _ = l.Allow(a)
+	require.False(t, ok)
+}
+
+func TestClientKey(t *testing.T) {
+	l, _, _ := newLimiter(t, cfg)
+
+	direct := req("/", "192.0.2.1:1000")
+	direct.Header.Set("X-Forwarded-For", "198.51.100.7")
+	require.Equal(t, "ip:192.0.2.1", l.clientKey(direct), "XFF от недоверенного адреса игнорируется")
+
+	proxied := req("/", "10.1.2.3:1000")
+	proxied.Header.Set("X-Forwarded-For", "203.0.113.9, 198.51.100.7, 10.0.0.5")
+	require.Equal(t, "ip:198.51.100.7", l.clientKey(proxied))
+
+	onlyProxies := req("/", "10.1.2.3:1000")
+	onlyProxies.Header.Set("X-Forwarded-For", "10.0.0.5")
+	require.Equal(t, "ip:10.1.2.3", l.clientKey(onlyProxies))
+
+	authed := req("/", "192.0.2.1:1000")
+	authed = authed.WithContext(auth.NewContext(authed.Context(), auth.Principal{Subject: "bot", Method: auth.MethodAPIKey}))
+	require.Equal(t, "sub:api_key:bot", l.clientKey(authed))
+}
+
+func TestReload(t *testing.T) {
+	l, path, _ := newLimiter(t, cfg)
+	a := req("/order/x", "192.0.2.1:1000")
+	l.Allow(a)
+	l.Allow(a)
+	ok, _ := l.Allow(a)
+	require.False(t, ok)
+
+	changed, err := l.Reload()
+	require.NoError(t, err)
+	require.False(t, changed, "тот же файл — вёдра не сбрасываются")
+	ok, _ = l.Allow(a)
+	require.False(t, ok)
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"default": {"rps": 100, "burst": 100}}`), 0o600))
+	changed, err = l.Reload()
+	require.NoError(t, err)
+	require.True(t, changed)
+	ok, _ = l.Allow(a)
+	require.True(t, ok)
+
+	// битый файл не применяется
+	require.NoError(t, os.WriteFile(path, []byte(`{"default": {"rps": 1}}`), 0o600))
+	_, err = l.Reload()
+	require.Error(t, err)
+	ok, _ = l.Allow(a)
+	require.True(t, ok)
+}
+
+func TestLoad_Errors(t *testing.T) {
+	for _, content := range []string{
+		`{"default": {"rps": 1, "burst": 0}}`,
+		`{"routes": {"/x": {"rps": 2}}}`,
+		`{"trusted_proxies": ["not-an-ip"]}`,
+		`{"default": {"rps": 1, "burst": 1}, "typo": true}`,
+	} {
+		path := filepath.Join(t.TempDir(), "limits.json")
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+		_, err := Load(path, func(*http.Request) string { return "" })
+		require.Error(t, err, content)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	l, _, _ := newLimiter(t, `{"default": {"rps": 0.25, "burst": 1}}`)
+	h := l.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req("/order/x", "192.0.2.1:1000"))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req("/order/x", "192.0.2.1:1000"))
+	require.Equal(t, http.StatusTooManyRequests, rec.Code)
+	require.Equal(t, "4", rec.Header().Get("Retry-After"))
+	require.Equal(t, problem.ContentType, rec.Header().Get("Content-Type"))
+
+	var nilLimiter *Limiter
+	require.NotNil(t, nilLimiter.Middleware(h))
+}
+
+func TestAuthFailures(t *testing.T) {
+	l, _, now := newLimiter(t, `{"auth_failures": {"rps": 0.5, "burst": 2}}`)
+	status := http.StatusUnauthorized
+	h := l.AuthFailures(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(status) }))
+	do := func(remote string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req("/order/x", remote))
+		return rec
+	}
+
+	require.Equal(t, http.StatusUnauthorized, do("192.0.2.1:1000").Code)
+	require.Equal(t, http.StatusUnauthorized, do("192.0.2.1:1001").Code)
+	rec := do("192.0.2.1:1002")
+	require.Equal(t, http.StatusTooManyRequests, rec.Code)
+	require.Equal(t, "2", rec.Header().Get("Retry-After"))
+	require.Equal(t, http.StatusUnauthorized, do("192.0.2.2:1000").Code, "у другого IP своё ведро")
+
+	// успешные запросы ведро не тратят
+	*now = now.Add(2 * time.Second)
+	status = http.StatusOK
+	for i := 0; i < 5; i++ {
+		require.Equal(t, http.StatusOK, do("192.0.2.1:1000").Code)
+	}
+
+	l, _, _ = newLimiter(t, `{}`)
+	require.Equal(t, DefaultAuthFailures, *l.st.cfg.AuthFailures)
+	l, _, _ = newLimiter(t, `{"auth_failures": {"rps": 0}}`)
+	status = http.StatusUnauthorized
+	h = l.AuthFailures(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(status) }))
+	for i := 0; i < 20; i++ {
+		require.Equal(t, http.StatusUnauthorized, do("192.0.2.1:1000").Code)
+	}
+}
+
+func TestBuckets_Evicted(t *testing.T) {
+	l, _, now := newLimiter(t, cfg)
+	l.max = 3
+	for _, ip := range []string{"192.0.2.1", "192.0.2.2", "192.0.2.3", "192.0.2.4"} {
+		l.Allow(req("/order/x", ip+":1000"))
+	}
+	// сверх max забыт самый давний
+	require.Equal(t, 3, l.lru.Len())
+	require.NotContains(t, l.buckets, bucketKey{route: "/order/x", client: "ip:192.0.2.1"})
+
+	// простаивающие дольше idleTTL забываются при следующем новом ведре
+	*now = now.Add(idleTTL + time.Second)
+	l.Allow(req("/order/x", "192.0.2.9:1000"))
+	require.Equal(t, 1, l.lru.Len())
+	require.Len(t, l.buckets, 1)
+}
@@ -0,0 +1,141 @@
+// Package redact — какие данные заказа видит вызывающий в зависимости от роли.
+// Все правила маскирования PII собраны здесь; HTTP, выгрузки, ленты и GraphQL применяют их
+// через Order/Apply перед отдачей заказа наружу.
+package redact
+
+import (
+	"context"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"demo/orders/internal/auth"
+	"demo/orders/internal/model"
+)
+
+// Роли, от которых зависит представление заказа.
+const (
+	RoleSupport   = "support"
+	RoleAnalytics = "analytics"
+	RolePublic    = "public"
+)
+
+// View — представление заказа.
+type View int
+
+const (
+	Full      View = iota // всё как есть — support
+	Analytics             // контакты замаскированы, адрес без дома и квартиры
+	Public                // только то, что нужно для отслеживания: номер, трек и товары со статусами
+)
+
+func (v View) String() string {
+	switch v {
+	case Full:
+		return "full"
+	case Analytics:
+		return "analytics"
+	}
+	return "public"
+}
+
+// ForPrincipal выбирает самое полное представление из доступных ролям субъекта.
+// Субъект без известных ролей видит Public.
+func ForPrincipal(p auth.Principal) View {
+	switch {
+	case p.HasRole(RoleSupport):
+		return Full
+	case p.HasRole(RoleAnalytics):
+		return Analytics
+	}
+	return Public
+}
+
+// ViewOf — представление для запроса. Анонимный запрос (аутентификация выключена) видит
+// заказ целиком, как и до появления ролей.
+func ViewOf(ctx context.Context) View {
+	p, ok := auth.FromContext(ctx)
+	if !ok {
+		return Full
+	}
+	return ForPrincipal(p)
+}
+
+// Order — заказ в представлении вызывающего.
+func Order(ctx context.Context, o model.Order) model.Order { return Apply(ViewOf(ctx), o) }
+
+// Apply возвращает копию заказа в представлении v; исходный заказ (например, из кэша) не меняется.
+func Apply(v View, o model.Order) model.Order {
+	switch v {
+	case Full:
+		return o
+	case Analytics:
+		d := o.Delivery
+		d.Name = initials(d.Name)
+		d.Phone = maskPhone(d.Phone)
+		d.Email = maskEmail(d.Email)
+		d.Address = street(d.Address)
+		o.Delivery = d
+		return o
+	}
+	return model.Order{
+		OrderUID:    o.OrderUID,
+		TrackNumber: o.TrackNumber,
+		Items:       o.Items,
+		UpdatedAt:   o.UpdatedAt,
+	}
+}
+
+// initials: "Test Testov" → "T. T."
+func initials(name string) string {
+	parts := strings.Fields(name)
+	for i, p := range parts {
+		r, _ := utf8.DecodeRuneInString(p)
+		parts[i] = string(r) + "."
+	}
+	return strings.Join(parts, " ")
+}
+
+// maskPhone прячет все цифры, кроме двух последних: "+9720000000" → "+********00".
+func maskPhone(phone string) string {
+	digits := 0
+	for _, r := range phone {
+		if unicode.IsDigit(r) {
+			digits++
+		}
+	}
+	var b strings.Builder
+	seen := 0
+	for _, r := range phone {
+		if unicode.IsDigit(r) {
+			seen++
+			if seen <= digits-2 {
+				r = '*'
+			}
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// maskEmail оставляет первую букву ящика и домен: "test@gmail.com" → "t***@gmail.com".
+func maskEmail(email string) string {
+	if email == "" {
+		return ""
+	}
+	local, domain, ok := strings.Cut(email, "@")
+	if !ok || local == "" {
+		return "***"
+	}
+	r, _ := utf8.DecodeRuneInString(local)
+	return string(r) + "***@" + domain
+}
+
+// street обрезает адрес до первой цифры — номер дома, корпус и квартира уходят:
+// "Ploshad Mira 15" → "Ploshad Mira".
+func street(addr string) string {
+	if i := strings.IndexFunc(addr, unicode.IsDigit); i >= 0 {
+		addr = addr[:i]
+	}
+	return strings.TrimRight(addr, " ,.-/№#")
+}
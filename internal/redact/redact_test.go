@@ -0,0 +1,75 @@
+// internal/redact/redact_test.go
+package redact_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"demo/orders/internal/auth"
+	"demo/orders/internal/model"
+	"demo/orders/internal/redact"
+)
+
+func order() model.Order {
+	return model.Order{
+		OrderUID:    "b563feb7b2b84b6test",
+		TrackNumber: "WBILMTESTTRACK",
+		CustomerID:  "test",
+		Delivery: model.Delivery{
+			Name: "Test Testov", Phone: "+9720000000", Zip: "2639809", City: "Kiryat Mozkin",
+			Address: "Ploshad Mira 15", Region: "Kraiot", Email: "test@gmail.com",
+		},
+		Payment: model.Payment{Transaction: "b563feb7b2b84b6test", Currency: "USD", Amount: 1817},
+		Items:   []model.Item{{ChrtID: 9934930, Name: "Mascaras", Status: 202}},
+	}
+}
+
+func TestApply_Analytics(t *testing.T) {
+	o := order()
+	got := redact.Apply(redact.Analytics, o)
+
+	require.Equal(t, model.Delivery{
+		Name: "T. T.", Phone: "+********00", Zip: "2639809", City: "Kiryat Mozkin",
+		Address: "Ploshad Mira", Region: "Kraiot", Email: "t***@gmail.com",
+	}, got.Delivery)
+	require.Equal(t, o.Payment, got.Payment)
+	require.Equal(t, "Test Testov", o.Delivery.Name, "исходный заказ не меняется")
+}
+
+func TestApply_Public(t *testing.T) {
+	got := redact.Apply(redact.Public, order())
+	require.Equal(t, model.Order{
+		OrderUID:    "b563feb7b2b84b6test",
+		TrackNumber: "WBILMTESTTRACK",
+		Items:       []model.Item{{ChrtID: 9934930, Name: "Mascaras", Status: 202}},
+	}, got)
+}
+
+func TestViewOf(t *testing.T) {
+	ctx := context.Background()
+	require.Equal(t, redact.Full, redact.ViewOf(ctx), "без аутентификации — как раньше")
+
+	for roles, want := range map[string]redact.View{
+		"support":           redact.Full,
+		"analytics":         redact.Analytics,
+		"analytics,support": redact.Full,
+		"public":            redact.Public,
+		"":                  redact.Public,
+	} {
+		p := auth.Principal{Subject: "x"}
+		if roles != "" {
+			p.Roles = strings.Split(roles, ",")
+		}
+		require.Equal(t, want, redact.ViewOf(auth.NewContext(ctx, p)), roles)
+	}
+}
+
+func TestMasks_EdgeCases(t *testing.T) {
+	d := redact.Apply(redact.Analytics, model.Order{Delivery: model.Delivery{Email: "broken", Phone: "1", Address: "12 Baker St"}}).Delivery
+	require.Equal(t, "***", d.Email)
+	require.Equal(t, "1", d.Phone)
+	require.Equal(t, "", d.Address)
+}
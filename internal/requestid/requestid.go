@@ -0,0 +1,69 @@
+// Package requestid — идентификатор запроса: берётся из X-Request-ID клиента (или заголовка
+// сообщения Kafka) либо генерируется, кладётся в контекст и возвращается в ответе.
+package requestid
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+)
+
+// Header — заголовок, в котором ID приходит и уходит.
+const Header = "X-Request-ID"
+
+// MessageHeader — заголовок сообщения Kafka с ID, под которым заказ отправлен; консьюмер
+// продолжает логи с тем же ID.
+const MessageHeader = "x-request-id"
+
+const maxLen = 128
+
+type ctxKey struct{}
+
+// New генерирует случайный ID (128 бит, hex).
+func New() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// NewContext возвращает контекст с ID запроса.
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, ctxKey{}, id)
+}
+
+// FromContext достаёт ID запроса; "" — если его нет.
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(ctxKey{}).(string)
+	return id
+}
+
+// Middleware проставляет ID каждому запросу; клиентский ID проходит через Accept.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := Accept(r.Header.Get(Header))
+		w.Header().Set(Header, id)
+		next.ServeHTTP(w, r.WithContext(NewContext(r.Context(), id)))
+	})
+}
+
+// Accept возвращает пришедший извне id, если он годится в ID запроса, иначе новый.
+// Годится короткий ID из печатных ASCII-символов — он попадает в логи и заголовки.
+func Accept(id string) string {
+	if !valid(id) {
+		return New()
+	}
+	return id
+}
+
+func valid(id string) bool {
+	if id == "" || len(id) > maxLen {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,46 @@
+// internal/requestid/requestid_test.go
+package requestid_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"demo/orders/internal/requestid"
+)
+
+func TestMiddleware(t *testing.T) {
+	var seen string
+	h := requestid.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = requestid.FromContext(r.Context())
+	}))
+
+	for _, tc := range []struct{ in, want string }{
+		{"abc-123", "abc-123"},
+		{"", ""},
+		{"bad id\n", ""},
+		{strings.Repeat("x", 200), ""},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(requestid.Header, tc.in)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+
+		require.Equal(t, seen, rec.Header().Get(requestid.Header))
+		if tc.want != "" {
+			require.Equal(t, tc.want, seen)
+		} else {
+			require.Len(t, seen, 32, "сгенерированный вместо %q", tc.in)
+		}
+	}
+}
+
+func TestAccept(t *testing.T) {
+	require.Equal(t, "from-kafka-1", requestid.Accept("from-kafka-1"))
+	require.Len(t, requestid.Accept(""), 32)
+	require.Len(t, requestid.Accept("line\nbreak"), 32)
+	require.NotEqual(t, requestid.Accept(""), requestid.Accept(""))
+}
@@ -0,0 +1,66 @@
+// Package risk — оценка риска заказа при приёме из Kafka. Оценку складывают признаки (Signal):
+// каждый смотрит на заказ и прошлые заказы того же покупателя и говорит, насколько он подозрителен.
+// Свой признак — тип с методами Name и Check, добавленный в Scorer.Signals.
+package risk
+
+import (
+	"math"
+	"slices"
+
+	"demo/orders/internal/model"
+)
+
+// Signal — признак риска. Check возвращает силу от 0 (признака нет) до 1 и причину для человека;
+// past — прошлые заказы покупателя (и отложенные на проверку), новые первыми, без самого заказа.
+// Это только шапки: order_uid, date_created, payment.amount и currency, delivery.zip и region — без товаров.
+type Signal interface {
+	Name() string
+	Check(o model.Order, past []model.Order) (strength float64, reason string)
+}
+
+// Weighted — признак с весом: вклад в оценку — вес × сила.
+type Weighted struct {
+	Signal
+	Weight float64
+}
+
+// Scorer считает оценку заказа. nil — оценка выключена.
+type Scorer struct {
+	Signals []Weighted
+	History int     // сколько последних заказов покупателя смотреть
+	Review  float64 // оценка от Review и выше — заказ на ручную проверку; 0 — только сохранять оценку
+}
+
+// Score — оценка заказа: сработавшие признаки по убыванию вклада и их сумма.
+func (s *Scorer) Score(o model.Order, past []model.Order) model.Risk {
+	past = slices.DeleteFunc(slices.Clone(past), func(p model.Order) bool { return p.OrderUID == o.OrderUID })
+	r := model.Risk{Signals: []model.RiskSignal{}}
+	for _, w := range s.Signals {
+		strength, reason := w.Check(o, past)
+		if strength <= 0 {
+			continue
+		}
+		score := round(w.Weight * min(strength, 1))
+		r.Score += score
+		r.Signals = append(r.Signals, model.RiskSignal{Name: w.Name(), Score: score, Reason: reason})
+	}
+	r.Score = round(r.Score)
+	slices.SortStableFunc(r.Signals, func(a, b model.RiskSignal) int {
+		switch {
+		case a.Score > b.Score:
+			return -1
+		case a.Score < b.Score:
+			return 1
+		}
+		return 0
+	})
+	return r
+}
+
+// NeedsReview — отправить ли заказ с оценкой r на ручную проверку.
+func (s *Scorer) NeedsReview(r model.Risk) bool {
+	return s != nil && s.Review > 0 && r.Score >= s.Review
+}
+
+// round — до тысячных: 0.1+0.2 в логах и API выглядит как 0.3.
+func round(f float64) float64 { return math.Round(f*1000) / 1000 }
@@ -0,0 +1,113 @@
+// internal/risk/risk_test.go
+package risk_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"demo/orders/internal/model"
+	"demo/orders/internal/risk"
+)
+
+var now = time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+
+func order(uid string, amount int, ago time.Duration) model.Order {
+	return model.Order{
+		OrderUID: uid, CustomerID: "test", DateCreated: now.Add(-ago),
+		Delivery: model.Delivery{Zip: "2639809", Region: "Kraiot"},
+		Payment:  model.Payment{Currency: "USD", Amount: amount},
+		Items:    []model.Item{{Sale: 30}},
+	}
+}
+
+func TestSignals(t *testing.T) {
+	past := []model.Order{order("p1", 1000, 24*time.Hour), order("p2", 1200, 48*time.Hour), order("p3", 900, 72*time.Hour)}
+
+	amount := risk.AmountOutlier{Factor: 5, MinHistory: 3}
+	s, _ := amount.Check(order("o", 4000, 0), past)
+	require.Zero(t, s)
+	s, reason := amount.Check(order("o", 5000, 0), past)
+	require.Equal(t, 1.0, s)
+	require.Equal(t, "amount 5000 is 5.0x the customer's median 1000 over 3 orders", reason)
+	s, _ = amount.Check(order("o", 50000, 0), past[:2]) // истории мало
+	require.Zero(t, s)
+	eur := order("o", 50000, 0)
+	eur.Payment.Currency = "EUR" // в другой валюте истории нет
+	s, _ = amount.Check(eur, past)
+	require.Zero(t, s)
+
+	velocity := risk.Velocity{Window: time.Hour, Max: 2}
+	s, _ = velocity.Check(order("o", 0, 0), append(past, order("p4", 0, 10*time.Minute)))
+	require.Zero(t, s)
+	s, reason = velocity.Check(order("o", 0, 0), append(past, order("p4", 0, 10*time.Minute), order("p5", 0, 50*time.Minute)))
+	require.Equal(t, 1.0, s)
+	require.Equal(t, "3 orders from the customer within 1h0m0s", reason)
+
+	zip := risk.ZipRegion{Regions: map[string]string{"26": "Kraiot", "1": "North"}}
+	s, _ = zip.Check(order("o", 0, 0), past)
+	require.Zero(t, s)
+	moved := order("o", 0, 0)
+	moved.Delivery.Zip = "1000"
+	s, reason = zip.Check(moved, nil)
+	require.Equal(t, 1.0, s)
+	require.Equal(t, "zip 1000 belongs to North, not Kraiot", reason)
+	moved = order("o", 0, 0)
+	moved.Delivery.Region = "South"
+	s, reason = risk.ZipRegion{}.Check(moved, past)
+	require.Equal(t, 1.0, s)
+	require.Equal(t, "zip 2639809 was delivered to Kraiot in order p1, now South", reason)
+
+	sale := risk.SaleOutlier{Max: 80}
+	s, _ = sale.Check(order("o", 0, 0), nil)
+	require.Zero(t, s)
+	big := order("o", 0, 0)
+	big.Items = append(big.Items, model.Item{Sale: 90}, model.Item{Sale: 85})
+	s, reason = sale.Check(big, nil)
+	require.Equal(t, 0.5, s)
+	require.Equal(t, "items[1].sale 90% is above 80%", reason)
+}
+
+func TestScorer(t *testing.T) {
+	sc := &risk.Scorer{
+		Signals: []risk.Weighted{
+			{Signal: risk.SaleOutlier{Max: 80}, Weight: 0.4},
+			{Signal: risk.AmountOutlier{Factor: 5, MinHistory: 1}, Weight: 0.5},
+			{Signal: risk.Velocity{Window: time.Hour, Max: 5}, Weight: 0.3},
+		},
+		Review: 0.6,
+	}
+	o := order("o", 10000, 0)
+	o.Items[0].Sale = 90
+	// сам заказ в истории (повтор сообщения) не считается
+	r := sc.Score(o, []model.Order{order("o", 10000, 0), order("p1", 1000, time.Hour)})
+	require.Equal(t, 0.7, r.Score)
+	require.Len(t, r.Signals, 2)
+	require.Equal(t, "amount", r.Signals[0].Name)
+	require.Equal(t, 0.5, r.Signals[0].Score)
+	require.Equal(t, model.RiskSignal{Name: "sale", Score: 0.2, Reason: "items[0].sale 90% is above 80%"}, r.Signals[1])
+	require.True(t, sc.NeedsReview(r))
+
+	r = sc.Score(order("o", 1000, 0), nil)
+	require.Equal(t, model.Risk{Signals: []model.RiskSignal{}}, r)
+	require.False(t, sc.NeedsReview(r))
+
+	sc.Review = 0 // только оценка
+	require.False(t, sc.NeedsReview(model.Risk{Score: 10}))
+	require.False(t, (*risk.Scorer)(nil).NeedsReview(model.Risk{Score: 10}))
+}
+
+func TestLoadZipRegions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "zip.csv")
+	require.NoError(t, os.WriteFile(path, []byte("zip_prefix,region\n26,Kraiot\n 1 , North \n"), 0o644))
+	m, err := risk.LoadZipRegions(path)
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"26": "Kraiot", "1": "North"}, m)
+
+	require.NoError(t, os.WriteFile(path, []byte("zip_prefix,region\n,Kraiot\n"), 0o644))
+	_, err = risk.LoadZipRegions(path)
+	require.ErrorContains(t, err, "zip.csv:2: want zip_prefix,region")
+}
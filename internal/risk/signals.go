@@ -0,0 +1,144 @@
+package risk
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"demo/orders/internal/model"
+)
+
+// AmountOutlier — сумма заказа во много раз больше обычной для покупателя: медианы его прошлых
+// заказов в той же валюте. Покупателей с короткой историей не трогает.
+type AmountOutlier struct {
+	Factor     float64 // во сколько раз больше медианы — уже подозрительно
+	MinHistory int     // сколько прошлых заказов в валюте нужно, чтобы судить
+}
+
+func (AmountOutlier) Name() string { return "amount" }
+
+func (s AmountOutlier) Check(o model.Order, past []model.Order) (float64, string) {
+	var amounts []int
+	for _, p := range past {
+		if strings.EqualFold(p.Payment.Currency, o.Payment.Currency) {
+			amounts = append(amounts, p.Payment.Amount)
+		}
+	}
+	if len(amounts) == 0 || len(amounts) < s.MinHistory {
+		return 0, ""
+	}
+	slices.Sort(amounts)
+	median := amounts[len(amounts)/2]
+	if median <= 0 || float64(o.Payment.Amount) < s.Factor*float64(median) {
+		return 0, ""
+	}
+	return 1, fmt.Sprintf("amount %d is %.1fx the customer's median %d over %d orders",
+		o.Payment.Amount, float64(o.Payment.Amount)/float64(median), median, len(amounts))
+}
+
+// Velocity — у покупателя слишком много заказов за короткое окно (считая этот).
+type Velocity struct {
+	Window time.Duration
+	Max    int // больше стольких заказов за Window — подозрительно
+}
+
+func (Velocity) Name() string { return "velocity" }
+
+func (s Velocity) Check(o model.Order, past []model.Order) (float64, string) {
+	n := 1
+	for _, p := range past {
+		if d := o.DateCreated.Sub(p.DateCreated); d >= 0 && d < s.Window {
+			n++
+		}
+	}
+	if n <= s.Max {
+		return 0, ""
+	}
+	return 1, fmt.Sprintf("%d orders from the customer within %s", n, s.Window)
+}
+
+// ZipRegion — индекс не сходится с регионом доставки: по справочнику префиксов индексов
+// (если задан) или по прошлым заказам покупателя с тем же индексом и другим регионом.
+type ZipRegion struct {
+	Regions map[string]string // префикс индекса → регион; сверяется самый длинный подходящий
+}
+
+func (ZipRegion) Name() string { return "zip_region" }
+
+func (s ZipRegion) Check(o model.Order, past []model.Order) (float64, string) {
+	zip, region := strings.TrimSpace(o.Delivery.Zip), strings.TrimSpace(o.Delivery.Region)
+	if zip == "" || region == "" {
+		return 0, ""
+	}
+	if want, ok := s.region(zip); ok && !strings.EqualFold(want, region) {
+		return 1, fmt.Sprintf("zip %s belongs to %s, not %s", zip, want, region)
+	}
+	for _, p := range past {
+		pr := strings.TrimSpace(p.Delivery.Region)
+		if strings.TrimSpace(p.Delivery.Zip) == zip && pr != "" && !strings.EqualFold(pr, region) {
+			return 1, fmt.Sprintf("zip %s was delivered to %s in order %s, now %s", zip, pr, p.OrderUID, region)
+		}
+	}
+	return 0, ""
+}
+
+func (s ZipRegion) region(zip string) (string, bool) {
+	for n := len(zip); n > 0; n-- {
+		if r, ok := s.Regions[zip[:n]]; ok {
+			return r, true
+		}
+	}
+	return "", false
+}
+
+// LoadZipRegions читает справочник индексов из CSV: строки "префикс,регион", первая — заголовок.
+func LoadZipRegions(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	rows, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	m := make(map[string]string, len(rows))
+	for i, r := range rows {
+		if i == 0 {
+			continue
+		}
+		if len(r) != 2 || strings.TrimSpace(r[0]) == "" {
+			return nil, fmt.Errorf("%s:%d: want zip_prefix,region", path, i+1)
+		}
+		m[strings.TrimSpace(r[0])] = strings.TrimSpace(r[1])
+	}
+	return m, nil
+}
+
+// SaleOutlier — у товара необычно большая скидка. Сила растёт от Max до 100%.
+type SaleOutlier struct {
+	Max int // скидка выше Max процентов — подозрительно
+}
+
+func (SaleOutlier) Name() string { return "sale" }
+
+func (s SaleOutlier) Check(o model.Order, _ []model.Order) (float64, string) {
+	worst := -1
+	for i, it := range o.Items {
+		if it.Sale > s.Max && (worst < 0 || it.Sale > o.Items[worst].Sale) {
+			worst = i
+		}
+	}
+	if worst < 0 {
+		return 0, ""
+	}
+	sale := o.Items[worst].Sale
+	strength := 1.0
+	if s.Max < 100 {
+		strength = float64(sale-s.Max) / float64(100-s.Max)
+	}
+	return strength, fmt.Sprintf("items[%d].sale %d%% is above %d%%", worst, sale, s.Max)
+}
@@ -0,0 +1,192 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"demo/orders/internal/model"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// OrderFilter — общие фильтры для листинга и выгрузки. Пустые поля не фильтруют.
+type OrderFilter struct {
+	CustomerID      string
+	DeliveryService string
+	Currency        string
+	CreatedFrom     time.Time // включительно
+	CreatedTo       time.Time // не включительно
+	Flagged         bool      // только заказы с предупреждениями (в карантине)
+	WarningCode     string    // только заказы с предупреждением этого кода
+}
+
+// where собирает условие WHERE; номера плейсхолдеров продолжают args.
+func (f OrderFilter) where(args []any) (string, []any) {
+	var conds []string
+	add := func(cond string, v any) {
+		args = append(args, v)
+		conds = append(conds, fmt.Sprintf(cond, len(args)))
+	}
+	if f.CustomerID != "" {
+		add("o.customer_id = $%d", f.CustomerID)
+	}
+	if f.DeliveryService != "" {
+		add("o.delivery_service = $%d", f.DeliveryService)
+	}
+	if f.Currency != "" {
+		add("upper(p.currency) = upper($%d)", f.Currency)
+	}
+	if !f.CreatedFrom.IsZero() {
+		add("o.date_created >= $%d", f.CreatedFrom)
+	}
+	if !f.CreatedTo.IsZero() {
+		add("o.date_created < $%d", f.CreatedTo)
+	}
+	if f.WarningCode != "" {
+		add("EXISTS (SELECT 1 FROM order_warnings w WHERE w.order_uid = o.order_uid AND w.code = $%d)", f.WarningCode)
+	} else if f.Flagged {
+		conds = append(conds, "EXISTS (SELECT 1 FROM order_warnings w WHERE w.order_uid = o.order_uid)")
+	}
+	if len(conds) == 0 {
+		return "", args
+	}
+	return " WHERE " + strings.Join(conds, " AND "), args
+}
+
+// ListOrders — страница заказов по фильтру, упорядоченная по order_uid.
+// after — order_uid последнего заказа предыдущей страницы (keyset-пагинация).
+func (r *Repo) ListOrders(ctx context.Context, f OrderFilter, after string, limit int) ([]model.Order, error) {
+	where, args := f.where(nil)
+	if after != "" {
+		args = append(args, after)
+		if where == "" {
+			where = " WHERE "
+		} else {
+			where += " AND "
+		}
+		where += fmt.Sprintf("o.order_uid > $%d", len(args))
+	}
+	args = append(args, limit)
+	query := orderSelect + where + fmt.Sprintf(" ORDER BY o.order_uid LIMIT $%d", len(args))
+	return queryOrders(ctx, r.Pool, query, args...)
+}
+
+const streamBatch = 500
+
+// StreamOrders проходит по всем заказам фильтра через серверный курсор Postgres и вызывает fn
+// для каждого заказа по порядку order_uid. В памяти одновременно не больше streamBatch строк,
+// так что потребление не зависит от размера выборки. Ошибка из fn прерывает обход.
+func (r *Repo) StreamOrders(ctx context.Context, f OrderFilter, fn func(model.Order) error) error {
+	tx, err := r.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	where, args := f.where(nil)
+	// items присоединяются сразу: строки одного заказа идут подряд благодаря ORDER BY
+	_, err = tx.Exec(ctx, `DECLARE orders_export NO SCROLL CURSOR FOR `+streamSelect+where+` ORDER BY o.order_uid, i.chrt_id`, args...)
+	if err != nil {
+		return err
+	}
+
+	var cur *model.Order
+	for {
+		rows, err := tx.Query(ctx, fmt.Sprintf(`FETCH FORWARD %d FROM orders_export`, streamBatch))
+		if err != nil {
+			return err
+		}
+		n := 0
+		for rows.Next() {
+			n++
+			o, it, hasItem, err := scanStreamRow(rows)
+			if err != nil {
+				rows.Close()
+				return err
+			}
+			if cur != nil && cur.OrderUID != o.OrderUID {
+				if err := fn(*cur); err != nil {
+					rows.Close()
+					return err
+				}
+				cur = nil
+			}
+			if cur == nil {
+				cur = &o
+			}
+			if hasItem {
+				cur.Items = append(cur.Items, it)
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		if n < streamBatch {
+			break
+		}
+	}
+	if cur != nil {
+		if err := fn(*cur); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+// streamSelect — orderSelect с товарами через LEFT JOIN: строка на товар (или одна на заказ без товаров).
+// Столбцы заказа — те же и в том же порядке, что у orderSelect; scanStreamRow читает их по позиции.
+const streamSelect = `
+		SELECT o.order_uid, o.track_number, o.entry, o.locale, o.internal_signature, o.customer_id,
+		       o.delivery_service, o.shardkey, o.sm_id, o.date_created, o.oof_shard, o.updated_at,
+		       d.name, d.phone, d.zip, d.city, d.address, d.region, d.email,
+		       p.transaction, p.request_id, p.currency, p.provider, p.amount, p.payment_dt, p.bank, p.delivery_cost, p.goods_total, p.custom_fee,
+		       i.chrt_id IS NOT NULL, COALESCE(i.chrt_id, 0), COALESCE(i.track_number, ''), COALESCE(i.price, 0),
+		       COALESCE(i.rid, ''), COALESCE(i.name, ''), COALESCE(i.sale, 0), COALESCE(i.size, ''),
+		       COALESCE(i.total_price, 0), COALESCE(i.nm_id, 0), COALESCE(i.brand, ''), COALESCE(i.status, 0)
+		FROM orders o
+		LEFT JOIN deliveries d ON d.order_uid = o.order_uid
+		LEFT JOIN payments  p ON p.order_uid = o.order_uid
+		LEFT JOIN items i ON i.order_uid = o.order_uid`
+
+func scanStreamRow(row pgx.Row) (model.Order, model.Item, bool, error) {
+	var o model.Order
+	var it model.Item
+	var hasItem bool
+	var payTime time.Time
+	err := row.Scan(&o.OrderUID, &o.TrackNumber, &o.Entry, &o.Locale, &o.InternalSignature, &o.CustomerID,
+		&o.DeliveryService, &o.ShardKey, &o.SmID, &o.DateCreated, &o.OofShard, &o.UpdatedAt,
+		&o.Delivery.Name, &o.Delivery.Phone, &o.Delivery.Zip, &o.Delivery.City, &o.Delivery.Address, &o.Delivery.Region, &o.Delivery.Email,
+		&o.Payment.Transaction, &o.Payment.RequestID, &o.Payment.Currency, &o.Payment.Provider, &o.Payment.Amount, &payTime, &o.Payment.Bank, &o.Payment.DeliveryCost, &o.Payment.GoodsTotal, &o.Payment.CustomFee,
+		&hasItem, &it.ChrtID, &it.TrackNumber, &it.Price, &it.RID, &it.Name, &it.Sale, &it.Size, &it.TotalPrice, &it.NmID, &it.Brand, &it.Status)
+	if err != nil {
+		return model.Order{}, model.Item{}, false, err
+	}
+	o.Payment.PaymentDT = payTime.Unix()
+	return o, it, hasItem, nil
+}
+
+// CustomerOrders — последние perCustomer заказов (по date_created) для каждого из покупателей одним запросом.
+func (r *Repo) CustomerOrders(ctx context.Context, customerIDs []string, perCustomer int) (map[string][]model.Order, error) {
+	out := make(map[string][]model.Order, len(customerIDs))
+	if len(customerIDs) == 0 || perCustomer <= 0 {
+		return out, nil
+	}
+	orders, err := queryOrders(ctx, r.Pool, orderSelect+`
+		WHERE o.order_uid IN (
+			SELECT order_uid FROM (
+				SELECT order_uid, row_number() OVER (PARTITION BY customer_id ORDER BY date_created DESC, order_uid) AS rn
+				FROM orders WHERE customer_id = ANY($1)
+			) h WHERE h.rn <= $2
+		)
+		ORDER BY o.date_created DESC, o.order_uid`, customerIDs, perCustomer)
+	if err != nil {
+		return nil, err
+	}
+	for _, o := range orders {
+		out[o.CustomerID] = append(out[o.CustomerID], o)
+	}
+	return out, nil
+}
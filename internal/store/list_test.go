@@ -0,0 +1,18 @@
+package store
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// streamSelect читается scanStreamRow по позиции: столбцы заказа должны совпадать с orderSelect.
+func TestStreamSelect_MatchesOrderSelect(t *testing.T) {
+	cols, joins, ok := strings.Cut(orderSelect, "FROM orders o")
+	require.True(t, ok)
+	require.True(t, strings.HasPrefix(streamSelect, strings.TrimRight(cols, " \t\n")+",\n"), "order columns differ")
+	require.Contains(t, streamSelect, "FROM orders o"+joins)
+	require.Contains(t, streamSelect, "LEFT JOIN items i ON i.order_uid = o.order_uid")
+	require.Equal(t, 11, strings.Count(streamSelect, "COALESCE(i."), "item columns")
+}
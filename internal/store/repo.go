@@ -14,12 +14,39 @@ import (
 type Repository interface {
 	UpsertOrder(ctx context.Context, o model.Order) error
 	GetOrder(ctx context.Context, orderUID string) (model.Order, bool, error)
+	GetOrders(ctx context.Context, orderUIDs []string) ([]model.Order, error)
 	LoadAllOrders(ctx context.Context) ([]model.Order, error)
+	ListOrders(ctx context.Context, f OrderFilter, after string, limit int) ([]model.Order, error)
+	StreamOrders(ctx context.Context, f OrderFilter, fn func(model.Order) error) error
+	CustomerOrders(ctx context.Context, customerIDs []string, perCustomer int) (map[string][]model.Order, error)
+	RiskHistory(ctx context.Context, customerID string, limit int) ([]model.Order, error)
+	UpdateOrder(ctx context.Context, orderUID string, fn UpdateFunc) (model.Order, error)
+	OrderWarnings(ctx context.Context, orderUID string) ([]model.Warning, bool, error)
+	OrderRisk(ctx context.Context, orderUID string) (*model.Risk, bool, error)
+	QueueReview(ctx context.Context, o model.Order) error
+	ListReviews(ctx context.Context, after string, limit int) ([]model.Review, error)
+	ApproveReview(ctx context.Context, orderUID string) (model.Order, bool, error)
+	RejectReview(ctx context.Context, orderUID string) (bool, error)
 }
+
+// UpdateFunc получает текущий заказ (found=false, если его ещё нет) и возвращает новую версию.
+// Ошибка из fn отменяет транзакцию и возвращается из UpdateOrder как есть.
+type UpdateFunc func(cur model.Order, found bool) (model.Order, error)
+
+var ErrNotFound = errors.New("order not found")
+
+// ErrUnderReview — заказ ждёт ручной проверки; менять его можно только через ApproveReview и RejectReview.
+var ErrUnderReview = errors.New("order is awaiting review")
+
 type Repo struct {
 	Pool PgxIface
 }
 
+type querier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
 type PgxIface interface {
 	Begin(ctx context.Context) (pgx.Tx, error)
 	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
@@ -36,15 +63,74 @@ func (r *Repo) UpsertOrder(ctx context.Context, o model.Order) error {
 	}
 	defer func() { _ = tx.Rollback(ctx) }()
 
-	_, err = tx.Exec(ctx, `
-		INSERT INTO orders (order_uid, track_number, entry, locale, internal_signature, customer_id, delivery_service, shardkey, sm_id, date_created, oof_shard)
-		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11)
+	if err := writeOrder(ctx, tx, o); err != nil {
+		return err
+	}
+	// консьюмер принял версию новее отложенной на проверку — одобрять ту уже нечего
+	if _, err := tx.Exec(ctx, `DELETE FROM review_queue WHERE order_uid=$1`, o.OrderUID); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// UpdateOrder блокирует строку заказа (SELECT ... FOR UPDATE), вызывает fn и сохраняет результат
+// в той же транзакции — конкурентные обновления одного заказа выполняются строго по очереди.
+// Заказ в очереди проверки не меняется: ErrUnderReview.
+func (r *Repo) UpdateOrder(ctx context.Context, orderUID string, fn UpdateFunc) (model.Order, error) {
+	tx, err := r.Pool.Begin(ctx)
+	if err != nil {
+		return model.Order{}, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	var queued bool
+	if err := tx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM review_queue WHERE order_uid=$1)`, orderUID).Scan(&queued); err != nil {
+		return model.Order{}, err
+	}
+	if queued {
+		return model.Order{}, ErrUnderReview
+	}
+
+	cur, found, err := getOrder(ctx, tx, orderUID, true)
+	if err != nil {
+		return model.Order{}, err
+	}
+	next, err := fn(cur, found)
+	if err != nil {
+		return model.Order{}, err
+	}
+	next.UpdatedAt = time.Now().UTC().Truncate(time.Microsecond)
+	if err := writeOrder(ctx, tx, next); err != nil {
+		return model.Order{}, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return model.Order{}, err
+	}
+	return next, nil
+}
+
+// writeOrder пишет заказ целиком. Если o.UpdatedAt не задан, берётся текущее время;
+// вызывающему, который кладёт заказ ещё и в кэш, лучше выставить его самому.
+func writeOrder(ctx context.Context, tx pgx.Tx, o model.Order) error {
+	if o.UpdatedAt.IsZero() {
+		o.UpdatedAt = time.Now().UTC()
+	}
+	// оценка относится к этой версии заказа: без неё (запись через HTTP) прежняя стирается
+	var riskScore *float64
+	var riskSignals any
+	if o.Risk != nil {
+		riskScore, riskSignals = &o.Risk.Score, o.Risk.Signals
+	}
+	_, err := tx.Exec(ctx, `
+		INSERT INTO orders (order_uid, track_number, entry, locale, internal_signature, customer_id, delivery_service, shardkey, sm_id, date_created, oof_shard, updated_at, risk_score, risk_signals)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14)
 		ON CONFLICT (order_uid) DO UPDATE SET
 		  track_number=EXCLUDED.track_number, entry=EXCLUDED.entry, locale=EXCLUDED.locale,
 		  internal_signature=EXCLUDED.internal_signature, customer_id=EXCLUDED.customer_id,
 		  delivery_service=EXCLUDED.delivery_service, shardkey=EXCLUDED.shardkey,
-		  sm_id=EXCLUDED.sm_id, date_created=EXCLUDED.date_created, oof_shard=EXCLUDED.oof_shard
-	`, o.OrderUID, o.TrackNumber, o.Entry, o.Locale, o.InternalSignature, o.CustomerID, o.DeliveryService, o.ShardKey, o.SmID, o.DateCreated, o.OofShard)
+		  sm_id=EXCLUDED.sm_id, date_created=EXCLUDED.date_created, oof_shard=EXCLUDED.oof_shard,
+		  updated_at=EXCLUDED.updated_at, risk_score=EXCLUDED.risk_score, risk_signals=EXCLUDED.risk_signals
+	`, o.OrderUID, o.TrackNumber, o.Entry, o.Locale, o.InternalSignature, o.CustomerID, o.DeliveryService, o.ShardKey, o.SmID, o.DateCreated, o.OofShard, o.UpdatedAt, riskScore, riskSignals)
 	if err != nil {
 		return err
 	}
@@ -88,42 +174,79 @@ func (r *Repo) UpsertOrder(ctx context.Context, o model.Order) error {
 		}
 	}
 
-	return tx.Commit(ctx)
+	// предупреждения относятся к этой версии заказа — прежние уходят
+	_, err = tx.Exec(ctx, `DELETE FROM order_warnings WHERE order_uid=$1`, o.OrderUID)
+	if err != nil {
+		return err
+	}
+	for _, w := range o.Warnings {
+		_, err = tx.Exec(ctx, `
+			INSERT INTO order_warnings (order_uid, field, pointer, code, message)
+			VALUES ($1,$2,$3,$4,$5)
+		`, o.OrderUID, w.Field, w.Pointer, w.Code, w.Message)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func (r *Repo) GetOrder(ctx context.Context, orderUID string) (model.Order, bool, error) {
-	var o model.Order
-	row := r.Pool.QueryRow(ctx, `
+	return getOrder(ctx, r.Pool, orderUID, false)
+}
+
+const orderSelect = `
 		SELECT o.order_uid, o.track_number, o.entry, o.locale, o.internal_signature, o.customer_id,
-		       o.delivery_service, o.shardkey, o.sm_id, o.date_created, o.oof_shard,
+		       o.delivery_service, o.shardkey, o.sm_id, o.date_created, o.oof_shard, o.updated_at,
 		       d.name, d.phone, d.zip, d.city, d.address, d.region, d.email,
 		       p.transaction, p.request_id, p.currency, p.provider, p.amount, p.payment_dt, p.bank, p.delivery_cost, p.goods_total, p.custom_fee
 		FROM orders o
 		LEFT JOIN deliveries d ON d.order_uid = o.order_uid
-		LEFT JOIN payments  p ON p.order_uid = o.order_uid
-		WHERE o.order_uid=$1`, orderUID)
+		LEFT JOIN payments  p ON p.order_uid = o.order_uid`
 
+const itemColumns = `chrt_id, track_number, price, rid, name, sale, size, total_price, nm_id, brand, status`
+
+// scanOrder читает строку orderSelect (без items).
+func scanOrder(row pgx.Row) (model.Order, error) {
+	var o model.Order
 	var payTime time.Time
 	err := row.Scan(&o.OrderUID, &o.TrackNumber, &o.Entry, &o.Locale, &o.InternalSignature, &o.CustomerID,
-		&o.DeliveryService, &o.ShardKey, &o.SmID, &o.DateCreated, &o.OofShard,
+		&o.DeliveryService, &o.ShardKey, &o.SmID, &o.DateCreated, &o.OofShard, &o.UpdatedAt,
 		&o.Delivery.Name, &o.Delivery.Phone, &o.Delivery.Zip, &o.Delivery.City, &o.Delivery.Address, &o.Delivery.Region, &o.Delivery.Email,
 		&o.Payment.Transaction, &o.Payment.RequestID, &o.Payment.Currency, &o.Payment.Provider, &o.Payment.Amount, &payTime, &o.Payment.Bank, &o.Payment.DeliveryCost, &o.Payment.GoodsTotal, &o.Payment.CustomFee)
+	if err != nil {
+		return model.Order{}, err
+	}
+	o.Payment.PaymentDT = payTime.Unix()
+	return o, nil
+}
+
+func scanItem(row pgx.Row, it *model.Item) error {
+	return row.Scan(&it.ChrtID, &it.TrackNumber, &it.Price, &it.RID, &it.Name, &it.Sale, &it.Size, &it.TotalPrice, &it.NmID, &it.Brand, &it.Status)
+}
+
+func getOrder(ctx context.Context, q querier, orderUID string, lock bool) (model.Order, bool, error) {
+	query := orderSelect + ` WHERE o.order_uid=$1`
+	if lock {
+		// nullable-сторону LEFT JOIN блокировать нельзя, поэтому только строку orders
+		query += ` FOR UPDATE OF o`
+	}
+	o, err := scanOrder(q.QueryRow(ctx, query, orderUID))
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return model.Order{}, false, nil
 		}
 		return model.Order{}, false, err
 	}
-	o.Payment.PaymentDT = payTime.Unix()
 
-	rows, err := r.Pool.Query(ctx, `SELECT chrt_id, track_number, price, rid, name, sale, size, total_price, nm_id, brand, status FROM items WHERE order_uid=$1`, orderUID)
+	rows, err := q.Query(ctx, `SELECT `+itemColumns+` FROM items WHERE order_uid=$1`, orderUID)
 	if err != nil {
 		return model.Order{}, false, err
 	}
 	defer rows.Close()
 	for rows.Next() {
 		var it model.Item
-		if err := rows.Scan(&it.ChrtID, &it.TrackNumber, &it.Price, &it.RID, &it.Name, &it.Sale, &it.Size, &it.TotalPrice, &it.NmID, &it.Brand, &it.Status); err != nil {
+		if err := scanItem(rows, &it); err != nil {
 			return model.Order{}, false, err
 		}
 		o.Items = append(o.Items, it)
@@ -134,33 +257,115 @@ func (r *Repo) GetOrder(ctx context.Context, orderUID string) (model.Order, bool
 	return o, true, nil
 }
 
+// OrderWarnings — предупреждения, с которыми сохранена текущая версия заказа; found=false — заказа нет.
+func (r *Repo) OrderWarnings(ctx context.Context, orderUID string) ([]model.Warning, bool, error) {
+	rows, err := r.Pool.Query(ctx, `
+		SELECT w.field, w.pointer, w.code, w.message
+		FROM orders o
+		LEFT JOIN order_warnings w ON w.order_uid = o.order_uid
+		WHERE o.order_uid=$1
+		ORDER BY w.id`, orderUID)
+	if err != nil {
+		return nil, false, err
+	}
+	defer rows.Close()
+	found := false
+	ws := []model.Warning{}
+	for rows.Next() {
+		found = true
+		var field, pointer, code, message *string // NULL — заказ без предупреждений
+		if err := rows.Scan(&field, &pointer, &code, &message); err != nil {
+			return nil, false, err
+		}
+		if field != nil {
+			ws = append(ws, model.Warning{Field: *field, Pointer: *pointer, Code: *code, Message: *message})
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, false, err
+	}
+	return ws, found, nil
+}
+
+// OrderRisk — оценка риска текущей версии заказа: nil — версия не оценивалась; found=false — заказа нет.
+func (r *Repo) OrderRisk(ctx context.Context, orderUID string) (*model.Risk, bool, error) {
+	var score *float64
+	var signals []model.RiskSignal
+	err := r.Pool.QueryRow(ctx, `SELECT risk_score, risk_signals FROM orders WHERE order_uid=$1`, orderUID).Scan(&score, &signals)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+	if score == nil {
+		return nil, true, nil
+	}
+	if signals == nil {
+		signals = []model.RiskSignal{}
+	}
+	return &model.Risk{Score: *score, Signals: signals}, true, nil
+}
+
+// GetOrders достаёт набор заказов двумя запросами (шапки + все items) вместо N отдельных GetOrder.
+// Порядок результата не определён; отсутствующие id просто не попадают в ответ.
+func (r *Repo) GetOrders(ctx context.Context, orderUIDs []string) ([]model.Order, error) {
+	if len(orderUIDs) == 0 {
+		return nil, nil
+	}
+	return queryOrders(ctx, r.Pool, orderSelect+` WHERE o.order_uid = ANY($1)`, orderUIDs)
+}
+
 func (r *Repo) LoadAllOrders(ctx context.Context) ([]model.Order, error) {
-	rows, err := r.Pool.Query(ctx, `SELECT order_uid FROM orders`)
+	return queryOrders(ctx, r.Pool, orderSelect)
+}
+
+// queryOrders выполняет query (на базе orderSelect) и дозагружает items одним запросом по всем найденным id.
+func queryOrders(ctx context.Context, q querier, query string, args ...any) ([]model.Order, error) {
+	rows, err := q.Query(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
-	var ids []string
+	var out []model.Order
 	for rows.Next() {
-		var id string
-		if err := rows.Scan(&id); err != nil {
+		o, err := scanOrder(rows)
+		if err != nil {
+			rows.Close()
 			return nil, err
 		}
-		ids = append(ids, id)
+		out = append(out, o)
 	}
+	rows.Close()
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
+	if len(out) == 0 {
+		return out, nil
+	}
 
-	out := make([]model.Order, 0, len(ids))
-	for _, id := range ids {
-		o, ok, err := r.GetOrder(ctx, id)
-		if err != nil {
+	ids := make([]string, len(out))
+	idx := make(map[string]int, len(out))
+	for i, o := range out {
+		ids[i] = o.OrderUID
+		idx[o.OrderUID] = i
+	}
+	irows, err := q.Query(ctx, `SELECT order_uid, `+itemColumns+` FROM items WHERE order_uid = ANY($1)`, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer irows.Close()
+	for irows.Next() {
+		var uid string
+		var it model.Item
+		if err := irows.Scan(&uid, &it.ChrtID, &it.TrackNumber, &it.Price, &it.RID, &it.Name, &it.Sale, &it.Size, &it.TotalPrice, &it.NmID, &it.Brand, &it.Status); err != nil {
 			return nil, err
 		}
-		if ok {
-			out = append(out, o)
+		if i, ok := idx[uid]; ok {
+			out[i].Items = append(out[i].Items, it)
 		}
 	}
+	if err := irows.Err(); err != nil {
+		return nil, err
+	}
 	return out, nil
 }
@@ -0,0 +1,143 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"demo/orders/internal/model"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// QueueReview откладывает заказ на ручную проверку: в orders он не пишется, пока его не одобрят.
+// Повторное сообщение того же заказа заменяет отложенную версию. o.Risk обязателен.
+func (r *Repo) QueueReview(ctx context.Context, o model.Order) error {
+	if o.Risk == nil {
+		return errors.New("queue review: order has no risk score")
+	}
+	warnings := o.Warnings
+	if warnings == nil {
+		warnings = []model.Warning{}
+	}
+	_, err := r.Pool.Exec(ctx, `
+		INSERT INTO review_queue (order_uid, customer_id, payload, warnings, risk_score, risk_signals)
+		VALUES ($1,$2,$3,$4,$5,$6)
+		ON CONFLICT (order_uid) DO UPDATE SET
+		  customer_id=EXCLUDED.customer_id, payload=EXCLUDED.payload, warnings=EXCLUDED.warnings,
+		  risk_score=EXCLUDED.risk_score, risk_signals=EXCLUDED.risk_signals, queued_at=now()
+	`, o.OrderUID, o.CustomerID, o, warnings, o.Risk.Score, o.Risk.Signals)
+	return err
+}
+
+const reviewColumns = `payload, warnings, risk_score, risk_signals, queued_at`
+
+func scanReview(row pgx.Row) (model.Review, error) {
+	var rv model.Review
+	err := row.Scan(&rv.Order, &rv.Warnings, &rv.Risk.Score, &rv.Risk.Signals, &rv.QueuedAt)
+	return rv, err
+}
+
+// ListReviews — страница очереди проверки по order_uid; after — order_uid последнего заказа
+// предыдущей страницы.
+func (r *Repo) ListReviews(ctx context.Context, after string, limit int) ([]model.Review, error) {
+	rows, err := r.Pool.Query(ctx, `SELECT `+reviewColumns+` FROM review_queue WHERE order_uid > $1 ORDER BY order_uid LIMIT $2`, after, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []model.Review{}
+	for rows.Next() {
+		rv, err := scanReview(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, rv)
+	}
+	return out, rows.Err()
+}
+
+// ApproveReview переносит отложенный заказ в orders вместе с его предупреждениями и оценкой;
+// found=false — в очереди такого заказа нет.
+func (r *Repo) ApproveReview(ctx context.Context, orderUID string) (model.Order, bool, error) {
+	tx, err := r.Pool.Begin(ctx)
+	if err != nil {
+		return model.Order{}, false, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	rv, err := scanReview(tx.QueryRow(ctx, `DELETE FROM review_queue WHERE order_uid=$1 RETURNING `+reviewColumns, orderUID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.Order{}, false, nil
+		}
+		return model.Order{}, false, fmt.Errorf("review %s: %w", orderUID, err)
+	}
+	o := rv.Order
+	o.Warnings, o.Risk = rv.Warnings, &rv.Risk
+	if len(o.Warnings) == 0 {
+		o.Warnings = nil
+	}
+	o.UpdatedAt = time.Now().UTC().Truncate(time.Microsecond)
+	if err := writeOrder(ctx, tx, o); err != nil {
+		return model.Order{}, false, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return model.Order{}, false, err
+	}
+	return o, true, nil
+}
+
+// RiskHistory — последние limit заказов покупателя для оценки риска, новые первыми: записанные
+// и отложенные на проверку (отложенная версия важнее записанной). Только шапки — order_uid,
+// date_created, сумма с валютой, индекс и регион доставки; товары не читаются.
+func (r *Repo) RiskHistory(ctx context.Context, customerID string, limit int) ([]model.Order, error) {
+	if customerID == "" || limit <= 0 {
+		return []model.Order{}, nil
+	}
+	rows, err := r.Pool.Query(ctx, `
+		SELECT order_uid, date_created, currency, amount, zip, region FROM (
+			SELECT DISTINCT ON (order_uid) * FROM (
+				(SELECT o.order_uid, o.date_created, COALESCE(p.currency, '') AS currency, COALESCE(p.amount, 0) AS amount,
+				        COALESCE(d.zip, '') AS zip, COALESCE(d.region, '') AS region, 1 AS src
+				 FROM orders o
+				 LEFT JOIN payments p ON p.order_uid = o.order_uid
+				 LEFT JOIN deliveries d ON d.order_uid = o.order_uid
+				 WHERE o.customer_id = $1
+				 ORDER BY o.date_created DESC, o.order_uid
+				 LIMIT $2)
+				UNION ALL
+				SELECT q.order_uid, (q.payload->>'date_created')::timestamptz,
+				       COALESCE(q.payload->'payment'->>'currency', ''), COALESCE((q.payload->'payment'->>'amount')::int, 0),
+				       COALESCE(q.payload->'delivery'->>'zip', ''), COALESCE(q.payload->'delivery'->>'region', ''), 0
+				FROM review_queue q
+				WHERE q.customer_id = $1
+			) u
+			ORDER BY order_uid, src
+		) h
+		ORDER BY date_created DESC, order_uid
+		LIMIT $2`, customerID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []model.Order{}
+	for rows.Next() {
+		o := model.Order{CustomerID: customerID}
+		if err := rows.Scan(&o.OrderUID, &o.DateCreated, &o.Payment.Currency, &o.Payment.Amount, &o.Delivery.Zip, &o.Delivery.Region); err != nil {
+			return nil, err
+		}
+		out = append(out, o)
+	}
+	return out, rows.Err()
+}
+
+// RejectReview убирает заказ из очереди проверки, не записывая его; false — такого заказа там нет.
+func (r *Repo) RejectReview(ctx context.Context, orderUID string) (bool, error) {
+	tag, err := r.Pool.Exec(ctx, `DELETE FROM review_queue WHERE order_uid=$1`, orderUID)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
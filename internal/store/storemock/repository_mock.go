@@ -7,6 +7,7 @@ package storemock
 import (
 	context "context"
 	model "demo/orders/internal/model"
+	store "demo/orders/internal/store"
 	reflect "reflect"
 
 	gomock "github.com/golang/mock/gomock"
@@ -35,6 +36,37 @@ func (m *MockRepository) EXPECT() *MockRepositoryMockRecorder {
 	return m.recorder
 }
 
+// ApproveReview mocks base method.
+func (m *MockRepository) ApproveReview(arg0 context.Context, arg1 string) (model.Order, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ApproveReview", arg0, arg1)
+	ret0, _ := ret[0].(model.Order)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// ApproveReview indicates an expected call of ApproveReview.
+func (mr *MockRepositoryMockRecorder) ApproveReview(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ApproveReview", reflect.TypeOf((*MockRepository)(nil).ApproveReview), arg0, arg1)
+}
+
+// CustomerOrders mocks base method.
+func (m *MockRepository) CustomerOrders(arg0 context.Context, arg1 []string, arg2 int) (map[string][]model.Order, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CustomerOrders", arg0, arg1, arg2)
+	ret0, _ := ret[0].(map[string][]model.Order)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CustomerOrders indicates an expected call of CustomerOrders.
+func (mr *MockRepositoryMockRecorder) CustomerOrders(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CustomerOrders", reflect.TypeOf((*MockRepository)(nil).CustomerOrders), arg0, arg1, arg2)
+}
+
 // GetOrder mocks base method.
 func (m *MockRepository) GetOrder(arg0 context.Context, arg1 string) (model.Order, bool, error) {
 	m.ctrl.T.Helper()
@@ -51,6 +83,51 @@ func (mr *MockRepositoryMockRecorder) GetOrder(arg0, arg1 interface{}) *gomock.C
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOrder", reflect.TypeOf((*MockRepository)(nil).GetOrder), arg0, arg1)
 }
 
+// GetOrders mocks base method.
+func (m *MockRepository) GetOrders(arg0 context.Context, arg1 []string) ([]model.Order, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOrders", arg0, arg1)
+	ret0, _ := ret[0].([]model.Order)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOrders indicates an expected call of GetOrders.
+func (mr *MockRepositoryMockRecorder) GetOrders(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOrders", reflect.TypeOf((*MockRepository)(nil).GetOrders), arg0, arg1)
+}
+
+// ListOrders mocks base method.
+func (m *MockRepository) ListOrders(arg0 context.Context, arg1 store.OrderFilter, arg2 string, arg3 int) ([]model.Order, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListOrders", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].([]model.Order)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListOrders indicates an expected call of ListOrders.
+func (mr *MockRepositoryMockRecorder) ListOrders(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListOrders", reflect.TypeOf((*MockRepository)(nil).ListOrders), arg0, arg1, arg2, arg3)
+}
+
+// ListReviews mocks base method.
+func (m *MockRepository) ListReviews(arg0 context.Context, arg1 string, arg2 int) ([]model.Review, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListReviews", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]model.Review)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListReviews indicates an expected call of ListReviews.
+func (mr *MockRepositoryMockRecorder) ListReviews(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListReviews", reflect.TypeOf((*MockRepository)(nil).ListReviews), arg0, arg1, arg2)
+}
+
 // LoadAllOrders mocks base method.
 func (m *MockRepository) LoadAllOrders(arg0 context.Context) ([]model.Order, error) {
 	m.ctrl.T.Helper()
@@ -66,6 +143,111 @@ func (mr *MockRepositoryMockRecorder) LoadAllOrders(arg0 interface{}) *gomock.Ca
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LoadAllOrders", reflect.TypeOf((*MockRepository)(nil).LoadAllOrders), arg0)
 }
 
+// OrderRisk mocks base method.
+func (m *MockRepository) OrderRisk(arg0 context.Context, arg1 string) (*model.Risk, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "OrderRisk", arg0, arg1)
+	ret0, _ := ret[0].(*model.Risk)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// OrderRisk indicates an expected call of OrderRisk.
+func (mr *MockRepositoryMockRecorder) OrderRisk(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OrderRisk", reflect.TypeOf((*MockRepository)(nil).OrderRisk), arg0, arg1)
+}
+
+// OrderWarnings mocks base method.
+func (m *MockRepository) OrderWarnings(arg0 context.Context, arg1 string) ([]model.Warning, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "OrderWarnings", arg0, arg1)
+	ret0, _ := ret[0].([]model.Warning)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// OrderWarnings indicates an expected call of OrderWarnings.
+func (mr *MockRepositoryMockRecorder) OrderWarnings(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OrderWarnings", reflect.TypeOf((*MockRepository)(nil).OrderWarnings), arg0, arg1)
+}
+
+// QueueReview mocks base method.
+func (m *MockRepository) QueueReview(arg0 context.Context, arg1 model.Order) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "QueueReview", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// QueueReview indicates an expected call of QueueReview.
+func (mr *MockRepositoryMockRecorder) QueueReview(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueueReview", reflect.TypeOf((*MockRepository)(nil).QueueReview), arg0, arg1)
+}
+
+// RejectReview mocks base method.
+func (m *MockRepository) RejectReview(arg0 context.Context, arg1 string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RejectReview", arg0, arg1)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RejectReview indicates an expected call of RejectReview.
+func (mr *MockRepositoryMockRecorder) RejectReview(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RejectReview", reflect.TypeOf((*MockRepository)(nil).RejectReview), arg0, arg1)
+}
+
+// RiskHistory mocks base method.
+func (m *MockRepository) RiskHistory(arg0 context.Context, arg1 string, arg2 int) ([]model.Order, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RiskHistory", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]model.Order)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RiskHistory indicates an expected call of RiskHistory.
+func (mr *MockRepositoryMockRecorder) RiskHistory(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RiskHistory", reflect.TypeOf((*MockRepository)(nil).RiskHistory), arg0, arg1, arg2)
+}
+
+// StreamOrders mocks base method.
+func (m *MockRepository) StreamOrders(arg0 context.Context, arg1 store.OrderFilter, arg2 func(model.Order) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StreamOrders", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// StreamOrders indicates an expected call of StreamOrders.
+func (mr *MockRepositoryMockRecorder) StreamOrders(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StreamOrders", reflect.TypeOf((*MockRepository)(nil).StreamOrders), arg0, arg1, arg2)
+}
+
+// UpdateOrder mocks base method.
+func (m *MockRepository) UpdateOrder(arg0 context.Context, arg1 string, arg2 store.UpdateFunc) (model.Order, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateOrder", arg0, arg1, arg2)
+	ret0, _ := ret[0].(model.Order)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateOrder indicates an expected call of UpdateOrder.
+func (mr *MockRepositoryMockRecorder) UpdateOrder(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateOrder", reflect.TypeOf((*MockRepository)(nil).UpdateOrder), arg0, arg1, arg2)
+}
+
 // UpsertOrder mocks base method.
 func (m *MockRepository) UpsertOrder(arg0 context.Context, arg1 model.Order) error {
 	m.ctrl.T.Helper()
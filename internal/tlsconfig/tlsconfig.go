@@ -0,0 +1,152 @@
+// Package tlsconfig — TLS сервера из PEM-файлов с перечитыванием без рестарта и опциональной
+// проверкой клиентских сертификатов (mTLS). Новые сертификат и CA применяются к новым рукопожатиям,
+// открытые соединения доживают со старыми.
+package tlsconfig
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// Files — откуда читать сертификаты.
+type Files struct {
+	Cert, Key string
+	// ClientCA — CA клиентских сертификатов; пусто — mTLS выключен.
+	ClientCA string
+	// ClientAuth — насколько обязателен клиентский сертификат при ClientCA;
+	// по умолчанию tls.RequireAndVerifyClientCert.
+	ClientAuth tls.ClientAuthType
+}
+
+// Reloader держит текущую конфигурацию и подменяет её при Reload.
+type Reloader struct {
+	files      Files
+	nextProtos []string
+
+	mu      sync.RWMutex
+	cfg     *tls.Config
+	raw     [][]byte
+	modTime []time.Time
+}
+
+// Load читает файлы. nextProtos — протоколы ALPN ("h2", "http/1.1").
+func Load(files Files, nextProtos ...string) (*Reloader, error) {
+	if files.Cert == "" || files.Key == "" {
+		return nil, errors.New("tls: both cert and key files are required")
+	}
+	if files.ClientCA != "" && files.ClientAuth == tls.NoClientCert {
+		files.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	r := &Reloader{files: files, nextProtos: nextProtos}
+	if _, err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *Reloader) paths() []string {
+	p := []string{r.files.Cert, r.files.Key}
+	if r.files.ClientCA != "" {
+		p = append(p, r.files.ClientCA)
+	}
+	return p
+}
+
+// Reload перечитывает файлы. Содержимое не изменилось — changed=false; ошибка (например, ключ
+// ещё не дописан или не подходит к сертификату) — остаётся прежняя конфигурация.
+func (r *Reloader) Reload() (changed bool, err error) {
+	paths := r.paths()
+	raw := make([][]byte, len(paths))
+	mod := make([]time.Time, len(paths))
+	for i, p := range paths {
+		fi, err := os.Stat(p)
+		if err != nil {
+			return false, fmt.Errorf("tls: %w", err)
+		}
+		if raw[i], err = os.ReadFile(p); err != nil {
+			return false, fmt.Errorf("tls: %w", err)
+		}
+		mod[i] = fi.ModTime()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.modTime = mod
+	if r.raw != nil && sameContent(r.raw, raw) {
+		return false, nil
+	}
+	cert, err := tls.X509KeyPair(raw[0], raw[1])
+	if err != nil {
+		return false, fmt.Errorf("tls %s: %w", r.files.Cert, err)
+	}
+	cfg := &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{cert},
+		NextProtos:   r.nextProtos,
+	}
+	if r.files.ClientCA != "" {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(raw[2]) {
+			return false, fmt.Errorf("tls %s: no certificates in PEM", r.files.ClientCA)
+		}
+		cfg.ClientCAs = pool
+		cfg.ClientAuth = r.files.ClientAuth
+	}
+	r.cfg, r.raw = cfg, raw
+	return true, nil
+}
+
+func sameContent(a, b [][]byte) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !bytes.Equal(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// Stale — изменился ли какой-нибудь из файлов с последнего Reload (по mtime).
+func (r *Reloader) Stale() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for i, p := range r.paths() {
+		fi, err := os.Stat(p)
+		if err != nil {
+			continue
+		}
+		if !fi.ModTime().Equal(r.modTime[i]) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Reloader) current() *tls.Config {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cfg
+}
+
+// Config — конфигурация для http.Server.TLSConfig: каждое рукопожатие получает
+// актуальные сертификат и CA.
+func (r *Reloader) Config() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		NextProtos: r.nextProtos,
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return &r.current().Certificates[0], nil
+		},
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			return r.current(), nil
+		},
+	}
+}
@@ -0,0 +1,203 @@
+// internal/tlsconfig/tlsconfig_test.go
+package tlsconfig_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"demo/orders/internal/tlsconfig"
+)
+
+type pair struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+	kpem []byte
+}
+
+// issue выпускает сертификат cn, подписанный parent (nil — самоподписанный CA).
+func issue(t *testing.T, cn string, parent *pair, usage x509.ExtKeyUsage) *pair {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+	}
+	signer, signKey := tmpl, key
+	if parent == nil {
+		tmpl.IsCA, tmpl.BasicConstraintsValid = true, true
+		tmpl.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature
+	} else {
+		signer, signKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signer, &key.PublicKey, signKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	kder, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	return &pair{
+		cert: cert, key: key,
+		pem:  pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		kpem: pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: kder}),
+	}
+}
+
+func write(t *testing.T, path string, b []byte, mtime time.Time) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(path, b, 0o600))
+	require.NoError(t, os.Chtimes(path, mtime, mtime))
+}
+
+// serve поднимает HTTPS-сервер, который отвечает версией протокола и CN клиентского сертификата.
+func serve(t *testing.T, cfg *tls.Config) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	srv := &http.Server{TLSConfig: cfg, ErrorLog: log.New(io.Discard, "", 0), Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Proto", r.Proto)
+		if len(r.TLS.PeerCertificates) > 0 {
+			w.Header().Set("X-Client", r.TLS.PeerCertificates[0].Subject.CommonName)
+		}
+	})}
+	go func() { _ = srv.ServeTLS(ln, "", "") }()
+	t.Cleanup(func() { _ = srv.Close() })
+	return "https://" + ln.Addr().String()
+}
+
+func client(ca *pair, cert *pair) *http.Client {
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	cfg := &tls.Config{RootCAs: roots}
+	if cert != nil {
+		c, _ := tls.X509KeyPair(cert.pem, cert.kpem)
+		// отдаём сертификат всегда, даже если сервер просит другой CA
+		cfg.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) { return &c, nil }
+	}
+	return &http.Client{Transport: &http.Transport{TLSClientConfig: cfg, ForceAttemptHTTP2: true}, Timeout: 5 * time.Second}
+}
+
+func serverCN(t *testing.T, c *http.Client, url string) (string, *http.Response) {
+	t.Helper()
+	resp, err := c.Get(url)
+	require.NoError(t, err)
+	resp.Body.Close()
+	return resp.TLS.PeerCertificates[0].Subject.CommonName, resp
+}
+
+func TestReloader_RotateAndMTLS(t *testing.T) {
+	ca := issue(t, "ca", nil, x509.ExtKeyUsageAny)
+	dir := t.TempDir()
+	files := tlsconfig.Files{
+		Cert:     filepath.Join(dir, "tls.crt"),
+		Key:      filepath.Join(dir, "tls.key"),
+		ClientCA: filepath.Join(dir, "ca.crt"),
+	}
+	t0 := time.Now().Add(-time.Minute)
+	v1 := issue(t, "v1", ca, x509.ExtKeyUsageServerAuth)
+	write(t, files.Cert, v1.pem, t0)
+	write(t, files.Key, v1.kpem, t0)
+	write(t, files.ClientCA, ca.pem, t0)
+
+	r, err := tlsconfig.Load(files, "h2", "http/1.1")
+	require.NoError(t, err)
+	url := serve(t, r.Config())
+	alice := issue(t, "alice", ca, x509.ExtKeyUsageClientAuth)
+
+	// без клиентского сертификата рукопожатие не проходит
+	_, err = client(ca, nil).Get(url)
+	require.Error(t, err)
+
+	cn, resp := serverCN(t, client(ca, alice), url)
+	require.Equal(t, "v1", cn)
+	require.Equal(t, "alice", resp.Header.Get("X-Client"))
+	require.Equal(t, "HTTP/2.0", resp.Header.Get("X-Proto"))
+
+	require.False(t, r.Stale())
+	changed, err := r.Reload()
+	require.NoError(t, err)
+	require.False(t, changed)
+
+	// ротация: новые рукопожатия получают новый сертификат
+	v2 := issue(t, "v2", ca, x509.ExtKeyUsageServerAuth)
+	write(t, files.Cert, v2.pem, t0.Add(time.Second))
+	write(t, files.Key, v2.kpem, t0.Add(time.Second))
+	require.True(t, r.Stale())
+	changed, err = r.Reload()
+	require.NoError(t, err)
+	require.True(t, changed)
+	cn, _ = serverCN(t, client(ca, alice), url)
+	require.Equal(t, "v2", cn)
+
+	// ключ не от того сертификата (например, дописан только один файл) — остаётся v2
+	write(t, files.Key, v1.kpem, t0.Add(2*time.Second))
+	_, err = r.Reload()
+	require.Error(t, err)
+	cn, _ = serverCN(t, client(ca, alice), url)
+	require.Equal(t, "v2", cn)
+}
+
+func TestReloader_OptionalClientCert(t *testing.T) {
+	ca := issue(t, "ca", nil, x509.ExtKeyUsageAny)
+	srvCert := issue(t, "server", ca, x509.ExtKeyUsageServerAuth)
+	dir := t.TempDir()
+	files := tlsconfig.Files{
+		Cert:       filepath.Join(dir, "tls.crt"),
+		Key:        filepath.Join(dir, "tls.key"),
+		ClientCA:   filepath.Join(dir, "ca.crt"),
+		ClientAuth: tls.VerifyClientCertIfGiven,
+	}
+	write(t, files.Cert, srvCert.pem, time.Now())
+	write(t, files.Key, srvCert.kpem, time.Now())
+	write(t, files.ClientCA, ca.pem, time.Now())
+
+	r, err := tlsconfig.Load(files, "http/1.1")
+	require.NoError(t, err)
+	url := serve(t, r.Config())
+
+	_, resp := serverCN(t, client(ca, nil), url)
+	require.Empty(t, resp.Header.Get("X-Client"))
+	require.Equal(t, "HTTP/1.1", resp.Header.Get("X-Proto"))
+
+	// сертификат от чужого CA не принимается и в optional
+	other := issue(t, "other-ca", nil, x509.ExtKeyUsageAny)
+	_, err = client(ca, issue(t, "mallory", other, x509.ExtKeyUsageClientAuth)).Get(url)
+	require.Error(t, err)
+}
+
+func TestLoad_Errors(t *testing.T) {
+	_, err := tlsconfig.Load(tlsconfig.Files{Cert: "x"})
+	require.Error(t, err)
+
+	dir := t.TempDir()
+	files := tlsconfig.Files{Cert: filepath.Join(dir, "tls.crt"), Key: filepath.Join(dir, "tls.key"), ClientCA: filepath.Join(dir, "ca.crt")}
+	c := issue(t, "self", nil, x509.ExtKeyUsageServerAuth)
+	write(t, files.Cert, c.pem, time.Now())
+	write(t, files.Key, c.kpem, time.Now())
+	write(t, files.ClientCA, []byte("not pem"), time.Now())
+	_, err = tlsconfig.Load(files)
+	require.Error(t, err)
+}
@@ -0,0 +1,34 @@
+package tracing
+
+import (
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// HTTP — серверный спан на запрос, продолжающий trace context из заголовков клиента.
+// route возвращает шаблон маршрута ("GET /order/") — он и есть имя спана; статус ответа
+// ставит на спан access-лог (logging.AccessLog), который стоит внутри.
+func HTTP(route func(*http.Request) string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+			name := route(r)
+			if name == "" {
+				name = r.Method
+			}
+			ctx, span := Tracer().Start(ctx, name,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(
+					attribute.String("http.request.method", r.Method),
+					attribute.String("http.route", name),
+					attribute.String("url.path", r.URL.Path),
+				))
+			defer span.End()
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
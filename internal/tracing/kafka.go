@@ -0,0 +1,74 @@
+package tracing
+
+import (
+	"context"
+
+	"github.com/segmentio/kafka-go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// kafkaCarrier — propagation.TextMapCarrier поверх заголовков сообщения.
+type kafkaCarrier struct{ headers *[]kafka.Header }
+
+func (c kafkaCarrier) Get(key string) string {
+	for _, h := range *c.headers {
+		if h.Key == key {
+			return string(h.Value)
+		}
+	}
+	return ""
+}
+
+func (c kafkaCarrier) Set(key, value string) {
+	for i, h := range *c.headers {
+		if h.Key == key {
+			(*c.headers)[i].Value = []byte(value)
+			return
+		}
+	}
+	*c.headers = append(*c.headers, kafka.Header{Key: key, Value: []byte(value)})
+}
+
+func (c kafkaCarrier) Keys() []string {
+	keys := make([]string, 0, len(*c.headers))
+	for _, h := range *c.headers {
+		keys = append(keys, h.Key)
+	}
+	return keys
+}
+
+// InjectKafka кладёт trace context из ctx в заголовки сообщения (traceparent, tracestate, baggage).
+func InjectKafka(ctx context.Context, m *kafka.Message) {
+	otel.GetTextMapPropagator().Inject(ctx, kafkaCarrier{&m.Headers})
+}
+
+// ExtractKafka продолжает в ctx трейс, пришедший в заголовках сообщения.
+func ExtractKafka(ctx context.Context, m kafka.Message) context.Context {
+	return otel.GetTextMapPropagator().Extract(ctx, kafkaCarrier{&m.Headers})
+}
+
+// StartPublish открывает спан отправки сообщения и кладёт его trace context в заголовки m:
+// консьюмер продолжит этот же трейс. Спан закрывает вызывающий, когда запись завершится.
+func StartPublish(ctx context.Context, topic string, m *kafka.Message) (context.Context, trace.Span) {
+	ctx, span := Tracer().Start(ctx, "orders publish",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			attribute.String("messaging.system", "kafka"),
+			attribute.String("messaging.destination.name", topic),
+			attribute.String("messaging.kafka.message.key", string(m.Key)),
+		))
+	InjectKafka(ctx, m)
+	return ctx, span
+}
+
+// EndPublish закрывает спан отправки, отмечая ошибку записи.
+func EndPublish(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,82 @@
+// Package tracing — OpenTelemetry: провайдер трейсов с экспортом по OTLP/HTTP или в файл,
+// W3C trace context в заголовках HTTP и сообщений Kafka, спаны HTTP-запросов и запросов к БД.
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ScopeName — имя инструментирования, под которым сервис создаёт спаны.
+const ScopeName = "demo/orders"
+
+// Экспортёры.
+const (
+	ExporterNone = "none"
+	ExporterOTLP = "otlp" // OTLP/HTTP; адрес и заголовки — из стандартных OTEL_EXPORTER_OTLP_*
+	ExporterFile = "file" // JSON-строка на спан — для локальной отладки и тестов
+)
+
+// Config — куда и под каким именем отправлять трейсы.
+type Config struct {
+	Exporter string // none (по умолчанию) | otlp | file
+	File     string // для file: путь, "-" — stdout
+	Service  string // service.name
+}
+
+// Setup настраивает глобальные провайдер и пропагатор; вернувшаяся функция дописывает
+// накопленные спаны и закрывает экспортёр. Пропагатор W3C ставится и при выключенном
+// экспорте: trace context тогда просто проходит через сервис дальше.
+func Setup(ctx context.Context, cfg Config) (shutdown func(context.Context) error, err error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var exp sdktrace.SpanExporter
+	closeFile := func() error { return nil }
+	switch cfg.Exporter {
+	case "", ExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case ExporterOTLP:
+		if exp, err = otlptracehttp.New(ctx); err != nil {
+			return nil, fmt.Errorf("otlp exporter: %w", err)
+		}
+	case ExporterFile:
+		var w io.Writer = os.Stdout
+		if cfg.File != "" && cfg.File != "-" {
+			f, err := os.OpenFile(cfg.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+			if err != nil {
+				return nil, fmt.Errorf("traces file: %w", err)
+			}
+			w, closeFile = f, f.Close
+		}
+		if exp, err = stdouttrace.New(stdouttrace.WithWriter(w)); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown OTEL_TRACES_EXPORTER=%q (want none|otlp|file)", cfg.Exporter)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(attribute.String("service.name", cfg.Service)))
+	if err != nil {
+		return nil, err
+	}
+	tp := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exp), sdktrace.WithResource(res))
+	otel.SetTracerProvider(tp)
+	return func(ctx context.Context) error {
+		return errors.Join(tp.Shutdown(ctx), closeFile())
+	}, nil
+}
+
+// Tracer — трейсер сервиса из глобального провайдера.
+func Tracer() trace.Tracer { return otel.Tracer(ScopeName) }
@@ -0,0 +1,146 @@
+// internal/tracing/tracing_test.go
+package tracing_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"demo/orders/internal/tracing"
+)
+
+// record ставит глобальный провайдер, который копит законченные спаны в памяти.
+func record(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	shutdown, err := tracing.Setup(context.Background(), tracing.Config{Exporter: tracing.ExporterNone})
+	require.NoError(t, err)
+	require.NoError(t, shutdown(context.Background()))
+
+	rec := tracetest.NewSpanRecorder()
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)))
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+	return rec
+}
+
+func attr(s sdktrace.ReadOnlySpan, key string) attribute.Value {
+	for _, kv := range s.Attributes() {
+		if string(kv.Key) == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestKafka_PublishToConsume(t *testing.T) {
+	rec := record(t)
+
+	m := kafka.Message{Key: []byte("b563feb7b2b84b6test"), Headers: []kafka.Header{{Key: "source", Value: []byte("test")}}}
+	_, span := tracing.StartPublish(context.Background(), "orders", &m)
+	tracing.EndPublish(span, nil)
+	require.Len(t, m.Headers, 2)
+	require.Equal(t, "traceparent", m.Headers[1].Key)
+
+	// консьюмер продолжает тот же трейс
+	ctx := tracing.ExtractKafka(context.Background(), m)
+	_, consume := tracing.Tracer().Start(ctx, "orders consume")
+	consume.End()
+
+	spans := rec.Ended()
+	require.Len(t, spans, 2)
+	pub, con := spans[0], spans[1]
+	require.Equal(t, "orders publish", pub.Name())
+	require.Equal(t, trace.SpanKindProducer, pub.SpanKind())
+	require.Equal(t, "orders", attr(pub, "messaging.destination.name").AsString())
+	require.Equal(t, pub.SpanContext().TraceID(), con.SpanContext().TraceID())
+	require.Equal(t, pub.SpanContext().SpanID(), con.Parent().SpanID())
+	require.True(t, con.Parent().IsRemote())
+
+	// повторная отправка того же сообщения не плодит заголовки
+	_, span = tracing.StartPublish(context.Background(), "orders", &m)
+	tracing.EndPublish(span, context.DeadlineExceeded)
+	require.Len(t, m.Headers, 2)
+	require.Equal(t, codes.Error, rec.Ended()[2].Status().Code)
+}
+
+func TestHTTP_ContinuesClientTrace(t *testing.T) {
+	rec := record(t)
+
+	var inner trace.SpanContext
+	h := tracing.HTTP(func(*http.Request) string { return "GET /order/{id}" })(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		inner = trace.SpanContextFromContext(r.Context())
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/order/abc", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := rec.Ended()
+	require.Len(t, spans, 1)
+	s := spans[0]
+	require.Equal(t, "GET /order/{id}", s.Name())
+	require.Equal(t, trace.SpanKindServer, s.SpanKind())
+	require.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", s.SpanContext().TraceID().String())
+	require.Equal(t, "00f067aa0ba902b7", s.Parent().SpanID().String())
+	require.Equal(t, "/order/abc", attr(s, "url.path").AsString())
+	require.Equal(t, s.SpanContext().SpanID(), inner.SpanID())
+
+	// без шаблона маршрута спан называется методом
+	h = tracing.HTTP(func(*http.Request) string { return "" })(http.NotFoundHandler())
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/nope", nil))
+	require.Equal(t, "POST", rec.Ended()[1].Name())
+	require.False(t, rec.Ended()[1].Parent().IsValid())
+}
+
+func TestDBTracer(t *testing.T) {
+	rec := record(t)
+
+	var db tracing.DBTracer
+	ctx := db.TraceQueryStart(context.Background(), nil, pgx.TraceQueryStartData{SQL: "update orders set locale = $1 where order_uid = $2"})
+	db.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{CommandTag: pgconn.NewCommandTag("UPDATE 2")})
+	ctx = db.TraceQueryStart(context.Background(), nil, pgx.TraceQueryStartData{SQL: " select 1"})
+	db.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{Err: pgx.ErrNoRows})
+
+	spans := rec.Ended()
+	require.Len(t, spans, 2)
+	require.Equal(t, "db UPDATE", spans[0].Name())
+	require.Equal(t, trace.SpanKindClient, spans[0].SpanKind())
+	require.Equal(t, int64(2), attr(spans[0], "db.response.rows_affected").AsInt64())
+	require.Equal(t, "db SELECT", spans[1].Name())
+	require.Equal(t, codes.Error, spans[1].Status().Code)
+}
+
+func TestSetup_FileExporter(t *testing.T) {
+	prev := otel.GetTracerProvider()
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+
+	path := filepath.Join(t.TempDir(), "traces.jsonl")
+	shutdown, err := tracing.Setup(context.Background(), tracing.Config{Exporter: tracing.ExporterFile, File: path, Service: "orders-test"})
+	require.NoError(t, err)
+	_, span := tracing.Tracer().Start(context.Background(), "orders consume")
+	span.End()
+	require.NoError(t, shutdown(context.Background()))
+
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Contains(t, string(b), `"Name":"orders consume"`)
+	require.Contains(t, string(b), `"orders-test"`)
+	require.Equal(t, 1, strings.Count(strings.TrimSpace(string(b)), "\n")+1)
+
+	_, err = tracing.Setup(context.Background(), tracing.Config{Exporter: "jaeger"})
+	require.Error(t, err)
+}
@@ -0,0 +1,180 @@
+package validate
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"demo/orders/internal/model"
+)
+
+// CodeMismatch — суммы заказа не сходятся между собой.
+const CodeMismatch = "mismatch"
+
+// Severity — что делать с заказом, нарушившим необязательную проверку.
+type Severity string
+
+const (
+	SeverityOff    Severity = "off"    // проверка выключена
+	SeverityWarn   Severity = "warn"   // заказ принимается, нарушение — предупреждение
+	SeverityReject Severity = "reject" // заказ отклоняется, как при обычной ошибке валидации
+)
+
+// Check — настройка сверки: серьёзность и допуск расхождения в единицах суммы.
+type Check struct {
+	Severity  Severity
+	Tolerance int
+}
+
+// ParseCheck разбирает "severity[:tolerance]": "reject", "warn:2", "off"; пусто — выключено.
+func ParseCheck(s string) (Check, error) {
+	sev, tol, hasTol := strings.Cut(s, ":")
+	c := Check{Severity: Severity(sev)}
+	switch c.Severity {
+	case "":
+		c.Severity = SeverityOff
+	case SeverityOff, SeverityWarn, SeverityReject:
+	default:
+		return Check{}, fmt.Errorf("unknown severity %q (want off|warn|reject)", sev)
+	}
+	if hasTol {
+		n, err := strconv.Atoi(tol)
+		if err != nil || n < 0 {
+			return Check{}, fmt.Errorf("tolerance %q: want a non-negative integer", tol)
+		}
+		c.Tolerance = n
+	}
+	return c, nil
+}
+
+// Financial — сверки сумм заказа между собой. Нулевое значение — все выключены.
+type Financial struct {
+	Amount     Check // payment.amount = goods_total + delivery_cost + custom_fee
+	GoodsTotal Check // payment.goods_total = сумма items[].total_price
+	ItemTotal  Check // items[].total_price = price со скидкой sale, с округлением
+}
+
+// Options — настройки проверки сверх встроенной ValidateOrder.
+type Options struct {
+	Financial Financial
+	// Rules — декларативные наборы правил; заказ, для которого нашёлся набор, проверяется им
+	// вместо ValidateOrder. nil — только встроенные правила.
+	Rules *Rules
+	// Warn — поля, нарушения которых — предупреждения, а не отказ: заказ сохраняется с пометкой.
+	// items[*].name — поле любого товара.
+	Warn []string
+}
+
+// hardFields — поля сверок checkItemRefs: Validate отклоняет их нарушения при любом Warn,
+// так что в списке они только вводили бы в заблуждение.
+var hardFields = map[string]string{
+	// chrt_id входит в первичный ключ items: пропущенный повтор роняет запись в БД
+	"items[*].chrt_id":      "duplicates would break the items primary key",
+	"items[*].rid":          "duplicate rids are always rejected",
+	"items[*].track_number": "a foreign track_number is always rejected",
+}
+
+// ParseWarn разбирает список полей через запятую для Options.Warn: "delivery.phone,items[*].sale".
+func ParseWarn(s string) ([]string, error) {
+	var out []string
+	for _, f := range strings.Split(s, ",") {
+		if f = strings.TrimSpace(f); f == "" {
+			continue
+		}
+		if _, err := fieldType(reflect.TypeOf(model.Order{}), f); err != nil {
+			return nil, fmt.Errorf("%s: %w", f, err)
+		}
+		if why, ok := hardFields[f]; ok {
+			return nil, fmt.Errorf("%s: cannot be soft, %s", f, why)
+		}
+		out = append(out, f)
+	}
+	return out, nil
+}
+
+var reIndex = regexp.MustCompile(`\[\d+\]`)
+
+// soft — попадает ли поле нарушения (items[3].sale) под Options.Warn.
+func (opts Options) soft(field string) bool {
+	return len(opts.Warn) > 0 && slices.Contains(opts.Warn, reIndex.ReplaceAllString(field, "[*]"))
+}
+
+// Validate проверяет заказ набором из opts.Rules (или ValidateOrder) и сверками сумм.
+// Нарушения с severity reject попадают в *ValidationError, с warn и в полях opts.Warn —
+// возвращаются отдельно как предупреждения. Сверки товаров (checkItemRefs) мягкими не бывают.
+func Validate(o model.Order, opts Options) (warnings []FieldError, err error) {
+	var errs, warns, refs violations
+	checkItemRefs(o, &refs)
+	if s, ok := opts.Rules.set(o); ok {
+		s.check(o, &errs, &warns)
+		errs = append(errs, refs...)
+	} else if base := ValidateOrder(o); base != nil {
+		errs = append(errs, Fields(base)...) // checkItemRefs в нём уже есть
+	}
+	checkFinancial(o, opts.Financial, &errs, &warns)
+	if len(opts.Warn) > 0 {
+		hard := errs[:0]
+		for _, fe := range errs {
+			isRef := slices.ContainsFunc(refs, func(r FieldError) bool { return r.Pointer == fe.Pointer && r.Code == fe.Code })
+			if opts.soft(fe.Field) && !isRef {
+				warns = append(warns, fe)
+			} else {
+				hard = append(hard, fe)
+			}
+		}
+		errs = hard
+	}
+	return warns, errs.err()
+}
+
+func checkFinancial(o model.Order, f Financial, errs, warns *violations) {
+	report := func(c Check, field string, got, want int, msg string) {
+		if diff(got, want) <= c.Tolerance {
+			return
+		}
+		dst := errs
+		if c.Severity == SeverityWarn {
+			dst = warns
+		}
+		dst.add(field, CodeMismatch, fmt.Sprintf("%s (%d), got %d", msg, want, got), got)
+	}
+
+	p := o.Payment
+	if on(f.Amount) {
+		report(f.Amount, "payment.amount", p.Amount, p.GoodsTotal+p.DeliveryCost+p.CustomFee,
+			"must equal goods_total + delivery_cost + custom_fee")
+	}
+	if on(f.GoodsTotal) && len(o.Items) > 0 {
+		sum := 0
+		for _, it := range o.Items {
+			sum += it.TotalPrice
+		}
+		report(f.GoodsTotal, "payment.goods_total", p.GoodsTotal, sum, "must equal the sum of items[].total_price")
+	}
+	if on(f.ItemTotal) {
+		for i, it := range o.Items {
+			if it.Sale < 0 || it.Sale > 100 {
+				continue // уже отклонено ValidateOrder
+			}
+			report(f.ItemTotal, item(i, "total_price"), it.TotalPrice, discounted(it.Price, it.Sale),
+				"must equal price with sale applied")
+		}
+	}
+}
+
+func on(c Check) bool { return c.Severity == SeverityWarn || c.Severity == SeverityReject }
+
+// discounted — цена со скидкой sale%, округлённая до целого (половина — вверх).
+func discounted(price, sale int) int {
+	return (price*(100-sale) + 50) / 100
+}
+
+func diff(a, b int) int {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
@@ -0,0 +1,139 @@
+// internal/validate/financial_test.go
+package validate_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"demo/orders/internal/model"
+	"demo/orders/internal/validate"
+)
+
+// sample — заказ из задания: суммы сходятся (1500 + 317 = 1817, 453 со скидкой 30% = 317).
+func sample() model.Order {
+	return model.Order{
+		OrderUID: "b563feb7b2b84b6test", TrackNumber: "WBILMTESTTRACK", Entry: "WBIL", CustomerID: "test",
+		DateCreated: time.Now(),
+		Payment:     model.Payment{Currency: "USD", Amount: 1817, DeliveryCost: 1500, GoodsTotal: 317, PaymentDT: time.Now().Unix()},
+		Items: []model.Item{{
+			ChrtID: 9934930, TrackNumber: "WBILMTESTTRACK", Price: 453, Name: "Mascaras",
+			Sale: 30, Size: "0", TotalPrice: 317, NmID: 2389212, Status: 202,
+		}},
+	}
+}
+
+func TestParseCheck(t *testing.T) {
+	for in, want := range map[string]validate.Check{
+		"":         {Severity: validate.SeverityOff},
+		"off":      {Severity: validate.SeverityOff},
+		"reject":   {Severity: validate.SeverityReject},
+		"warn:2":   {Severity: validate.SeverityWarn, Tolerance: 2},
+		"reject:0": {Severity: validate.SeverityReject},
+	} {
+		got, err := validate.ParseCheck(in)
+		require.NoError(t, err, in)
+		require.Equal(t, want, got, in)
+	}
+	for _, in := range []string{"strict", "warn:", "warn:-1", "warn:x"} {
+		_, err := validate.ParseCheck(in)
+		require.Error(t, err, in)
+	}
+}
+
+func TestValidate_Financial(t *testing.T) {
+	reject := validate.Check{Severity: validate.SeverityReject}
+	all := validate.Options{Financial: validate.Financial{Amount: reject, GoodsTotal: reject, ItemTotal: reject}}
+
+	warns, err := validate.Validate(sample(), all)
+	require.NoError(t, err)
+	require.Empty(t, warns)
+
+	// по умолчанию сверки выключены
+	o := sample()
+	o.Payment.Amount = 1
+	o.Items[0].TotalPrice = 300
+	warns, err = validate.Validate(o, validate.Options{})
+	require.NoError(t, err)
+	require.Empty(t, warns)
+
+	_, err = validate.Validate(o, all)
+	fields := validate.Fields(err)
+	require.Len(t, fields, 3)
+	require.Equal(t, validate.FieldError{
+		Field: "payment.amount", Pointer: "/payment/amount", Code: validate.CodeMismatch,
+		Message: "must equal goods_total + delivery_cost + custom_fee (1817), got 1", Value: 1,
+	}, fields[0])
+	require.Equal(t, "/payment/goods_total", fields[1].Pointer)
+	require.Equal(t, "/items/0/total_price", fields[2].Pointer)
+
+	// warn: заказ проходит, нарушения — предупреждения; допуск гасит мелкие расхождения
+	opts := validate.Options{Financial: validate.Financial{
+		Amount:     validate.Check{Severity: validate.SeverityWarn},
+		GoodsTotal: validate.Check{Severity: validate.SeverityReject, Tolerance: 20},
+		ItemTotal:  validate.Check{Severity: validate.SeverityWarn, Tolerance: 17},
+	}}
+	warns, err = validate.Validate(o, opts)
+	require.NoError(t, err)
+	require.Len(t, warns, 1)
+	require.Equal(t, "/payment/amount", warns[0].Pointer)
+
+	// обычные ошибки остаются ошибками и при одних предупреждениях сверок
+	o.Payment.Currency = ""
+	warns, err = validate.Validate(o, opts)
+	require.Error(t, err)
+	require.Len(t, validate.Fields(err), 1)
+	require.Len(t, warns, 1)
+}
+
+func TestValidate_Warn(t *testing.T) {
+	warn, err := validate.ParseWarn(" delivery.phone, items[*].sale ,")
+	require.NoError(t, err)
+	require.Equal(t, []string{"delivery.phone", "items[*].sale"}, warn)
+	for _, in := range []string{"delivery.fax", "items.sale", "payment[*].amount", "items[*].chrt_id", "items[*].rid", "items[*].track_number"} {
+		_, err := validate.ParseWarn(in)
+		require.Error(t, err, in)
+	}
+
+	o := sample()
+	o.Delivery.Phone = "call me"
+	o.Items = append(o.Items, o.Items[0])
+	o.Items[1].ChrtID, o.Items[1].Sale = 9934931, 120
+	opts := validate.Options{Warn: warn}
+	warns, err := validate.Validate(o, opts)
+	require.NoError(t, err)
+	require.Len(t, warns, 2)
+	require.Equal(t, "/delivery/phone", warns[0].Pointer)
+	require.Equal(t, "/items/1/sale", warns[1].Pointer)
+
+	// жёсткие нарушения по-прежнему отклоняют заказ
+	o.Payment.Currency = "XYZ"
+	warns, err = validate.Validate(o, opts)
+	require.Len(t, warns, 2)
+	require.Equal(t, []string{"/payment/currency"}, pointers(validate.Fields(err)))
+
+	_, err = validate.ParseWarn("delivery.phone,items[*].rid")
+	require.ErrorContains(t, err, "items[*].rid: cannot be soft")
+
+	// повторы и чужой трек-номер в товарах не смягчаются, даже если поле в Options.Warn
+	o = sample()
+	o.Items[0].RID = "rid-1"
+	o.Items = append(o.Items, o.Items[0])
+	o.Items[1].TrackNumber = "OTHERTRACK1"
+	opts = validate.Options{Warn: []string{"items[*].rid", "items[*].track_number"}}
+	warns, err = validate.Validate(o, opts)
+	require.Empty(t, warns)
+	fields := validate.Fields(err)
+	require.Equal(t, []string{"/items/1/chrt_id", "/items/1/rid", "/items/1/track_number"}, pointers(fields))
+	require.Equal(t, validate.CodeDuplicate, fields[1].Code)
+	require.Equal(t, validate.CodeMismatch, fields[2].Code)
+}
+
+func pointers(fes []validate.FieldError) []string {
+	out := make([]string, len(fes))
+	for i, fe := range fes {
+		out[i] = fe.Pointer
+	}
+	return out
+}
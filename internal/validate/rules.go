@@ -0,0 +1,514 @@
+package validate
+
+import (
+	"bytes"
+	_ "embed"
+	"errors"
+	"fmt"
+	"net/mail"
+	"os"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/vm"
+	"gopkg.in/yaml.v3"
+
+	"demo/orders/internal/model"
+)
+
+// Коды нарушений декларативных правил.
+const (
+	CodeEnum = "enum" // значение не из списка
+	CodeExpr = "expr" // не выполнено выражение правила (если у правила не задан свой code)
+)
+
+// RuleFile — файл наборов правил (YAML или JSON).
+type RuleFile struct {
+	Sets []RuleSet `yaml:"sets"`
+}
+
+// RuleSet — правила для части заказов. Заказ проверяется первым набором, чей match ему подходит.
+type RuleSet struct {
+	Name  string `yaml:"name"`
+	Match Match  `yaml:"match"`
+	Rules []Rule `yaml:"rules"`
+}
+
+// Match — к каким заказам относится набор; пустой список — к любым.
+type Match struct {
+	Entry  []string `yaml:"entry"`
+	Locale []string `yaml:"locale"`
+}
+
+// Rule — проверки одного поля. Идут по порядку required, format, regex, enum, min/max, min_items,
+// expr; нарушение даёт первая не прошедшая. Пустая необязательная строка дальше не проверяется.
+type Rule struct {
+	Field    string   `yaml:"field"` // JSON-путь: payment.currency, items[*].price
+	Required bool     `yaml:"required"`
+	Format   string   `yaml:"format"` // email | currency (код ISO 4217)
+	Regex    string   `yaml:"regex"`
+	Enum     []string `yaml:"enum"`
+	Min      *float64 `yaml:"min"`
+	Max      *float64 `yaml:"max"`
+	MinItems int      `yaml:"min_items"`
+	// Expr — выражение expr-lang, которое должно быть истинным. Доступны поля заказа по JSON-именам
+	// (payment.amount, items), value — значение поля и index — номер элемента для items[*].
+	Expr     string   `yaml:"expr"`
+	Code     string   `yaml:"code"` // код нарушения expr вместо CodeExpr
+	Message  string   `yaml:"message"`
+	Severity Severity `yaml:"severity"` // reject (по умолчанию) | warn
+}
+
+type compiledRule struct {
+	Rule
+	path []string
+	kind reflect.Kind
+	re   *regexp.Regexp
+	prog *vm.Program
+}
+
+type compiledSet struct {
+	RuleSet
+	rules []compiledRule
+}
+
+// parseRules разбирает и компилирует файл правил: неизвестные поля, несуществующие пути,
+// битые regex и выражения — ошибка.
+func parseRules(b []byte) ([]compiledSet, error) {
+	var f RuleFile
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	dec.KnownFields(true)
+	if err := dec.Decode(&f); err != nil {
+		return nil, err
+	}
+	if len(f.Sets) == 0 {
+		return nil, errors.New("no rule sets")
+	}
+	sets := make([]compiledSet, 0, len(f.Sets))
+	for i, s := range f.Sets {
+		if s.Name == "" {
+			s.Name = fmt.Sprintf("sets[%d]", i)
+		}
+		cs := compiledSet{RuleSet: s}
+		for j, r := range s.Rules {
+			cr, err := compileRule(r)
+			if err != nil {
+				return nil, fmt.Errorf("%s: rules[%d] (%s): %w", s.Name, j, r.Field, err)
+			}
+			cs.rules = append(cs.rules, cr)
+		}
+		sets = append(sets, cs)
+	}
+	return sets, nil
+}
+
+func compileRule(r Rule) (compiledRule, error) {
+	cr := compiledRule{Rule: r}
+	if r.Field == "" {
+		return cr, errors.New("field is required")
+	}
+	t, err := fieldType(reflect.TypeOf(model.Order{}), r.Field)
+	if err != nil {
+		return cr, err
+	}
+	cr.path = strings.Split(r.Field, ".")
+	cr.kind = t.Kind()
+	isString, isNumber := cr.kind == reflect.String, isNumeric(cr.kind)
+
+	switch r.Severity {
+	case "":
+		cr.Severity = SeverityReject
+	case SeverityWarn, SeverityReject:
+	default:
+		return cr, fmt.Errorf("unknown severity %q (want warn|reject)", r.Severity)
+	}
+	if cr.Severity == SeverityWarn && r.Field == "items[*].chrt_id" {
+		// как и в ParseWarn: пропущенный повтор chrt_id роняет запись в БД
+		return cr, errors.New("severity warn: cannot be soft, duplicates would break the items primary key")
+	}
+	if r.Format != "" && r.Format != "email" && r.Format != "currency" {
+		return cr, fmt.Errorf("unknown format %q (want email|currency)", r.Format)
+	}
+	if (r.Format != "" || r.Regex != "") && !isString {
+		return cr, errors.New("format and regex apply to strings only")
+	}
+	if r.Regex != "" {
+		if cr.re, err = regexp.Compile(r.Regex); err != nil {
+			return cr, err
+		}
+	}
+	if len(r.Enum) > 0 && !isString && !isNumber {
+		return cr, errors.New("enum applies to strings and numbers only")
+	}
+	if (r.Min != nil || r.Max != nil) && !isNumber {
+		return cr, errors.New("min and max apply to numbers only")
+	}
+	if r.MinItems > 0 && cr.kind != reflect.Slice {
+		return cr, errors.New("min_items applies to arrays only")
+	}
+	if r.Expr != "" {
+		// типы известны заранее: опечатка в имени поля или небулев результат — ошибка загрузки,
+		// а не отказ каждому заказу
+		env := exprEnv(model.Order{})
+		env["value"], env["index"] = reflect.Zero(mirrorType(t)).Interface(), 0
+		if cr.prog, err = expr.Compile(r.Expr, expr.Env(env), expr.AsBool()); err != nil {
+			return cr, errors.New(shortTypes(err.Error()))
+		}
+	}
+	return cr, nil
+}
+
+// fieldType находит тип поля заказа по JSON-пути; [*] — каждый элемент массива.
+func fieldType(t reflect.Type, path string) (reflect.Type, error) {
+	for _, name := range strings.Split(path, ".") {
+		name, all := strings.CutSuffix(name, "[*]")
+		if t.Kind() != reflect.Struct {
+			return nil, fmt.Errorf("%s: not an object", name)
+		}
+		f, ok := jsonField(t, name)
+		if !ok {
+			return nil, fmt.Errorf("unknown field %q", name)
+		}
+		t = f.Type
+		if all {
+			if t.Kind() != reflect.Slice {
+				return nil, fmt.Errorf("%s: [*] on a non-array", name)
+			}
+			t = t.Elem()
+		}
+	}
+	return t, nil
+}
+
+func jsonField(t reflect.Type, name string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if tag, _, _ := strings.Cut(f.Tag.Get("json"), ","); tag == name {
+			return f, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+func isNumeric(k reflect.Kind) bool {
+	return k >= reflect.Int && k <= reflect.Int64 || k >= reflect.Float32 && k <= reflect.Float64
+}
+
+// each вызывает fn для каждого значения пути в v; field — путь с индексами (items[0].name),
+// index — номер элемента последнего [*] (-1, если его нет).
+func each(v reflect.Value, path []string, prefix string, index int, fn func(field string, index int, v reflect.Value)) {
+	if len(path) == 0 {
+		fn(prefix, index, v)
+		return
+	}
+	name, all := strings.CutSuffix(path[0], "[*]")
+	f, _ := jsonField(v.Type(), name)
+	v = v.FieldByIndex(f.Index)
+	if prefix != "" {
+		name = prefix + "." + name
+	}
+	if !all {
+		each(v, path[1:], name, index, fn)
+		return
+	}
+	for i := 0; i < v.Len(); i++ {
+		each(v.Index(i), path[1:], fmt.Sprintf("%s[%d]", name, i), i, fn)
+	}
+}
+
+// check применяет набор к заказу; нарушения с severity warn уходят в warns.
+func (s compiledSet) check(o model.Order, errs, warns *violations) {
+	var env map[string]any // заказ для выражений — собирается, только если они есть
+	for _, r := range s.rules {
+		dst := errs
+		if r.Severity == SeverityWarn {
+			dst = warns
+		}
+		each(reflect.ValueOf(o), r.path, "", -1, func(field string, index int, v reflect.Value) {
+			if r.prog != nil && env == nil {
+				env = exprEnv(o)
+			}
+			if code, msg, ok := r.test(v, index, env); !ok {
+				if r.Message != "" {
+					msg = r.Message
+				}
+				dst.add(field, code, msg, v.Interface())
+			}
+		})
+	}
+}
+
+// test проверяет одно значение; при нарушении — код и сообщение по умолчанию.
+func (r compiledRule) test(v reflect.Value, index int, env map[string]any) (code, msg string, ok bool) {
+	if r.kind == reflect.String && strings.TrimSpace(v.String()) == "" {
+		if r.Required {
+			return CodeRequired, "required", false
+		}
+		return "", "", true
+	}
+	if r.Required && v.IsZero() {
+		return CodeRequired, "required", false
+	}
+	switch r.Format {
+	case "email":
+		if _, err := mail.ParseAddress(v.String()); err != nil {
+			return CodeFormat, "invalid", false
+		}
+	case "currency":
+		if code, msg, ok := checkCurrency(v.String()); !ok {
+			return code, msg, false
+		}
+	}
+	if r.re != nil && !r.re.MatchString(v.String()) {
+		return CodeFormat, "must match " + r.Regex, false
+	}
+	if len(r.Enum) > 0 && !slices.Contains(r.Enum, fmt.Sprint(v.Interface())) {
+		return CodeEnum, "must be one of " + strings.Join(r.Enum, ", "), false
+	}
+	if isNumeric(r.kind) {
+		n := toFloat(v)
+		if r.Min != nil && n < *r.Min {
+			return CodeRange, fmt.Sprintf("must be >= %v", *r.Min), false
+		}
+		if r.Max != nil && n > *r.Max {
+			return CodeRange, fmt.Sprintf("must be <= %v", *r.Max), false
+		}
+	}
+	if r.MinItems > 0 && v.Len() < r.MinItems {
+		return CodeMinItems, fmt.Sprintf("must contain at least %d items", r.MinItems), false
+	}
+	if r.prog != nil {
+		env["value"], env["index"] = mirrorValue(v).Interface(), index
+		out, err := expr.Run(r.prog, env)
+		code := r.Code
+		if code == "" {
+			code = CodeExpr
+		}
+		if err != nil {
+			return code, "expression failed: " + err.Error(), false
+		}
+		if ok, _ := out.(bool); !ok {
+			return code, "must satisfy " + r.Expr, false
+		}
+	}
+	return "", "", true
+}
+
+func toFloat(v reflect.Value) float64 {
+	if v.CanInt() {
+		return float64(v.Int())
+	}
+	return v.Float()
+}
+
+// exprEnv — заказ в том виде, в каком его видят авторы правил: поля верхнего уровня по JSON-именам,
+// вложенные объекты — структуры-зеркала (mirrorType) с теми же типами значений, что в модели.
+func exprEnv(o model.Order) map[string]any {
+	v := reflect.ValueOf(o)
+	env := make(map[string]any, v.NumField()+2)
+	for i := 0; i < v.NumField(); i++ {
+		if name, ok := jsonName(v.Type().Field(i)); ok {
+			env[name] = mirrorValue(v.Field(i)).Interface()
+		}
+	}
+	return env
+}
+
+var mirrors sync.Map // reflect.Type → reflect.Type
+
+// mirrorType — тип с полями под JSON-именами (тег expr) вместо Go-имён: payment.amount, а не Payment.Amount.
+// Структуры и срезы структур заменяются зеркалами, остальное (числа, строки, time.Time) — как есть.
+func mirrorType(t reflect.Type) reflect.Type {
+	if m, ok := mirrors.Load(t); ok {
+		return m.(reflect.Type)
+	}
+	m := t
+	switch {
+	case t.Kind() == reflect.Slice:
+		if elem := mirrorType(t.Elem()); elem != t.Elem() {
+			m = reflect.SliceOf(elem)
+		}
+	case t.Kind() == reflect.Struct && t != reflect.TypeOf(time.Time{}):
+		var fields []reflect.StructField
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if name, ok := jsonName(f); ok {
+				fields = append(fields, reflect.StructField{
+					Name: f.Name, Type: mirrorType(f.Type), Tag: reflect.StructTag(`expr:"` + name + `"`),
+				})
+			}
+		}
+		m = reflect.StructOf(fields)
+	}
+	mirrors.Store(t, m)
+	return m
+}
+
+// mirrorValue — v, переложенное в mirrorType(v.Type()).
+func mirrorValue(v reflect.Value) reflect.Value {
+	t := mirrorType(v.Type())
+	if t == v.Type() {
+		return v
+	}
+	out := reflect.New(t).Elem()
+	if v.Kind() == reflect.Slice {
+		if v.IsNil() {
+			return out
+		}
+		out.Set(reflect.MakeSlice(t, v.Len(), v.Len()))
+		for i := 0; i < v.Len(); i++ {
+			out.Index(i).Set(mirrorValue(v.Index(i)))
+		}
+		return out
+	}
+	for i := 0; i < t.NumField(); i++ {
+		out.Field(i).Set(mirrorValue(v.FieldByName(t.Field(i).Name)))
+	}
+	return out
+}
+
+// shortTypes заменяет в ошибке компиляции описания структур-зеркал ("type struct { Transaction string
+// \"expr:...\"; ... } has no field amout") на "object".
+func shortTypes(msg string) string {
+	var b strings.Builder
+	for {
+		i := strings.Index(msg, "struct {")
+		if i < 0 {
+			break
+		}
+		b.WriteString(msg[:i])
+		depth, j := 0, i
+		for ; j < len(msg); j++ {
+			if msg[j] == '{' {
+				depth++
+			} else if msg[j] == '}' {
+				if depth--; depth == 0 {
+					break
+				}
+			}
+		}
+		b.WriteString("object")
+		msg = msg[min(j+1, len(msg)):]
+	}
+	b.WriteString(msg)
+	return b.String()
+}
+
+func jsonName(f reflect.StructField) (string, bool) {
+	name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+	return name, f.IsExported() && name != "" && name != "-"
+}
+
+// match — подходит ли набор заказу.
+func (s compiledSet) match(o model.Order) bool {
+	return (len(s.Match.Entry) == 0 || slices.Contains(s.Match.Entry, o.Entry)) &&
+		(len(s.Match.Locale) == 0 || slices.Contains(s.Match.Locale, o.Locale))
+}
+
+// Rules — наборы правил из файла, перечитываемые без рестарта.
+type Rules struct {
+	path string
+
+	mu      sync.RWMutex
+	sets    []compiledSet
+	raw     []byte
+	modTime time.Time
+}
+
+// LoadRules читает наборы правил из path (YAML или JSON).
+func LoadRules(path string) (*Rules, error) {
+	r := &Rules{path: path}
+	if _, err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload перечитывает файл; changed=false, если содержимое не изменилось.
+// Битый файл не применяется — остаются прежние правила.
+func (r *Rules) Reload() (changed bool, err error) {
+	fi, err := os.Stat(r.path)
+	if err != nil {
+		return false, fmt.Errorf("validation rules: %w", err)
+	}
+	b, err := os.ReadFile(r.path)
+	if err != nil {
+		return false, fmt.Errorf("validation rules: %w", err)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.modTime = fi.ModTime()
+	if r.raw != nil && bytes.Equal(b, r.raw) {
+		return false, nil
+	}
+	sets, err := parseRules(b)
+	if err != nil {
+		return false, fmt.Errorf("validation rules %s: %w", r.path, err)
+	}
+	r.sets, r.raw = sets, b
+	return true, nil
+}
+
+// Stale — изменился ли файл с последнего Reload (по mtime).
+func (r *Rules) Stale() bool {
+	fi, err := os.Stat(r.path)
+	if err != nil {
+		return false
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return !fi.ModTime().Equal(r.modTime)
+}
+
+// set — набор для заказа; ok=false — ни один не подошёл (или правил нет).
+func (r *Rules) set(o model.Order) (compiledSet, bool) {
+	if r == nil {
+		return compiledSet{}, false
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, s := range r.sets {
+		if s.match(o) {
+			return s, true
+		}
+	}
+	return compiledSet{}, false
+}
+
+// SetRules — правила набора name как они записаны в файле; ok=false — такого набора нет.
+func (r *Rules) SetRules(name string) (rules []Rule, ok bool) {
+	if r == nil {
+		return nil, false
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, s := range r.sets {
+		if s.Name == name {
+			return s.Rules, true
+		}
+	}
+	return nil, false
+}
+
+//go:embed builtin.yaml
+var builtinYAML []byte
+
+// BuiltinRules — встроенные правила ValidateOrder в виде правил набора (builtin.yaml).
+var BuiltinRules = sync.OnceValue(func() []Rule {
+	sets, err := parseRules(builtinYAML)
+	if err != nil {
+		panic("validate: builtin.yaml: " + err.Error())
+	}
+	return sets[0].Rules
+})
+
+// SetName — имя набора, которым будет проверен заказ; "" — встроенные правила ValidateOrder.
+func (r *Rules) SetName(o model.Order) string {
+	s, _ := r.set(o)
+	return s.Name
+}
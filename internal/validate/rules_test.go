@@ -0,0 +1,184 @@
+// internal/validate/rules_test.go
+package validate_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"demo/orders/internal/model"
+	"demo/orders/internal/validate"
+)
+
+func writeRules(t *testing.T, path, body string, mtime time.Time) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(path, []byte(body), 0o600))
+	require.NoError(t, os.Chtimes(path, mtime, mtime))
+}
+
+func TestRules_BuiltinEquivalent(t *testing.T) {
+	rules, err := validate.LoadRules("builtin.yaml")
+	require.NoError(t, err)
+	opts := validate.Options{Rules: rules}
+
+	broken := sample()
+	broken.TrackNumber = "wbilm"
+	broken.CustomerID = "no spaces"
+	broken.DateCreated = time.Now().Add(time.Hour)
+	broken.Delivery = model.Delivery{Email: "nope", Phone: "x"}
+	broken.Payment = model.Payment{Currency: "usdx", Amount: -1, CustomFee: -2, PaymentDT: 1}
+	broken.Items = append(broken.Items, model.Item{Price: -1, Sale: 101, TotalPrice: -1, Name: " "})
+
+	lower := sample()
+	lower.TrackNumber = "wbilmtesttrack" // регистр не важен, как и в ValidateOrder
+	lower.Payment.Currency = "usd"
+
+	unknown := sample()
+	unknown.Payment.Currency = "XYZ"
+
+	for name, o := range map[string]model.Order{"valid": sample(), "lowercase": lower, "empty": {}, "broken": broken, "unknown currency": unknown} {
+		require.Equal(t, "builtin", rules.SetName(o), name)
+		warns, err := validate.Validate(o, opts)
+		require.Empty(t, warns, name)
+		require.ElementsMatch(t, validate.Fields(validate.ValidateOrder(o)), validate.Fields(err), name)
+	}
+}
+
+func TestRules_SetsAndChecks(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.yaml")
+	writeRules(t, path, `
+sets:
+  - name: ru-market
+    match: {entry: [WBRU], locale: [ru]}
+    rules:
+      - {field: track_number, required: true, regex: '^[A-Z0-9]{4,}$'}
+      - {field: payment.currency, enum: [RUB]}
+      - {field: 'items[*].sale', max: 50, severity: warn}
+      - field: payment.amount
+        expr: abs(payment.amount - (payment.goods_total + payment.delivery_cost + payment.custom_fee)) <= 1
+        code: mismatch
+      - field: 'items[*].total_price'
+        expr: value == items[index].price * (100 - items[index].sale) / 100
+        severity: warn
+  - name: other
+    rules:
+      - {field: order_uid, required: true}
+`, time.Now())
+	rules, err := validate.LoadRules(path)
+	require.NoError(t, err)
+	opts := validate.Options{Rules: rules}
+
+	o := sample()
+	o.Entry, o.Locale = "WBRU", "ru"
+	o.TrackNumber = "WB1" // короче 4 — нарушение; встроенной проверки 6..32 в наборе нет
+	o.Items[0].TrackNumber = "WB1"
+	o.Payment.Currency = "RUB"
+	o.Items[0].Sale = 60
+	require.Equal(t, "ru-market", rules.SetName(o))
+	warns, err := validate.Validate(o, opts)
+	require.Equal(t, []validate.FieldError{{
+		Field: "track_number", Pointer: "/track_number", Code: validate.CodeFormat,
+		Message: "must match ^[A-Z0-9]{4,}$", Value: "WB1",
+	}}, validate.Fields(err))
+	require.Len(t, warns, 2)
+	require.Equal(t, validate.FieldError{
+		Field: "items[0].sale", Pointer: "/items/0/sale", Code: validate.CodeRange, Message: "must be <= 50", Value: 60,
+	}, warns[0])
+	require.Equal(t, "/items/0/total_price", warns[1].Pointer)
+	require.Equal(t, validate.CodeExpr, warns[1].Code)
+
+	o.TrackNumber, o.Items[0].TrackNumber = "WBILM", "WBILM"
+	o.Payment.Currency = "USD"
+	o.Payment.Amount += 2
+	_, err = validate.Validate(o, opts)
+	fields := validate.Fields(err)
+	require.Len(t, fields, 2)
+	require.Equal(t, validate.CodeEnum, fields[0].Code)
+	require.Equal(t, "must be one of RUB", fields[0].Message)
+	require.Equal(t, "mismatch", fields[1].Code)
+
+	// другой набор — всё, что не ru-market
+	o.Locale = "en"
+	require.Equal(t, "other", rules.SetName(o))
+	_, err = validate.Validate(o, opts)
+	require.NoError(t, err)
+}
+
+func TestRules_FallbackAndReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.json")
+	t0 := time.Now().Add(-time.Minute)
+	writeRules(t, path, `{"sets": [{"name": "wb", "match": {"entry": ["WBIL"]}, "rules": [{"field": "locale", "enum": ["en"]}]}]}`, t0)
+	rules, err := validate.LoadRules(path)
+	require.NoError(t, err)
+	opts := validate.Options{Rules: rules}
+
+	o := sample()
+	o.Locale = "ru"
+	_, err = validate.Validate(o, opts)
+	require.Equal(t, "/locale", validate.Fields(err)[0].Pointer)
+
+	// набора нет — встроенные правила
+	o.Entry = "OTHER"
+	o.Payment.Currency = ""
+	require.Empty(t, rules.SetName(o))
+	_, err = validate.Validate(o, opts)
+	require.Equal(t, validate.Fields(validate.ValidateOrder(o)), validate.Fields(err))
+
+	require.False(t, rules.Stale())
+	changed, err := rules.Reload()
+	require.NoError(t, err)
+	require.False(t, changed)
+
+	writeRules(t, path, `{"sets": [{"name": "wb2", "match": {"entry": ["OTHER"]}, "rules": []}]}`, t0.Add(time.Second))
+	require.True(t, rules.Stale())
+	changed, err = rules.Reload()
+	require.NoError(t, err)
+	require.True(t, changed)
+	_, err = validate.Validate(o, opts)
+	require.NoError(t, err)
+
+	// битый файл не применяется
+	writeRules(t, path, `{"sets": [{"rules": [{"field": "nope"}]}]}`, t0.Add(2*time.Second))
+	_, err = rules.Reload()
+	require.ErrorContains(t, err, `unknown field "nope"`)
+	require.Equal(t, "wb2", rules.SetName(o))
+}
+
+func TestRules_Errors(t *testing.T) {
+	dir := t.TempDir()
+	for name, body := range map[string]string{
+		"empty":         `sets: []`,
+		"unknown key":   `{"sets": [{"rules": [{"field": "entry", "pattern": "x"}]}]}`,
+		"no field":      `{"sets": [{"rules": [{"required": true}]}]}`,
+		"bad path":      `{"sets": [{"rules": [{"field": "items.name"}]}]}`,
+		"bad regex":     `{"sets": [{"rules": [{"field": "entry", "regex": "("}]}]}`,
+		"regex on int":  `{"sets": [{"rules": [{"field": "payment.amount", "regex": "1"}]}]}`,
+		"min on string": `{"sets": [{"rules": [{"field": "entry", "min": 1}]}]}`,
+		"bad expr":      `{"sets": [{"rules": [{"field": "entry", "expr": "value =="}]}]}`,
+		// выражения проверяются по типам заказа при загрузке, а не на каждом заказе
+		"unknown name":   `{"sets": [{"rules": [{"field": "entry", "expr": "entri == value"}]}]}`,
+		"nested typo":    `{"sets": [{"rules": [{"field": "payment.amount", "expr": "payment.amout > 0"}]}]}`,
+		"item typo":      `{"sets": [{"rules": [{"field": "items[*].price", "expr": "items[index].prise > 0"}]}]}`,
+		"not bool":       `{"sets": [{"rules": [{"field": "payment.amount", "expr": "value + 1"}]}]}`,
+		"type mismatch":  `{"sets": [{"rules": [{"field": "entry", "expr": "value > 5"}]}]}`,
+		"bad severity":   `{"sets": [{"rules": [{"field": "entry", "severity": "fatal"}]}]}`,
+		"bad format":     `{"sets": [{"rules": [{"field": "entry", "format": "uuid"}]}]}`,
+		"min_items on 1": `{"sets": [{"rules": [{"field": "entry", "min_items": 1}]}]}`,
+		"warn chrt_id":   `{"sets": [{"rules": [{"field": "items[*].chrt_id", "min": 1, "severity": "warn"}]}]}`,
+	} {
+		path := filepath.Join(dir, "rules.yaml")
+		writeRules(t, path, body, time.Now())
+		_, err := validate.LoadRules(path)
+		require.Error(t, err, name)
+	}
+	_, err := validate.LoadRules(filepath.Join(dir, "missing.yaml"))
+	require.Error(t, err)
+
+	path := filepath.Join(dir, "rules.yaml")
+	writeRules(t, path, `{"sets": [{"rules": [{"field": "payment.amount", "expr": "payment.amout > 0"}]}]}`, time.Now())
+	_, err = validate.LoadRules(path)
+	require.ErrorContains(t, err, "rules[0] (payment.amount): type object has no field amout")
+}
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"demo/orders/internal/model"
+	"demo/orders/internal/money"
 )
 
 var (
@@ -19,98 +20,210 @@ var (
 	reOrderID = regexp.MustCompile(`^[A-Za-z0-9_\-\.]{6,64}$`)
 )
 
-type multiErr []error
+// Коды нарушений — для API, логов и метрик; Message — для человека.
+const (
+	CodeRequired  = "required"  // поле пустое
+	CodeFormat    = "format"    // не подходит под формат (шаблон, email, телефон)
+	CodeRange     = "range"     // число или дата вне допустимого диапазона
+	CodeMinItems  = "min_items" // в массиве слишком мало элементов
+	CodeDuplicate = "duplicate" // значение повторяется там, где должно быть уникальным
+)
+
+// FieldError — нарушение в одном поле заказа.
+type FieldError struct {
+	Field   string // путь к полю: delivery.email, items[0].name
+	Pointer string // тот же путь JSON Pointer'ом (RFC 6901): /delivery/email, /items/0/name
+	Code    string // Code*
+	Message string
+	Value   any // значение, не прошедшее проверку; может содержать персональные данные
+}
 
-func (m multiErr) Error() string {
+func (e FieldError) Error() string { return e.Field + ": " + e.Message }
+
+// ValidationError — все нарушения заказа; ValidateOrder возвращает *ValidationError,
+// достать его можно через errors.As.
+type ValidationError struct {
+	Errors []FieldError
+}
+
+func (e *ValidationError) Error() string {
 	var b strings.Builder
-	for i, e := range m {
+	for i, fe := range e.Errors {
 		if i > 0 {
 			b.WriteString("; ")
 		}
-		b.WriteString(e.Error())
+		b.WriteString(fe.Error())
 	}
 	return b.String()
 }
-func (m multiErr) OrNil() error {
-	if len(m) == 0 {
+
+// Fields раскладывает ошибку ValidateOrder по полям; для прочих ошибок — nil.
+func Fields(err error) []FieldError {
+	var ve *ValidationError
+	if !errors.As(err, &ve) {
+		return nil
+	}
+	return ve.Errors
+}
+
+// violations копит нарушения по ходу проверки.
+type violations []FieldError
+
+func (v *violations) add(field, code, msg string, value any) {
+	*v = append(*v, FieldError{Field: field, Pointer: Pointer(field), Code: code, Message: msg, Value: value})
+}
+
+func (v violations) err() error {
+	if len(v) == 0 {
 		return nil
 	}
-	return m
+	return &ValidationError{Errors: v}
 }
 
+// Pointer переводит путь items[0].name в JSON Pointer (RFC 6901) /items/0/name; ~ и / в именах
+// экранируются. Один на всех, кто отдаёт pointer рядом с field: validate, normalize, apispec.
+func Pointer(field string) string {
+	esc := strings.NewReplacer("~", "~0", "/", "~1")
+	var b strings.Builder
+	for _, seg := range strings.FieldsFunc(field, func(r rune) bool { return r == '.' || r == '[' || r == ']' }) {
+		b.WriteString("/" + esc.Replace(seg))
+	}
+	return b.String()
+}
+
+// ValidateOrder проверяет заказ целиком и возвращает *ValidationError со всеми нарушениями или nil.
 func ValidateOrder(o model.Order) error {
-	var errs multiErr
+	var errs violations
 
 	// Базовые поля
 	if !reOrderID.MatchString(o.OrderUID) {
-		errs = append(errs, fmt.Errorf("order_uid: invalid or empty"))
+		errs.add("order_uid", formatOrRequired(o.OrderUID), "invalid or empty", o.OrderUID)
 	}
 	if !reTrack.MatchString(strings.ToUpper(o.TrackNumber)) {
-		errs = append(errs, fmt.Errorf("track_number: must be 6-32 uppercase letters/digits"))
+		errs.add("track_number", formatOrRequired(o.TrackNumber), "must be 6-32 uppercase letters/digits", o.TrackNumber)
 	}
 	if strings.TrimSpace(o.Entry) == "" {
-		errs = append(errs, fmt.Errorf("entry: required"))
+		errs.add("entry", CodeRequired, "required", o.Entry)
 	}
 	if o.CustomerID == "" || !reCust.MatchString(o.CustomerID) {
-		errs = append(errs, fmt.Errorf("customer_id: 1..64 [A-Za-z0-9_.-]"))
+		errs.add("customer_id", formatOrRequired(o.CustomerID), "1..64 [A-Za-z0-9_.-]", o.CustomerID)
 	}
 	if o.DateCreated.After(time.Now().Add(5 * time.Minute)) {
-		errs = append(errs, fmt.Errorf("date_created: must not be in the future"))
+		errs.add("date_created", CodeRange, "must not be in the future", o.DateCreated)
 	}
 
 	// Delivery
 	if o.Delivery.Email != "" {
 		if _, err := mail.ParseAddress(o.Delivery.Email); err != nil {
-			errs = append(errs, fmt.Errorf("delivery.email: invalid"))
+			errs.add("delivery.email", CodeFormat, "invalid", o.Delivery.Email)
 		}
 	}
 	if o.Delivery.Phone != "" && !rePhone.MatchString(o.Delivery.Phone) {
-		errs = append(errs, fmt.Errorf("delivery.phone: invalid"))
+		errs.add("delivery.phone", CodeFormat, "invalid", o.Delivery.Phone)
 	}
 
 	// Payment
-	if !reCurr.MatchString(strings.ToUpper(o.Payment.Currency)) {
-		errs = append(errs, fmt.Errorf("payment.currency: must be 3-letter ISO code"))
+	if code, msg, ok := checkCurrency(o.Payment.Currency); !ok {
+		errs.add("payment.currency", code, msg, o.Payment.Currency)
 	}
 	if o.Payment.Amount < 0 {
-		errs = append(errs, fmt.Errorf("payment.amount: must be >= 0"))
+		errs.add("payment.amount", CodeRange, "must be >= 0", o.Payment.Amount)
 	}
-	if o.Payment.DeliveryCost < 0 || o.Payment.GoodsTotal < 0 || o.Payment.CustomFee < 0 {
-		errs = append(errs, fmt.Errorf("payment costs: must be >= 0"))
+	for _, c := range []struct {
+		field string
+		v     int
+	}{{"payment.delivery_cost", o.Payment.DeliveryCost}, {"payment.goods_total", o.Payment.GoodsTotal}, {"payment.custom_fee", o.Payment.CustomFee}} {
+		if c.v < 0 {
+			errs.add(c.field, CodeRange, "must be >= 0", c.v)
+		}
 	}
 	payTime := time.Unix(o.Payment.PaymentDT, 0)
 	if payTime.Before(time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)) || payTime.After(time.Now().Add(5*time.Minute)) {
-		errs = append(errs, fmt.Errorf("payment_dt: out of sane range"))
+		errs.add("payment.payment_dt", CodeRange, "out of sane range", o.Payment.PaymentDT)
 	}
 
 	// Items
 	if len(o.Items) == 0 {
-		errs = append(errs, errors.New("items: must contain at least 1 item"))
+		errs.add("items", CodeMinItems, "must contain at least 1 item", o.Items)
 	} else {
 		for i, it := range o.Items {
 			if it.ChrtID <= 0 {
-				errs = append(errs, fmt.Errorf("items[%d].chrt_id: must be > 0", i))
+				errs.add(item(i, "chrt_id"), CodeRange, "must be > 0", it.ChrtID)
 			}
 			if strings.TrimSpace(it.Name) == "" {
-				errs = append(errs, fmt.Errorf("items[%d].name: required", i))
+				errs.add(item(i, "name"), CodeRequired, "required", it.Name)
+			}
+			if it.Price < 0 {
+				errs.add(item(i, "price"), CodeRange, "must be >= 0", it.Price)
 			}
-			if it.Price < 0 || it.TotalPrice < 0 {
-				errs = append(errs, fmt.Errorf("items[%d].price/total_price: must be >= 0", i))
+			if it.TotalPrice < 0 {
+				errs.add(item(i, "total_price"), CodeRange, "must be >= 0", it.TotalPrice)
 			}
 			if it.Sale < 0 || it.Sale > 100 {
-				errs = append(errs, fmt.Errorf("items[%d].sale: 0..100", i))
+				errs.add(item(i, "sale"), CodeRange, "0..100", it.Sale)
 			}
 			if strings.TrimSpace(it.Size) == "" {
-				errs = append(errs, fmt.Errorf("items[%d].size: required", i))
+				errs.add(item(i, "size"), CodeRequired, "required", it.Size)
 			}
 			if it.NmID <= 0 {
-				errs = append(errs, fmt.Errorf("items[%d].nm_id: must be > 0", i))
+				errs.add(item(i, "nm_id"), CodeRange, "must be > 0", it.NmID)
 			}
 			if strings.TrimSpace(it.TrackNumber) == "" {
-				errs = append(errs, fmt.Errorf("items[%d].track_number: required", i))
+				errs.add(item(i, "track_number"), CodeRequired, "required", it.TrackNumber)
 			}
 		}
 	}
+	checkItemRefs(o, &errs)
 
-	return errs.OrNil()
+	return errs.err()
 }
+
+// checkItemRefs — сверки между товарами и с заказом: chrt_id и rid не повторяются (chrt_id входит
+// в первичный ключ items — повтор уронил бы запись в БД), track_number товара — как у заказа.
+// Наборы правил их не заменяют: Validate проверяет это при любом наборе.
+func checkItemRefs(o model.Order, errs *violations) {
+	chrt := make(map[int64]int, len(o.Items))
+	rid := make(map[string]int, len(o.Items))
+	for i, it := range o.Items {
+		// повтор проверяется при любом значении: набор правил может и не требовать chrt_id > 0
+		if j, ok := chrt[it.ChrtID]; ok {
+			errs.add(item(i, "chrt_id"), CodeDuplicate, "duplicate of "+item(j, "chrt_id"), it.ChrtID)
+		} else {
+			chrt[it.ChrtID] = i
+		}
+		if it.RID != "" {
+			if j, ok := rid[it.RID]; ok {
+				errs.add(item(i, "rid"), CodeDuplicate, "duplicate of "+item(j, "rid"), it.RID)
+			} else {
+				rid[it.RID] = i
+			}
+		}
+		if strings.TrimSpace(it.TrackNumber) != "" && strings.TrimSpace(o.TrackNumber) != "" &&
+			!strings.EqualFold(strings.TrimSpace(it.TrackNumber), strings.TrimSpace(o.TrackNumber)) {
+			errs.add(item(i, "track_number"), CodeMismatch, "must equal order track_number", it.TrackNumber)
+		}
+	}
+}
+
+// checkCurrency — код валюты: три буквы в любом регистре и есть в таблице ISO 4217.
+func checkCurrency(v string) (code, msg string, ok bool) {
+	switch {
+	case strings.TrimSpace(v) == "":
+		return CodeRequired, "required", false
+	case !reCurr.MatchString(strings.ToUpper(v)):
+		return CodeFormat, "must be 3-letter ISO code", false
+	case !money.Known(v):
+		return CodeEnum, "unknown ISO 4217 currency code", false
+	}
+	return "", "", true
+}
+
+// formatOrRequired — код для поля со строгим форматом: пустое — required, иначе format.
+func formatOrRequired(v string) string {
+	if strings.TrimSpace(v) == "" {
+		return CodeRequired
+	}
+	return CodeFormat
+}
+
+func item(i int, field string) string { return fmt.Sprintf("items[%d].%s", i, field) }
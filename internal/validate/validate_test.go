@@ -2,6 +2,10 @@
 package validate_test
 
 import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -33,7 +37,7 @@ func TestValidateOrder_Valid(t *testing.T) {
 		Items: []model.Item{
 			{
 				ChrtID:      1,
-				TrackNumber: "WB123456",
+				TrackNumber: "ABC123",
 				Price:       100,
 				RID:         "rid-1",
 				Name:        "Product",
@@ -56,3 +60,107 @@ func TestValidateOrder_InvalidEmpty(t *testing.T) {
 	err := validate.ValidateOrder(o)
 	require.Error(t, err)
 }
+
+func TestValidateOrder_Fields(t *testing.T) {
+	err := validate.ValidateOrder(model.Order{OrderUID: "order01", Payment: model.Payment{Currency: "usd1", CustomFee: -1}})
+	fields := map[string]string{}
+	for _, fe := range validate.Fields(err) {
+		fields[fe.Field] = fe.Message
+	}
+	require.Equal(t, "must be 3-letter ISO code", fields["payment.currency"])
+	require.Equal(t, "must be >= 0", fields["payment.custom_fee"])
+	require.Contains(t, fields, "items")
+	require.NotContains(t, fields, "order_uid")
+
+	require.Nil(t, validate.Fields(nil))
+}
+
+func TestValidateOrder_UnknownCurrency(t *testing.T) {
+	err := validate.ValidateOrder(model.Order{Payment: model.Payment{Currency: "usx"}})
+	for _, fe := range validate.Fields(err) {
+		if fe.Field == "payment.currency" {
+			require.Equal(t, validate.CodeEnum, fe.Code)
+			require.Equal(t, "unknown ISO 4217 currency code", fe.Message)
+			return
+		}
+	}
+	t.Fatal("payment.currency not reported")
+}
+
+func TestValidateOrder_ItemRefs(t *testing.T) {
+	o := model.Order{
+		OrderUID:    "order01",
+		TrackNumber: "ABC123",
+		Entry:       "WBIL",
+		CustomerID:  "cust1",
+		Payment:     model.Payment{Currency: "USD", PaymentDT: time.Now().Unix()},
+		Items: []model.Item{
+			{ChrtID: 1, TrackNumber: "ABC123", RID: "r1", Name: "P", Size: "M", NmID: 1},
+			{ChrtID: 2, TrackNumber: "abc123", RID: "r2", Name: "P", Size: "M", NmID: 1},
+		},
+	}
+	require.NoError(t, validate.ValidateOrder(o)) // регистр track_number не важен
+
+	o.Items = append(o.Items, model.Item{ChrtID: 1, TrackNumber: "WB999999", RID: "r1", Name: "P", Size: "M", NmID: 1})
+	require.Equal(t, []validate.FieldError{
+		{Field: "items[2].chrt_id", Pointer: "/items/2/chrt_id", Code: validate.CodeDuplicate, Message: "duplicate of items[0].chrt_id", Value: int64(1)},
+		{Field: "items[2].rid", Pointer: "/items/2/rid", Code: validate.CodeDuplicate, Message: "duplicate of items[0].rid", Value: "r1"},
+		{Field: "items[2].track_number", Pointer: "/items/2/track_number", Code: validate.CodeMismatch, Message: "must equal order track_number", Value: "WB999999"},
+	}, validate.Fields(validate.ValidateOrder(o)))
+
+	// набор правил сверки между товарами не отключает
+	path := filepath.Join(t.TempDir(), "rules.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("sets: [{name: any, rules: [{field: order_uid, required: true}]}]"), 0o644))
+	rules, err := validate.LoadRules(path)
+	require.NoError(t, err)
+	_, err = validate.Validate(o, validate.Options{Rules: rules})
+	require.Len(t, validate.Fields(err), 3)
+
+	// повтор chrt_id ловится при любом значении, даже если набор не требует chrt_id > 0
+	o.Items = []model.Item{
+		{ChrtID: 0, TrackNumber: "ABC123", RID: "r1", Name: "P", Size: "M", NmID: 1},
+		{ChrtID: 0, TrackNumber: "ABC123", RID: "r2", Name: "P", Size: "M", NmID: 1},
+	}
+	_, err = validate.Validate(o, validate.Options{Rules: rules})
+	require.Equal(t, []validate.FieldError{
+		{Field: "items[1].chrt_id", Pointer: "/items/1/chrt_id", Code: validate.CodeDuplicate, Message: "duplicate of items[0].chrt_id", Value: int64(0)},
+	}, validate.Fields(err))
+}
+
+func TestValidationError(t *testing.T) {
+	o := model.Order{
+		OrderUID:    "order01",
+		TrackNumber: "ABC123",
+		Entry:       "WBIL",
+		CustomerID:  "cust1",
+		Delivery:    model.Delivery{Email: "not-an-email"},
+		Payment:     model.Payment{Currency: "USD", PaymentDT: time.Now().Unix()},
+		Items:       []model.Item{{ChrtID: 1, TrackNumber: "ABC123", Name: "P", Size: "M", NmID: 1, Sale: 120}},
+	}
+	err := fmt.Errorf("order %s: %w", o.OrderUID, validate.ValidateOrder(o))
+
+	var ve *validate.ValidationError
+	require.True(t, errors.As(err, &ve))
+	require.Equal(t, []validate.FieldError{
+		{Field: "delivery.email", Pointer: "/delivery/email", Code: validate.CodeFormat, Message: "invalid", Value: "not-an-email"},
+		{Field: "items[0].sale", Pointer: "/items/0/sale", Code: validate.CodeRange, Message: "0..100", Value: 120},
+	}, ve.Errors)
+	require.Equal(t, "delivery.email: invalid; items[0].sale: 0..100", ve.Error())
+
+	err = validate.ValidateOrder(model.Order{OrderUID: "order01"})
+	require.True(t, errors.As(err, &ve))
+	codes := map[string]string{}
+	for _, fe := range ve.Errors {
+		codes[fe.Pointer] = fe.Code
+	}
+	require.Equal(t, validate.CodeRequired, codes["/track_number"])
+	require.Equal(t, validate.CodeRequired, codes["/payment/currency"])
+	require.Equal(t, validate.CodeMinItems, codes["/items"])
+}
+
+func TestPointer(t *testing.T) {
+	require.Equal(t, "/order_uid", validate.Pointer("order_uid"))
+	require.Equal(t, "/items/0/total_price", validate.Pointer("items[0].total_price"))
+	require.Equal(t, "/payment/currency", validate.Pointer("payment.currency"))
+	require.Equal(t, "/meta/a~1b/c~0d", validate.Pointer("meta.a/b.c~d"))
+}